@@ -149,6 +149,33 @@ var initCmd = &cli.Command{
 		if err = db.AutoMigrate(&model.CollectionCommentLike{}); err != nil {
 			return err
 		}
+		if err = db.AutoMigrate(&model.UploadJob{}); err != nil {
+			return err
+		}
+		if err = db.AutoMigrate(&model.UploadSession{}); err != nil {
+			return err
+		}
+		if err = db.AutoMigrate(&model.FileReplica{}); err != nil {
+			return err
+		}
+		if err = db.AutoMigrate(&model.Notification{}); err != nil {
+			return err
+		}
+		if err = db.AutoMigrate(&model.ChainEvent{}); err != nil {
+			return err
+		}
+		if err = db.AutoMigrate(&model.ChainCheckpoint{}); err != nil {
+			return err
+		}
+		if err = db.AutoMigrate(&model.FilePriceChange{}); err != nil {
+			return err
+		}
+		if err = db.AutoMigrate(&model.Withdrawal{}); err != nil {
+			return err
+		}
+		if err = db.AutoMigrate(&model.ChainTx{}); err != nil {
+			return err
+		}
 
 		log.Info("initialize saods succeed.")
 
@@ -200,13 +227,27 @@ var runCmd = &cli.Command{
 		if err != nil {
 			return err
 		}
+		if err = storeService.Start(ctx); err != nil {
+			return err
+		}
+
+		quotas, err := saoserver.NewQuotas(config.Quota)
+		if err != nil {
+			return err
+		}
 
 		server := saoserver.Server{
 			StoreService: storeService,
 			Model:        m,
 			Config:       config.ApiServer,
+			FileProcess:  config.FileProcess,
+			Quotas:       quotas,
 			Repodir:      cfgdir,
 		}
+		// resume the uploads interrupted by the last shutdown
+		server.StartUploadWorker(ctx)
+		server.StartExpiryWorker(ctx)
+
 		listen := fmt.Sprintf("%s:%d", config.ApiServer.Ip, config.ApiServer.Port)
 		log.Info("listening ", listen)
 		docs.SwaggerInfo.BasePath = config.ApiServer.ContextPath + "/api/v1"
@@ -219,6 +260,7 @@ var runCmd = &cli.Command{
 
 		finishCh := node.MonitorShutdown(
 			shutdownChan,
+			node.ShutdownHandler{Component: "store", StopFunc: storeService.Stop},
 		)
 		<-finishCh
 
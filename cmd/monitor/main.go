@@ -48,7 +48,7 @@ var runCmd = &cli.Command{
 			fmt.Println(err)
 			return nil
 		}
-		m.Run()
+		m.Run(cctx.Context)
 
 		return nil
 	},
@@ -149,7 +149,9 @@ var runCmd = &cli.Command{
 		}
 
 		procNode := proc.NewProcNode(ctx, n.Host, n.Wallet, m, config, cfgdir)
-		procNode.Start()
+		if err = procNode.Start(); err != nil {
+			return err
+		}
 
 		log.Info("process node server is started.")
 
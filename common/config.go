@@ -16,6 +16,9 @@ type MysqlInfo struct {
 
 type Transport struct {
 	MaxTransferDuration time.Duration
+	// how chunks are sent between the ds server and the proc nodes, "http"
+	// (default) over ApiServer.ExposedPath or "libp2p" CAR transfers
+	Type string
 }
 
 type ApiServerInfo struct {
@@ -25,11 +28,34 @@ type ApiServerInfo struct {
 	ContextPath  string
 	ExposedPath  string
 	PreviewsPath string
+	// max size of an uploaded file in bytes, 4GiB if not set
+	MaxUploadSize int64
 }
 
 type Libp2p struct {
 	ListenAddresses []string
 	DirectPeers     []string
+	// on procnodes, the ds servers allowed to request the encryption of chunks
+	DsPeers []string
+}
+
+type FileProcessInfo struct {
+	// number of chunks a paid file is split into, 2 if not set
+	ChunkCount int
+	// max size of a chunk in bytes, takes precedence over ChunkCount if set
+	ChunkSize int64
+	// max number of chunks encrypted at the same time, defaults to the number of proc nodes
+	EncryptWorkers int
+	// number of proc nodes holding a share of each chunk key, defaults to all proc nodes
+	KeyShares int
+	// number of key shares required to decrypt a chunk, defaults to a majority of KeyShares
+	KeyThreshold int
+	// number of times an upload is attempted before its preview is marked as failed, 5 if not set
+	MaxUploadAttempts int
+	// max bytes of decrypted chunks kept to serve downloads again, 1GiB if not set
+	DecryptCacheSize int64
+	// seconds a decrypted chunk is kept after it is last read, 10 minutes if not set
+	DecryptCacheTTL int
 }
 
 type MonitorInfo struct {
@@ -37,6 +63,12 @@ type MonitorInfo struct {
 	Contract    string
 	BlockNumber int64
 	Mnemonic    string
+	// derivation path of the account of Mnemonic which finishes the orders, m/44'/60'/0'/0/0 if not set
+	DerivationPath string
+	// blocks a log waits for on top of its own before it is processed, 6 if not set
+	Confirmations int
+	// seconds between two polls of the chain for new logs, 15 if not set
+	PollInterval int
 }
 
 type Config struct {
@@ -48,6 +80,56 @@ type Config struct {
 	PreviewsPath string
 	Transport    Transport
 	Mcs          McsInfo
+	FileProcess  FileProcessInfo
+	Storage      StorageInfo
+	Quota        QuotaInfo
+}
+
+// StorageInfo declares the store backends by name, in addition to the ones of
+// the ipfs and mcs sections.
+type StorageInfo struct {
+	// name of the store new files are stored on, mcs if enabled or ipfs if not set
+	Default string
+	Stores  []StoreInfo
+	// replication policies a file is stored with, by name
+	Policies []PolicyInfo
+	// policy of the files stored without one, only the default store if not set
+	DefaultPolicy string
+	// seconds between two checks of a replica, a day if not set
+	VerifyInterval int
+}
+
+// PolicyInfo is a replication policy, the stores each file of the policy is
+// replicated on. The file is stored on the first one, then copied to the
+// others in the background.
+type PolicyInfo struct {
+	Name   string
+	Stores []string
+}
+
+// QuotaInfo limits the bytes stored by each address, by tier.
+type QuotaInfo struct {
+	// tier of the addresses not listed in Users, unlimited if not set
+	DefaultTier string
+	Tiers       []QuotaTier
+	// tier of an address, by address
+	Users map[string]string
+}
+
+type QuotaTier struct {
+	Name string
+	// max bytes stored by an address, each replica counted, unlimited if 0
+	Space int64
+	// number of applications an address may use, 5 if not set
+	Applications int
+}
+
+type StoreInfo struct {
+	Name string
+	// backend type registered in the store package, e.g. "ipfs", "mcs" or "s3"
+	Type string
+	// backend specific settings, e.g. endpoint, bucket, accessKey and secretKey for s3
+	Params map[string]string
 }
 
 type IpfsInfo struct {
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"crypto/ecdsa"
 	"encoding/json"
+	"fmt"
 	"io"
 	"io/ioutil"
 	"math"
@@ -35,6 +36,27 @@ type UploadData struct {
 	WCid               string `json:"w_cid"`
 }
 
+type DealDetailResp struct {
+	Status  string         `json:"status"`
+	Data    DealDetailData `json:"data"`
+	Message string         `json:"message"`
+}
+type DealDetailData struct {
+	SourceFileUploadDeal SourceFileUploadDeal `json:"source_file_upload_deal"`
+}
+
+// SourceFileUploadDeal is the filecoin deal of an uploaded file, DealId is 0
+// until the deal is made.
+type SourceFileUploadDeal struct {
+	DealId     int64  `json:"deal_id"`
+	DealCid    string `json:"deal_cid"`
+	MessageCid string `json:"message_cid"`
+	Height     int64  `json:"height"`
+	PieceCid   string `json:"piece_cid"`
+	MinerFid   string `json:"miner_fid"`
+	Status     string `json:"status"`
+}
+
 type StatsResp struct {
 	Data    StatsData `json:"data"`
 	Status  string    `json:"status"`
@@ -152,6 +174,33 @@ func (s McsClient) Upload(filename string, reader io.Reader, options map[string]
 	}
 }
 
+// GetDealDetail returns the deal dealId of the uploaded file, or its latest
+// deal if dealId is 0.
+func (s McsClient) GetDealDetail(sourceFileUploadId int64, dealId int64) (*SourceFileUploadDeal, error) {
+	url := fmt.Sprintf("%s/storage/deal/detail/%d?source_file_upload_id=%d&wallet_address=%s", s.McsEndpoint, dealId, sourceFileUploadId, s.Address.Hex())
+	resp, err := http.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	resBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	jsonResp := DealDetailResp{}
+	if err = json.Unmarshal(resBody, &jsonResp); err != nil {
+		return nil, err
+	}
+
+	if jsonResp.Status == "success" {
+		return &jsonResp.Data.SourceFileUploadDeal, nil
+	} else {
+		return nil, xerrors.New(jsonResp.Message)
+	}
+}
+
 func (s McsClient) getParams() (*ParamData, error) {
 	resp, err := http.Get(s.McsEndpoint + "/common/system/params")
 	if err != nil {
@@ -0,0 +1,78 @@
+package model
+
+import "gorm.io/gorm/clause"
+
+// ChainEvent is a contract log the monitor processed. A log is processed
+// once, by its block hash and index.
+type ChainEvent struct {
+	SaoModel
+	Contract    string `gorm:"index;size:64;"`
+	BlockNumber uint64 `gorm:"index"`
+	BlockHash   string `gorm:"uniqueIndex:idx_chain_event;size:66;"`
+	LogIndex    uint   `gorm:"uniqueIndex:idx_chain_event"`
+	TxHash      string `gorm:"size:66;"`
+	// name of the event in the contract ABI
+	Name string `gorm:"size:64;"`
+	// arguments of the event as JSON
+	Data string `gorm:"type:text;"`
+}
+
+// ChainCheckpoint is where the monitor resumes processing the logs of a
+// contract.
+type ChainCheckpoint struct {
+	SaoModel
+	Contract string `gorm:"uniqueIndex;size:64;"`
+	// last block whose logs are all processed, and its hash to detect reorgs
+	BlockNumber uint64
+	BlockHash   string `gorm:"size:66;"`
+	// last processed log, in the block after BlockNumber if the monitor
+	// stopped in the middle of it
+	LogBlock uint64
+	LogIndex uint
+}
+
+// GetChainCheckpoint returns the checkpoint of the contract, a new one if
+// none is saved.
+func (model *Model) GetChainCheckpoint(contract string) (*ChainCheckpoint, error) {
+	var checkpoint ChainCheckpoint
+	err := model.DB.Where(ChainCheckpoint{Contract: contract}).FirstOrInit(&checkpoint).Error
+	return &checkpoint, err
+}
+
+func (model *Model) SaveChainCheckpoint(checkpoint *ChainCheckpoint) error {
+	return model.DB.Save(checkpoint).Error
+}
+
+// CreateChainEvent records the event, and tells whether it was not recorded
+// already.
+func (model *Model) CreateChainEvent(event *ChainEvent) (bool, error) {
+	result := model.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(event)
+	return result.RowsAffected > 0, result.Error
+}
+
+// GetLastChainEventBefore returns the last event of the contract in a block
+// before blockNumber.
+func (model *Model) GetLastChainEventBefore(contract string, blockNumber uint64) (*ChainEvent, error) {
+	var event ChainEvent
+	err := model.DB.Where("contract = ? AND block_number < ?", contract, blockNumber).
+		Order("block_number desc, log_index desc").First(&event).Error
+	if err != nil {
+		return nil, err
+	}
+	return &event, nil
+}
+
+// GetChainEventsAfter returns the events of the contract in the blocks after
+// blockNumber, the last one first.
+func (model *Model) GetChainEventsAfter(contract string, blockNumber uint64) ([]ChainEvent, error) {
+	var events []ChainEvent
+	err := model.DB.Where("contract = ? AND block_number > ?", contract, blockNumber).
+		Order("block_number desc, log_index desc").Find(&events).Error
+	return events, err
+}
+
+// DeleteChainEvent forgets the event, so that it is processed again if its
+// block is back on the chain.
+func (model *Model) DeleteChainEvent(event *ChainEvent) error {
+	return model.DB.Unscoped().Delete(event).Error
+}
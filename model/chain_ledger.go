@@ -0,0 +1,117 @@
+package model
+
+import (
+	"errors"
+	"math/big"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// FilePriceChange is a price set on chain for the token of a file, when the
+// token is listed or its price changed.
+type FilePriceChange struct {
+	SaoModel
+	// the Listing or ChangePrice event of the change
+	ChainEventId  uint `gorm:"uniqueIndex"`
+	FilePreviewId uint `gorm:"index"`
+	TokenId       int64
+	// token of the file before the change, TokenId unless it is a listing
+	OldTokenId int64
+	OldPrice   decimal.Decimal `gorm:"type:decimal(32,18);"`
+	Price      decimal.Decimal `gorm:"type:decimal(32,18);"`
+	ChangedAt  time.Time
+}
+
+// Withdrawal is an amount a seller, or the contract admin for the fees,
+// withdrew from the contract.
+type Withdrawal struct {
+	SaoModel
+	// the Withdraw event of the withdrawal
+	ChainEventId uint            `gorm:"uniqueIndex"`
+	EthAddr      string          `gorm:"index;size:64;"`
+	Amount       decimal.Decimal `gorm:"type:decimal(32,18);"`
+	WithdrawnAt  time.Time
+	TxHash       string `gorm:"size:66;"`
+}
+
+// ListFile links the file to the token minted for it and sets its price in
+// wei, recording the previous token and price.
+func (model *Model) ListFile(chainEventId uint, fileId int64, tokenId *big.Int, price *big.Int, listedAt time.Time) error {
+	filePreview, err := model.GetFilePreviewById(uint(fileId))
+	if err != nil {
+		return err
+	}
+	change := FilePriceChange{
+		ChainEventId:  chainEventId,
+		FilePreviewId: filePreview.Id,
+		TokenId:       tokenId.Int64(),
+		OldTokenId:    filePreview.NftTokenId,
+		OldPrice:      filePreview.Price,
+		Price:         decimal.NewFromBigInt(price, -18),
+		ChangedAt:     listedAt,
+	}
+	if err = model.DB.Create(&change).Error; err != nil {
+		return err
+	}
+	return model.DB.Model(&FilePreview{}).Where("id = ?", filePreview.Id).
+		Updates(map[string]interface{}{"price": change.Price, "nft_token_id": change.TokenId}).Error
+}
+
+// ChangeFilePrice sets the price in wei of the file of the token, recording
+// the previous one.
+func (model *Model) ChangeFilePrice(chainEventId uint, tokenId int64, price *big.Int, changedAt time.Time) error {
+	filePreview, err := model.GetFilePreviewByTokenId(tokenId)
+	if err != nil {
+		return err
+	}
+	change := FilePriceChange{
+		ChainEventId:  chainEventId,
+		FilePreviewId: filePreview.Id,
+		TokenId:       tokenId,
+		OldTokenId:    tokenId,
+		OldPrice:      filePreview.Price,
+		Price:         decimal.NewFromBigInt(price, -18),
+		ChangedAt:     changedAt,
+	}
+	if err = model.DB.Create(&change).Error; err != nil {
+		return err
+	}
+	return model.DB.Model(&FilePreview{}).Where("id = ?", filePreview.Id).Update("price", change.Price).Error
+}
+
+// RevertFilePriceChange sets the price and the token of the file back to the
+// ones before the change of the event.
+func (model *Model) RevertFilePriceChange(chainEventId uint) error {
+	var change FilePriceChange
+	err := model.DB.Where("chain_event_id = ?", chainEventId).First(&change).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	err = model.DB.Model(&FilePreview{}).Where("id = ?", change.FilePreviewId).
+		Updates(map[string]interface{}{"price": change.OldPrice, "nft_token_id": change.OldTokenId}).Error
+	if err != nil {
+		return err
+	}
+	return model.DB.Unscoped().Delete(&change).Error
+}
+
+// GetFilePriceChanges returns the price changes of the file, the latest
+// first.
+func (model *Model) GetFilePriceChanges(filePreviewId uint) ([]FilePriceChange, error) {
+	var changes []FilePriceChange
+	err := model.DB.Where("file_preview_id = ?", filePreviewId).Order("id desc").Find(&changes).Error
+	return changes, err
+}
+
+func (model *Model) CreateWithdrawal(withdrawal *Withdrawal) error {
+	return model.DB.Create(withdrawal).Error
+}
+
+func (model *Model) DeleteWithdrawal(chainEventId uint) error {
+	return model.DB.Unscoped().Where("chain_event_id = ?", chainEventId).Delete(&Withdrawal{}).Error
+}
@@ -0,0 +1,85 @@
+package model
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestRevertFilePriceChange(t *testing.T) {
+	cases := []struct {
+		name string
+		// the listings and changes of the file, one chain event each
+		apply  func(m *Model) error
+		revert uint
+		token  int64
+		price  int64
+	}{
+		{
+			name: "listing",
+			apply: func(m *Model) error {
+				return m.ListFile(1, 1, big.NewInt(1), big.NewInt(1e15), time.Now())
+			},
+			revert: 1,
+			token:  0,
+			price:  0,
+		},
+		{
+			name: "price change",
+			apply: func(m *Model) error {
+				if err := m.ListFile(1, 1, big.NewInt(1), big.NewInt(1e15), time.Now()); err != nil {
+					return err
+				}
+				return m.ChangeFilePrice(2, 1, big.NewInt(2e15), time.Now())
+			},
+			revert: 2,
+			token:  1,
+			price:  1e15,
+		},
+		{
+			name: "listing of another token",
+			apply: func(m *Model) error {
+				if err := m.ListFile(1, 1, big.NewInt(1), big.NewInt(1e15), time.Now()); err != nil {
+					return err
+				}
+				return m.ListFile(2, 1, big.NewInt(2), big.NewInt(3e15), time.Now())
+			},
+			revert: 2,
+			token:  1,
+			price:  1e15,
+		},
+		{
+			name: "no change recorded",
+			apply: func(m *Model) error {
+				return m.ListFile(1, 1, big.NewInt(1), big.NewInt(1e15), time.Now())
+			},
+			revert: 3,
+			token:  1,
+			price:  1e15,
+		},
+	}
+	for _, c := range cases {
+		m := newTestModel(t, &FilePreview{}, &FilePriceChange{})
+		if err := m.DB.Create(&FilePreview{SaoModel: SaoModel{Id: 1}}).Error; err != nil {
+			t.Fatal(err)
+		}
+		if err := c.apply(m); err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		if err := m.RevertFilePriceChange(c.revert); err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		filePreview, err := m.GetFilePreviewById(1)
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		if filePreview.NftTokenId != c.token {
+			t.Errorf("%s: token %d after the revert, want %d", c.name, filePreview.NftTokenId, c.token)
+		}
+		if price := decimal.NewFromBigInt(big.NewInt(c.price), -18); !filePreview.Price.Equal(price) {
+			t.Errorf("%s: price %s after the revert, want %s", c.name, filePreview.Price, price)
+		}
+	}
+}
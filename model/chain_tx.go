@@ -0,0 +1,84 @@
+package model
+
+import (
+	"strings"
+	"time"
+)
+
+type ChainTxStatus string
+
+const (
+	// the tx is sent, its receipt is not found yet
+	ChainTxPending ChainTxStatus = "Pending"
+	// the tx is mined and succeeded
+	ChainTxConfirmed ChainTxStatus = "Confirmed"
+	// the tx is mined and reverted
+	ChainTxFailed ChainTxStatus = "Failed"
+)
+
+// ChainTx is a tx the monitor sent, with the fees it was last sent with. A
+// tx whose fees are bumped keeps its nonce, the hashes of the txs it
+// replaced are kept to find the one mined.
+type ChainTx struct {
+	SaoModel
+	FromAddr string `gorm:"uniqueIndex:idx_chain_tx_nonce;size:64;"`
+	Nonce    uint64 `gorm:"uniqueIndex:idx_chain_tx_nonce"`
+	ToAddr   string `gorm:"size:64;"`
+	// hex calldata
+	Data     string `gorm:"type:text;"`
+	GasLimit uint64
+	// in wei
+	GasTipCap string `gorm:"size:78;"`
+	GasFeeCap string `gorm:"size:78;"`
+	Hash      string `gorm:"index;size:66;"`
+	// comma separated
+	ReplacedHashes string        `gorm:"type:text;"`
+	Status         ChainTxStatus `gorm:"index;size:16;"`
+	SentAt         time.Time
+	// the purchase order the tx finishes, if any
+	OrderId *uint
+}
+
+// Hashes returns the hash of the tx and of the ones it replaced, the latest
+// first.
+func (tx *ChainTx) Hashes() []string {
+	hashes := []string{tx.Hash}
+	if tx.ReplacedHashes == "" {
+		return hashes
+	}
+	replaced := strings.Split(tx.ReplacedHashes, ",")
+	for i := len(replaced) - 1; i >= 0; i-- {
+		hashes = append(hashes, replaced[i])
+	}
+	return hashes
+}
+
+func (model *Model) CreateChainTx(tx *ChainTx) error {
+	return model.DB.Create(tx).Error
+}
+
+func (model *Model) SaveChainTx(tx *ChainTx) error {
+	return model.DB.Save(tx).Error
+}
+
+func (model *Model) DeleteChainTx(tx *ChainTx) error {
+	return model.DB.Unscoped().Delete(tx).Error
+}
+
+// GetPendingChainTxs returns the pending txs sent from the address, by nonce.
+func (model *Model) GetPendingChainTxs(fromAddr string) ([]ChainTx, error) {
+	var txs []ChainTx
+	err := model.DB.Where("from_addr = ? AND status = ?", fromAddr, ChainTxPending).Order("nonce").Find(&txs).Error
+	return txs, err
+}
+
+// GetNextChainTxNonce returns the nonce after the ones of the txs sent from
+// the address.
+func (model *Model) GetNextChainTxNonce(fromAddr string) (uint64, error) {
+	var txs []ChainTx
+	err := model.DB.Where("from_addr = ?", fromAddr).Order("nonce desc").Limit(1).Find(&txs).Error
+	if err != nil || len(txs) == 0 {
+		return 0, err
+	}
+	return txs[0].Nonce + 1, nil
+}
@@ -6,6 +6,7 @@ import (
 	"github.com/gwaylib/log"
 	"golang.org/x/xerrors"
 	"gorm.io/gorm"
+	"strconv"
 	"strings"
 )
 
@@ -327,7 +328,7 @@ func (model *Model) UnlikeCollection(ethAddress string, collectionId uint) error
 		var count int64
 		tx.Model(&CollectionLike{}).Where("eth_addr = ? and collection_id = ? ", ethAddress, collectionId).Count(&count)
 		if count <= 0 {
-			return errors.New("the user" + ethAddress + " haven't clicked like yet:" + string(collectionId))
+			return errors.New("the user" + ethAddress + " haven't clicked like yet:" + strconv.FormatUint(uint64(collectionId), 10))
 		}
 
 		if err := tx.Where("eth_addr = ? and collection_id = ? ", ethAddress, collectionId).Delete(&CollectionLike{}).Error; err != nil {
@@ -362,7 +363,7 @@ func (model *Model) DeleteStarCollection(ethAddress string, collectionId uint) e
 		var count int64
 		tx.Model(&CollectionStar{}).Where("eth_addr = ? and collection_id = ? ", ethAddress, collectionId).Count(&count)
 		if count <= 0 {
-			return errors.New("the user" + ethAddress + " haven't clicked like yet:" + string(collectionId))
+			return errors.New("the user" + ethAddress + " haven't clicked like yet:" + strconv.FormatUint(uint64(collectionId), 10))
 		}
 
 		if err := tx.Where("eth_addr = ? and collection_id = ? ", ethAddress, collectionId).Delete(&CollectionStar{}).Error; err != nil {
@@ -6,6 +6,11 @@ const (
 	ContractOrdered OrderState = "ContractOrdered"
 	ReadyToDownload OrderState = "ReadyToDownload"
 	FinishContractStarted OrderState = "FinishContractStarted"
+	// the finish tx is mined and succeeded, the order is Finish once its
+	// Download event is confirmed
+	FinishContractConfirmed OrderState = "FinishContractConfirmed"
+	// the finish tx reverted
+	FinishContractFailed OrderState = "FinishContractFailed"
 	Finish OrderState = "Finish"
 )
 
@@ -17,6 +22,17 @@ const (
 	UploadSuccess FilePreviewStatus = 1
 	// file is processed and uploaded to ipfs
 	PlacedToIpfs FilePreviewStatus = 2
+	// file processing failed after all retries
+	UploadFailed FilePreviewStatus = 3
+	// the stored file reached its expiry and was deleted
+	Expired FilePreviewStatus = 4
+)
+
+// status of a stored file
+const (
+	FileStored uint = 0
+	// the file reached ExpireAt and was deleted from its stores
+	FileExpired uint = 1
 )
 
 type FileCategory string
@@ -1,5 +1,7 @@
 package model
 
+import "gorm.io/gorm"
+
 type FileChunkMetadata struct {
 	SaoModel
 	FileId          uint
@@ -7,10 +9,38 @@ type FileChunkMetadata struct {
 	Size            int64
 	EncryptedOffset int64
 	EncryptedSize   int64
+	// proc node which encrypted the chunk
+	PeerId string
+	// comma separated proc nodes holding the chunk key shares
+	SharePeers string
+	// number of key shares required to decrypt the chunk, 0 if PeerId holds the whole key
+	Threshold int
 }
 
 func (model *Model) GetFileChunkMetadatasByFileId(fileId uint) []FileChunkMetadata {
 	var fileChunkMetadatas []FileChunkMetadata
 	model.DB.Where("file_id", fileId).Find(&fileChunkMetadatas)
 	return fileChunkMetadatas
+}
+
+// ReplaceFileChunkMetadata stores the metadata of an encrypted chunk, in place
+// of the one left by a previous attempt to encrypt it.
+func (model *Model) ReplaceFileChunkMetadata(chunkMetadata *FileChunkMetadata) error {
+	return model.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where(map[string]interface{}{"file_id": chunkMetadata.FileId, "offset": chunkMetadata.Offset}).Delete(&FileChunkMetadata{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(chunkMetadata).Error
+	})
+}
+
+func (model *Model) UpdateFileChunkEncryptedOffsets(chunkMetadatas []FileChunkMetadata) error {
+	return model.DB.Transaction(func(tx *gorm.DB) error {
+		for _, chunkMetadata := range chunkMetadatas {
+			if err := tx.Model(&FileChunkMetadata{}).Where("id", chunkMetadata.Id).Update("encrypted_offset", chunkMetadata.EncryptedOffset).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
 }
\ No newline at end of file
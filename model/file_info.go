@@ -18,6 +18,31 @@ type FileInfo struct {
 	Cid             string `json:"cid" gorm:"column:cid;type:varchar(255) ;default:''"`
 	StorageProvider string `json:"storageProvider" gorm:"column:storageProvider;type:varchar(255) ;default:''"`
 	Status          uint   `json:"-" gorm:"column:status;type:int(11)"`
+	// name of the store the file is stored on, and where the store finds it.
+	// Files stored before have no backend, they are on mcs if McsInfoId is set
+	// or on ipfs.
+	Backend string `json:"backend" gorm:"column:backend;type:varchar(64) ;default:''"`
+	Locator string `json:"locator" gorm:"column:locator;type:varchar(1024) ;default:''"`
+	// hex sha256 of the file as stored, which the replicas are checked against
+	Sha256 string `json:"-" gorm:"column:sha256;type:varchar(64) ;default:''"`
+	// piece commitment of the CAR of the file, whose payload CID is Cid, and
+	// the padded size of the piece a storage provider seals
+	PieceCid  string `json:"pieceCid" gorm:"column:pieceCid;type:varchar(255) ;default:''"`
+	PieceSize int64  `json:"pieceSize" gorm:"column:pieceSize"`
+	CarSize   int64  `json:"carSize" gorm:"column:carSize"`
+	// whether the owners were warned the file is about to expire
+	ExpiryWarned bool `json:"-" gorm:"column:expiryWarned"`
+}
+
+// ExpiringFile is a stored file about to expire, with one of its previews.
+type ExpiringFile struct {
+	FileId    uint
+	PreviewId uint
+	EthAddr   string
+	Title     string
+	Filename  string
+	// unix milliseconds the file expires at
+	ExpireAt int64
 }
 
 type McsInfo struct {
@@ -28,6 +53,17 @@ type McsInfo struct {
 	FileSize           int64
 	WCid               string
 	PaymentTxHash      string
+	// name of the store which uploaded the file, "mcs" if empty
+	Backend string
+	// when the storage was last paid for, CreatedAt if not set
+	PaidAt time.Time
+	// the filecoin deal of the file, as last reported by MCS
+	DealId          int64
+	DealCid         string
+	MessageCid      string
+	MinerFid        string
+	DealStatus      string
+	NextDealCheckAt time.Time `gorm:"index"`
 }
 
 type FileInfoInMarket struct {
@@ -57,9 +93,20 @@ type FileDetail struct {
 	IpfsHash        string
 	Size            int64
 	Cid             string
+	PieceCid        string
+	PieceSize       int64
 	StorageProvider string
-	TotalComments int64
+	// unix milliseconds the file expires at, -1 if it is kept forever
+	ExpireAt int64
+	// the filecoin deal of the file, if stored through MCS
+	DealId           int64
+	DealCid          string
+	DealStatus       string
+	PaymentTxHash    string
+	TotalComments    int64
 	TotalCollections int64
+	// the prices set on chain after the file was listed, the latest first
+	PriceHistory []FilePriceChange
 }
 
 type PagedFileInfoInMarket struct {
@@ -76,9 +123,18 @@ func (model *Model) CountFileByFilenameAndStatus(dest string, status int) (int64
 	return count, nil
 }
 
+func (model *Model) GetFileByFilenameAndStatus(dest string, status int) (*FileInfo, error) {
+	var file FileInfo
+	result := model.DB.Model(&FileInfo{}).Where("filename = ? and status = ?", dest, status).First(&file)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return &file, nil
+}
+
 func (model *Model) GetFileInfoByPreviewId(fileId uint) *FileInfo {
 	var file FileInfo
-	model.DB.Raw("SELECT i.id, i.contentType, i.ipfsHash, p.filename, i.mcsInfoId FROM file_infos i, file_previews p WHERE p.file_id = i.id and p.id = ?", fileId).Scan(&file)
+	model.DB.Raw("SELECT i.id, i.contentType, i.size, i.ipfsHash, p.filename, i.mcsInfoId, i.backend, i.locator FROM file_infos i, file_previews p WHERE p.file_id = i.id and p.id = ?", fileId).Scan(&file)
 	return &file
 }
 
@@ -104,10 +160,22 @@ func (model *Model) StoreFile(file FileInfo, mcsInfo *McsInfo) (*FileInfo, error
 				return err
 			}
 		} else {
+			expireAt := file.ExpireAt
 			if err := model.DB.Model(&FileInfo{}).Where("ipfsHash = ?", file.IpfsHash).Update("filename", file.Filename).Error; err != nil {
 				return err
 			}
 			model.DB.Where("ipfsHash = ? and status = 0", file.IpfsHash).First(&file)
+			// the file is kept as long as the longest of its uploads
+			if file.ExpireAt >= 0 && (expireAt < 0 || expireAt > file.ExpireAt) {
+				err := tx.Model(&FileInfo{}).Where("id = ?", file.Id).Updates(map[string]interface{}{
+					"expireAt":     expireAt,
+					"expiryWarned": false,
+				}).Error
+				if err != nil {
+					return err
+				}
+				file.ExpireAt = expireAt
+			}
 		}
 
 		return nil
@@ -115,11 +183,106 @@ func (model *Model) StoreFile(file FileInfo, mcsInfo *McsInfo) (*FileInfo, error
 	return &file, err
 }
 
+// GetExpiredFiles returns up to limit previews of the stored files whose
+// ExpireAt is before.
+func (model *Model) GetExpiredFiles(before int64, limit int) ([]ExpiringFile, error) {
+	return model.getExpiringFiles("", 0, before, limit)
+}
+
+// GetFilesToWarn returns up to limit previews of the stored files expiring
+// between after and before, whose owners were not warned yet.
+func (model *Model) GetFilesToWarn(after int64, before int64, limit int) ([]ExpiringFile, error) {
+	return model.getExpiringFiles("and i.expiryWarned = false", after, before, limit)
+}
+
+func (model *Model) getExpiringFiles(condition string, after int64, before int64, limit int) ([]ExpiringFile, error) {
+	var files []ExpiringFile
+	err := model.DB.Raw("SELECT i.id as file_id, p.id as preview_id, p.eth_addr, p.title, p.filename, i.expireAt as expire_at FROM file_infos i, file_previews p "+
+		"WHERE p.file_id = i.id and i.deleted_at is null and p.deleted_at is null and i.status = ? and i.expireAt > ? and i.expireAt <= ? "+condition+" ORDER BY i.expireAt LIMIT ?",
+		FileStored, after, before, limit).Scan(&files).Error
+	return files, err
+}
+
+func (model *Model) SetExpiryWarned(fileId uint) error {
+	return model.DB.Model(&FileInfo{}).Where("id = ?", fileId).Update("expiryWarned", true).Error
+}
+
+// ExpireFile marks the file and its previews as expired, once deleted from
+// its stores.
+func (model *Model) ExpireFile(fileId uint) error {
+	return model.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&FileInfo{}).Where("id = ?", fileId).Update("status", FileExpired).Error; err != nil {
+			return err
+		}
+		return tx.Model(&FilePreview{}).Where("file_id = ?", fileId).Update("status", Expired).Error
+	})
+}
+
+// RenewFile keeps the file until expireAt, and warns its owners again before.
+func (model *Model) RenewFile(fileId uint, expireAt int64) error {
+	return model.DB.Model(&FileInfo{}).Where("id = ?", fileId).Updates(map[string]interface{}{
+		"expireAt":     expireAt,
+		"expiryWarned": false,
+	}).Error
+}
+
+func (model *Model) GetFileByMcsInfoId(mcsInfoId uint) (*FileInfo, error) {
+	var file FileInfo
+	if err := model.DB.Where("mcsInfoId = ?", mcsInfoId).First(&file).Error; err != nil {
+		return nil, err
+	}
+	return &file, nil
+}
+
+// UpdateFileDeal records the CIDs of the CAR of file, which a deal is made
+// for.
+func (model *Model) UpdateFileDeal(file *FileInfo) error {
+	return model.DB.Model(file).Updates(map[string]interface{}{
+		"cid":       file.Cid,
+		"pieceCid":  file.PieceCid,
+		"pieceSize": file.PieceSize,
+		"carSize":   file.CarSize,
+	}).Error
+}
+
 func (model *Model) StoreMcsInfo(info *McsInfo) (*McsInfo, error) {
 	err := model.DB.Create(info).Error
 	return info, err
 }
 
+func (model *Model) SaveMcsInfo(info *McsInfo) error {
+	return model.DB.Save(info).Error
+}
+
+// SetFileMcsInfo records the MCS upload of a file which is replicated to MCS.
+func (model *Model) SetFileMcsInfo(file *FileInfo, info *McsInfo) error {
+	return model.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(info).Error; err != nil {
+			return err
+		}
+		file.McsInfoId = info.Id
+		return tx.Model(file).Update("mcsInfoId", info.Id).Error
+	})
+}
+
+// GetDueMcsInfos returns up to limit paid MCS uploads of the files not
+// deleted, whose deal is due to be checked at now.
+func (model *Model) GetDueMcsInfos(now time.Time, limit int) ([]McsInfo, error) {
+	var infos []McsInfo
+	err := model.DB.Joins("JOIN file_infos ON file_infos.mcsInfoId = mcs_infos.id AND file_infos.deleted_at IS NULL AND file_infos.status = 0").
+		Where("mcs_infos.payment_tx_hash <> '' and mcs_infos.next_deal_check_at <= ?", now).
+		Order("mcs_infos.next_deal_check_at").
+		Limit(limit).
+		Find(&infos).Error
+	return infos, err
+}
+
+// UpdateStorageProvider sets the storage provider of the files stored
+// through the MCS upload mcsInfoId.
+func (model *Model) UpdateStorageProvider(mcsInfoId uint, storageProvider string) error {
+	return model.DB.Model(&FileInfo{}).Where("mcsInfoId = ?", mcsInfoId).Update("storageProvider", storageProvider).Error
+}
+
 func (model *Model) GetMcsInfoById(id uint) (*McsInfo, error) {
 	var info McsInfo
 	result := model.DB.First(&info, id)
@@ -129,14 +292,12 @@ func (model *Model) GetMcsInfoById(id uint) (*McsInfo, error) {
 	return &info, nil
 }
 
-func (model *Model) DeleteFile(preview *FilePreview) (string, error) {
-	var ipfsHash string
+func (model *Model) DeleteFile(preview *FilePreview) (*FileInfo, error) {
+	var ipfsFileInfo FileInfo
 	err := model.DB.Transaction(func(tx *gorm.DB) error {
-		var ipfsFileInfo FileInfo
 		if err := tx.Model(&FileInfo{}).Where("id = ?", preview.FileId).Find(&ipfsFileInfo).Error; err != nil {
 			return errors.New("ipfs file not found in system")
 		}
-		ipfsHash = ipfsFileInfo.IpfsHash
 
 		if err := tx.Model(&CollectionFile{}).Where("file_id = ?", preview.Id).Delete(&CollectionFile{}).Error; err != nil {
 			return err
@@ -159,5 +320,5 @@ func (model *Model) DeleteFile(preview *FilePreview) (string, error) {
 		}
 		return nil
 	})
-	return ipfsHash, err
-}
\ No newline at end of file
+	return &ipfsFileInfo, err
+}
@@ -8,6 +8,7 @@ import (
 	"github.com/shopspring/decimal"
 	"math/big"
 	"path/filepath"
+	"strconv"
 
 	"gorm.io/gorm"
 )
@@ -29,6 +30,15 @@ type FilePreview struct {
 	FileCategory   FileCategory
 	NftTokenId     int64
 	AdditionalInfo string
+	// hex sha256 of the uploaded file
+	Sha256 string
+	// name of the replication policy the file is stored with, the default
+	// one if empty
+	Replication string
+	// days the file is kept, forever if 0
+	Duration int64
+	// bytes of the uploaded file
+	Size int64
 }
 
 type FileStar struct {
@@ -115,6 +125,10 @@ func (model *Model) GetFileInfo(fileId uint, ethAddress string) (*FileDetail, er
 	if err := model.DB.Model(&FileInfo{}).Where("id = ?", filePreview.FileId).Find(&ipfsFileInfo).Error; err != nil {
 		return nil, errors.New("ipfs file not found in system")
 	}
+	var mcsInfo McsInfo
+	if ipfsFileInfo.McsInfoId > 0 {
+		model.DB.First(&mcsInfo, ipfsFileInfo.McsInfoId)
+	}
 	fileExtension := filepath.Ext(filePreview.Filename)
 	if fileExtension != "" {
 		fileExtension = fileExtension[1:]
@@ -129,6 +143,11 @@ func (model *Model) GetFileInfo(fileId uint, ethAddress string) (*FileDetail, er
 		log.Error(err)
 	}
 
+	priceHistory, err := model.GetFilePriceChanges(filePreview.Id)
+	if err != nil {
+		log.Error(err)
+	}
+
 	filesInfoInMarket := FileDetail{
 		FileInfoInMarket: FileInfoInMarket{Id: filePreview.Id,
 			CreatedAt:      filePreview.CreatedAt,
@@ -151,7 +170,15 @@ func (model *Model) GetFileInfo(fileId uint, ethAddress string) (*FileDetail, er
 		IpfsHash:         ipfsFileInfo.IpfsHash,
 		Size:             ipfsFileInfo.Size,
 		Cid:              ipfsFileInfo.Cid,
+		PieceCid:         ipfsFileInfo.PieceCid,
+		PieceSize:        ipfsFileInfo.PieceSize,
 		StorageProvider:  ipfsFileInfo.StorageProvider,
+		ExpireAt:         ipfsFileInfo.ExpireAt,
+		DealId:           mcsInfo.DealId,
+		DealCid:          mcsInfo.DealCid,
+		DealStatus:       mcsInfo.DealStatus,
+		PaymentTxHash:    mcsInfo.PaymentTxHash,
+		PriceHistory:     priceHistory,
 		TotalComments:    TotalComments,
 		TotalCollections: TotalCollections}
 	return &filesInfoInMarket, nil
@@ -332,10 +359,6 @@ func (model *Model) GetMarketFiles(limit int, offset int, ethAddress string, con
 	return filesInfoInMarket, count
 }
 
-func (model *Model) UpdatePreviewLinkedWithIpfs(Id uint, updates map[string]interface{}) error {
-	return model.DB.Model(&FilePreview{}).Where("id", Id).Updates(updates).Error
-}
-
 func (model *Model) UpdatePreview(Id uint, updates map[string]interface{}) error {
 	err := model.DB.Transaction(func(tx *gorm.DB) error {
 		var err error
@@ -350,27 +373,9 @@ func (model *Model) UpdatePreview(Id uint, updates map[string]interface{}) error
 	return err
 }
 
-func (model *Model) UpdatePreviewPriceAndTokenId(fileId int64, price *big.Int, tokenId *big.Int) error {
-	ethPrice := decimal.NewFromBigInt(price, -18)
-	return model.DB.Model(&FilePreview{}).Where("ID = ?", uint(fileId)).Update("price", ethPrice).Update("nft_token_id", tokenId.Int64()).Error
-}
-
-func (model *Model) StoreFileMetadata(chunkMetadatas []FileChunkMetadata, fileId uint, previewId int64) error {
-	err := model.DB.Transaction(func(tx *gorm.DB) error {
-		var err error
-		if err = tx.Create(chunkMetadatas).Error; err != nil {
-			return err
-		}
-		updateMap := map[string]interface{}{
-			"Status": PlacedToIpfs,
-			"FileId": fileId,
-		}
-		if err = tx.Model(&FilePreview{}).Where("id", previewId).Updates(updateMap).Error; err != nil {
-			return err
-		}
-		return nil
-	})
-	return err
+// ClearPreviewTokenId unlinks the preview of the token from it.
+func (model *Model) ClearPreviewTokenId(tokenId *big.Int) error {
+	return model.DB.Model(&FilePreview{}).Where("nft_token_id = ?", tokenId.Int64()).Update("nft_token_id", 0).Error
 }
 
 func (model *Model) StarFile(ethAddress string, fileId uint) error {
@@ -396,7 +401,7 @@ func (model *Model) DeleteStarFile(ethAddress string, fileId uint) error {
 		var count int64
 		tx.Model(&FileStar{}).Where("eth_addr = ? and file_preview_id = ? ", ethAddress, fileId).Count(&count)
 		if count <= 0 {
-			return errors.New("the user" + ethAddress + " haven't clicked star yet:" + string(fileId))
+			return errors.New("the user" + ethAddress + " haven't clicked star yet:" + strconv.FormatUint(uint64(fileId), 10))
 		}
 
 		if err := tx.Where("eth_addr = ? and file_preview_id = ? ", ethAddress, fileId).Delete(&FileStar{}).Error; err != nil {
@@ -0,0 +1,81 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type FileReplicaStatus string
+
+const (
+	// the replica is to be copied from another one
+	FileReplicaPending FileReplicaStatus = "Pending"
+	// the replica is stored and its hash was checked at VerifiedAt
+	FileReplicaStored FileReplicaStatus = "Stored"
+	// the replica could not be read back or its hash is wrong, it is to be
+	// copied again
+	FileReplicaFailed FileReplicaStatus = "Failed"
+)
+
+// FileReplica is a copy of a stored file on one of the backends of its
+// replication policy.
+type FileReplica struct {
+	SaoModel
+	FileId  uint   `gorm:"uniqueIndex:idx_file_replica"`
+	Backend string `gorm:"uniqueIndex:idx_file_replica;size:64;"`
+	// where the backend finds the replica
+	Locator     string `gorm:"size:1024;"`
+	Status      FileReplicaStatus
+	VerifiedAt  time.Time
+	NextCheckAt time.Time `gorm:"index"`
+	Error       string    `gorm:"type:text;"`
+}
+
+// AddFileReplicas records the replicas of a file which are not recorded yet.
+func (model *Model) AddFileReplicas(replicas []FileReplica) error {
+	return model.DB.Transaction(func(tx *gorm.DB) error {
+		for _, replica := range replicas {
+			err := tx.Where(FileReplica{FileId: replica.FileId, Backend: replica.Backend}).FirstOrCreate(&replica).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (model *Model) GetFileReplicas(fileId uint) ([]FileReplica, error) {
+	var replicas []FileReplica
+	err := model.DB.Where("file_id = ?", fileId).Order("id").Find(&replicas).Error
+	return replicas, err
+}
+
+// GetDueFileReplicas returns up to limit replicas of the files not deleted,
+// which are due to be copied or verified at now.
+func (model *Model) GetDueFileReplicas(now time.Time, limit int) ([]FileReplica, error) {
+	var replicas []FileReplica
+	err := model.DB.Joins("JOIN file_infos ON file_infos.id = file_replicas.file_id AND file_infos.deleted_at IS NULL").
+		Where("file_replicas.next_check_at <= ?", now).
+		Order("file_replicas.next_check_at").
+		Limit(limit).
+		Find(&replicas).Error
+	return replicas, err
+}
+
+func (model *Model) SaveFileReplica(replica *FileReplica) error {
+	return model.DB.Save(replica).Error
+}
+
+func (model *Model) DeleteFileReplica(replica *FileReplica) error {
+	return model.DB.Unscoped().Delete(replica).Error
+}
+
+// GetFileInfoById returns the stored file, even if it is deleted.
+func (model *Model) GetFileInfoById(fileId uint) (*FileInfo, error) {
+	var file FileInfo
+	if err := model.DB.Unscoped().First(&file, fileId).Error; err != nil {
+		return nil, err
+	}
+	return &file, nil
+}
@@ -1,5 +1,15 @@
 package model
 
+import (
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+// ErrKeyStoreOwner is returned when the key of a chunk would replace a key
+// stored for another owner.
+var ErrKeyStoreOwner = errors.New("key of the chunk stored for another owner")
+
 type KeyStore struct {
 	Id     string
 	FileId string
@@ -7,14 +17,45 @@ type KeyStore struct {
 	Size   uint64
 	Key    []byte
 	Nonce  []byte
+	// number of shares required to recover the key, 0 if Key is the whole key
+	Threshold int
+	// file owner address, allowed to decrypt without purchase
+	Owner string
+	// format of the encrypted chunk, 0 for the chunks sealed whole before
+	// the segmented format
+	EncryptionVersion int
 }
 
 func (model *Model) CreateKeyStore(keyStore *KeyStore) error {
 	return model.DB.Create(keyStore).Error
 }
 
+// ReplaceKeyStore stores the key of a chunk in place of the one left by a
+// previous attempt of the same owner to encrypt it. The key of another owner
+// is never replaced, ErrKeyStoreOwner is returned instead.
+func (model *Model) ReplaceKeyStore(keyStore *KeyStore) error {
+	return model.DB.Transaction(func(tx *gorm.DB) error {
+		condition := map[string]interface{}{"file_id": keyStore.FileId, "offset": keyStore.Offset, "size": keyStore.Size}
+		var previous []KeyStore
+		if err := tx.Where(condition).Find(&previous).Error; err != nil {
+			return err
+		}
+		for _, k := range previous {
+			if k.Owner != keyStore.Owner {
+				return ErrKeyStoreOwner
+			}
+		}
+
+		err := tx.Where(condition).Delete(&KeyStore{}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Create(keyStore).Error
+	})
+}
+
 func (model *Model) GetKeyStore(condition map[string]interface{}, offset uint64) (KeyStore, error) {
 	var keyStore KeyStore
 	err := model.DB.Model(&KeyStore{}).Where(condition).Where("Offset", offset).First(&keyStore).Error
 	return keyStore, err
-}
\ No newline at end of file
+}
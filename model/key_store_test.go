@@ -0,0 +1,52 @@
+package model
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func newTestModel(t *testing.T, tables ...interface{}) *Model {
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "test.db")), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = db.AutoMigrate(tables...); err != nil {
+		t.Fatal(err)
+	}
+	return &Model{DB: db}
+}
+
+func TestReplaceKeyStore(t *testing.T) {
+	m := newTestModel(t, &KeyStore{})
+	if err := m.CreateKeyStore(&KeyStore{Id: "1", FileId: "7", Offset: 0, Size: 10, Key: []byte("owner"), Owner: "0x01"}); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name     string
+		keyStore KeyStore
+		err      error
+		key      string
+	}{
+		{"other owner", KeyStore{Id: "2", FileId: "7", Offset: 0, Size: 10, Key: []byte("attacker"), Owner: "0x03"}, ErrKeyStoreOwner, "owner"},
+		{"same owner", KeyStore{Id: "3", FileId: "7", Offset: 0, Size: 10, Key: []byte("retry"), Owner: "0x01"}, nil, "retry"},
+		{"new chunk", KeyStore{Id: "4", FileId: "7", Offset: 10, Size: 10, Key: []byte("other"), Owner: "0x03"}, nil, "other"},
+	}
+	for _, c := range cases {
+		err := m.ReplaceKeyStore(&c.keyStore)
+		if !errors.Is(err, c.err) {
+			t.Errorf("%s: got error %v, want %v", c.name, err, c.err)
+		}
+		keyStore, err := m.GetKeyStore(map[string]interface{}{"file_id": c.keyStore.FileId, "size": c.keyStore.Size}, c.keyStore.Offset)
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		if string(keyStore.Key) != c.key {
+			t.Errorf("%s: stored key %q, want %q", c.name, keyStore.Key, c.key)
+		}
+	}
+}
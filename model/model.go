@@ -38,3 +38,14 @@ func NewModel(connString string, debug bool, config *common.Config) (*Model, err
 		Config: config,
 	}, nil
 }
+
+// Transaction calls fn with a model whose queries run in a transaction,
+// committed if fn returns nil.
+func (model *Model) Transaction(fn func(tx *Model) error) error {
+	return model.DB.Transaction(func(db *gorm.DB) error {
+		return fn(&Model{
+			DB:     db,
+			Config: model.Config,
+		})
+	})
+}
@@ -0,0 +1,39 @@
+package model
+
+type NotificationType string
+
+const (
+	// a file of the user is about to expire
+	NotifyFileExpiring NotificationType = "FileExpiring"
+	// a file of the user expired and was deleted
+	NotifyFileExpired NotificationType = "FileExpired"
+)
+
+// Notification is a message to a user about their files.
+type Notification struct {
+	SaoModel
+	EthAddr string `gorm:"index"`
+	Type    NotificationType
+	// the file preview the notification is about
+	FileId  uint
+	Message string `gorm:"type:text;"`
+}
+
+type PagedNotifications struct {
+	Notifications []Notification
+	Total         int64
+}
+
+func (model *Model) CreateNotification(notification *Notification) error {
+	return model.DB.Create(notification).Error
+}
+
+// GetNotifications returns the notifications of the user, the latest first.
+func (model *Model) GetNotifications(ethAddress string, offset int, limit int) (*PagedNotifications, error) {
+	var paged PagedNotifications
+	if err := model.DB.Model(&Notification{}).Where("eth_addr = ?", ethAddress).Count(&paged.Total).Error; err != nil {
+		return nil, err
+	}
+	err := model.DB.Where("eth_addr = ?", ethAddress).Order("id desc").Offset(offset).Limit(limit).Find(&paged.Notifications).Error
+	return &paged, err
+}
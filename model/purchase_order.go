@@ -1,6 +1,10 @@
 package model
 
-import "time"
+import (
+	"time"
+
+	"gorm.io/gorm/clause"
+)
 
 type PurchaseOrder struct {
 	Id             uint	`gorm:"autoIncrement:false"`
@@ -10,6 +14,9 @@ type PurchaseOrder struct {
 	CompleteTxHash string
 	State          OrderState
 	UpdatedAt    time.Time
+	// failed attempts to send the finish tx, tried again after NextFinishAt
+	FinishAttempts int
+	NextFinishAt   *time.Time
 }
 
 func (model *Model) GetPurchaseOrder(fileId uint, ethAddress string) *PurchaseOrder {
@@ -18,32 +25,64 @@ func (model *Model) GetPurchaseOrder(fileId uint, ethAddress string) *PurchaseOr
 	return &purchaseOrder
 }
 
-func (model *Model) GetNextPurchaseOrderToFinish() (*PurchaseOrder, bool) {
-	var count int64
-	model.DB.Model(&PurchaseOrder{}).Where("state = ?", FinishContractStarted).Where("updated_at > ?", time.Now().Add(-time.Minute * 5)).Count(&count)
-	if count >0 {
-		return nil, false
-	}
+// GetNextPurchaseOrderToFinish returns the first order downloaded by its
+// buyer whose finish tx is not sent yet, and is due at now.
+func (model *Model) GetNextPurchaseOrderToFinish(now time.Time) (*PurchaseOrder, bool) {
 	var purchaseOrder PurchaseOrder
 
-	// the contract started but not finished - retry
-	model.DB.Model(&PurchaseOrder{}).Where("state = ?", FinishContractStarted).Where("updated_at < ?", time.Now().Add(-time.Minute * 5)).First(&purchaseOrder)
-	if purchaseOrder.Id > 0 {
-		return &purchaseOrder, true
-	}
-
-	model.DB.Model(&PurchaseOrder{}).Where("state = ?", ReadyToDownload).First(&purchaseOrder)
-	if purchaseOrder.Id > 0 {
+	// the orders started before the txs were recorded are sent again, the
+	// first order of the contract has id 0
+	err := model.DB.Model(&PurchaseOrder{}).
+		Where("state = ? or (state = ? and complete_tx_hash = '')", ReadyToDownload, FinishContractStarted).
+		Where("next_finish_at is null or next_finish_at <= ?", now).
+		Order("id").First(&purchaseOrder).Error
+	if err == nil {
 		return &purchaseOrder, true
 	} else {
 		return nil, false
 	}
 }
 
+// CreatePurchaseOrder creates the order, unless an order with its id exists.
 func (model *Model) CreatePurchaseOrder(purchaseOrder map[string]interface{}) error {
-	return model.DB.Model(&PurchaseOrder{}).Create(purchaseOrder).Error
+	return model.DB.Model(&PurchaseOrder{}).Clauses(clause.OnConflict{DoNothing: true}).Create(purchaseOrder).Error
+}
+
+func (model *Model) DeletePurchaseOrder(orderId uint) error {
+	return model.DB.Delete(&PurchaseOrder{}, orderId).Error
 }
 
 func (model *Model) UpdatePurchaseOrderState(orderId uint, state OrderState) error {
 	return model.DB.Model(&PurchaseOrder{}).Where("Id = ?", orderId).Update("state", state).Error
+}
+
+// StartFinishPurchaseOrder records the finish tx of the order.
+func (model *Model) StartFinishPurchaseOrder(orderId uint, txHash string) error {
+	return model.DB.Model(&PurchaseOrder{}).Where("Id = ?", orderId).Updates(map[string]interface{}{
+		"state":            FinishContractStarted,
+		"complete_tx_hash": txHash,
+	}).Error
+}
+
+// DelayFinishPurchaseOrder records a failed attempt to send the finish tx of
+// the order, which is tried again after next.
+func (model *Model) DelayFinishPurchaseOrder(orderId uint, attempts int, next time.Time) error {
+	return model.DB.Model(&PurchaseOrder{}).Where("Id = ?", orderId).Updates(map[string]interface{}{
+		"finish_attempts": attempts,
+		"next_finish_at":  next,
+	}).Error
+}
+
+// FailFinishPurchaseOrder gives up the order after the failed attempts to
+// send its finish tx.
+func (model *Model) FailFinishPurchaseOrder(orderId uint, attempts int) error {
+	return model.DB.Model(&PurchaseOrder{}).Where("Id = ?", orderId).Updates(map[string]interface{}{
+		"state":           FinishContractFailed,
+		"finish_attempts": attempts,
+	}).Error
+}
+
+// UpdatePurchaseOrderStateFrom sets the state of the order if it is from.
+func (model *Model) UpdatePurchaseOrderStateFrom(orderId uint, from OrderState, to OrderState) error {
+	return model.DB.Model(&PurchaseOrder{}).Where("Id = ? and state = ?", orderId, from).Update("state", to).Error
 }
\ No newline at end of file
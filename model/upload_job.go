@@ -0,0 +1,114 @@
+package model
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type UploadJobState string
+
+const (
+	// the preview is submitted, the file is not processed yet
+	UploadJobCreated UploadJobState = "Created"
+	// the file is split into ChunkCount chunks
+	UploadJobSplit UploadJobState = "Split"
+	// EncryptedChunks of the chunks are encrypted by the proc nodes
+	UploadJobEncrypting UploadJobState = "Encrypting"
+	// the encrypted chunks are combined into one file
+	UploadJobCombined UploadJobState = "Combined"
+	// the file is stored on ipfs/filecoin as FileId
+	UploadJobUploaded UploadJobState = "Uploaded"
+	// the preview is linked with the stored file
+	UploadJobLinked UploadJobState = "Linked"
+)
+
+// UploadJob keeps track of a preview going through the upload pipeline, so
+// that an upload interrupted by a crash or a failed step is resumed from the
+// last completed state.
+type UploadJob struct {
+	SaoModel
+	PreviewId       uint `gorm:"uniqueIndex"`
+	Encrypt         bool
+	State           UploadJobState
+	ChunkCount      int
+	EncryptedChunks int
+	FileId          uint
+	Attempts        int
+	NextAttemptAt   time.Time
+	// set once the job gave up retrying, the preview is UploadFailed
+	Failed bool
+	Error  string `gorm:"type:text;"`
+}
+
+// SubmitUploadJob creates the upload job of a preview, or restarts it if it
+// has failed.
+func (model *Model) SubmitUploadJob(previewId uint, encrypt bool) error {
+	return model.DB.Transaction(func(tx *gorm.DB) error {
+		var job UploadJob
+		err := tx.Where("preview_id", previewId).First(&job).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return tx.Create(&UploadJob{
+				PreviewId:     previewId,
+				Encrypt:       encrypt,
+				State:         UploadJobCreated,
+				NextAttemptAt: time.Now(),
+			}).Error
+		}
+		if err != nil || !job.Failed {
+			return err
+		}
+		return tx.Model(&job).Updates(map[string]interface{}{
+			"Failed":        false,
+			"Attempts":      0,
+			"NextAttemptAt": time.Now(),
+		}).Error
+	})
+}
+
+// GetPendingUploadJobs returns the jobs which are neither done nor failed and
+// due to run at now.
+func (model *Model) GetPendingUploadJobs(now time.Time) ([]UploadJob, error) {
+	var jobs []UploadJob
+	err := model.DB.Where("state <> ? and failed = ? and next_attempt_at <= ?", UploadJobLinked, false, now).Order("id").Find(&jobs).Error
+	return jobs, err
+}
+
+func (model *Model) GetUploadJobByPreviewId(previewId uint) (*UploadJob, error) {
+	var job UploadJob
+	if err := model.DB.Where("preview_id", previewId).First(&job).Error; err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+func (model *Model) SaveUploadJob(job *UploadJob) error {
+	return model.DB.Save(job).Error
+}
+
+// FailUploadJob stops retrying the job and marks its preview as failed.
+func (model *Model) FailUploadJob(job *UploadJob) error {
+	job.Failed = true
+	return model.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(job).Error; err != nil {
+			return err
+		}
+		return tx.Model(&FilePreview{}).Where("id", job.PreviewId).Update("status", UploadFailed).Error
+	})
+}
+
+// LinkUploadJob links the preview with the file stored by the job.
+func (model *Model) LinkUploadJob(job *UploadJob) error {
+	job.State = UploadJobLinked
+	return model.DB.Transaction(func(tx *gorm.DB) error {
+		updateMap := map[string]interface{}{
+			"Status": PlacedToIpfs,
+			"FileId": job.FileId,
+		}
+		if err := tx.Model(&FilePreview{}).Where("id", job.PreviewId).Updates(updateMap).Error; err != nil {
+			return err
+		}
+		return tx.Save(job).Error
+	})
+}
@@ -0,0 +1,31 @@
+package model
+
+// UploadSession is a file uploaded over several requests, resumed from the
+// size of its staged file.
+type UploadSession struct {
+	SaoModel
+	UploadId       string `gorm:"uniqueIndex;size:64;"`
+	EthAddr        string `json:"-"`
+	Filename       string
+	AdditionalInfo string `gorm:"type:text;"`
+	Size           int64
+	TmpPath        string `json:"-"`
+	// number of bytes received, the size of the staged file
+	Offset int64 `gorm:"-"`
+}
+
+func (model *Model) CreateUploadSession(session *UploadSession) error {
+	return model.DB.Create(session).Error
+}
+
+func (model *Model) GetUploadSession(uploadId string, ethAddress string) (*UploadSession, error) {
+	var session UploadSession
+	if err := model.DB.Where("upload_id = ? and eth_addr = ?", uploadId, ethAddress).First(&session).Error; err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+func (model *Model) DeleteUploadSession(session *UploadSession) error {
+	return model.DB.Delete(session).Error
+}
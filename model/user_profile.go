@@ -47,7 +47,9 @@ type UserProfileDetailVO struct {
 }
 
 type UserSummary struct {
-	SpaceUsed     int64
+	// bytes stored by the user, see GetSpaceUsed
+	SpaceUsed int64
+	// max bytes the user may store, unlimited if 0
 	SpaceQuota    int64
 	Applications  int
 	TotalUploads  int
@@ -75,6 +77,9 @@ type PurchaseSummary struct {
 type SellSummary struct {
 	SellFiles   int
 	TotalEarned decimal.Decimal
+	// amounts withdrawn from the contract
+	Withdrawals    int
+	TotalWithdrawn decimal.Decimal
 }
 
 func (model *Model) UpsertUserProfile(ethAddr string, updateProfile UserProfile) (*UserProfile, error) {
@@ -275,12 +280,21 @@ func (model *Model) GetUserSummary(ethAddr string) (*UserSummary, error) {
 	model.DB.Table("file_previews").Select("sum(price) as total_earned, count(*) as sell_files").
 		Joins("inner join purchase_orders on file_previews.id = purchase_orders.file_id").Where("file_previews.eth_addr = ?", ethAddr).Scan(&sellSummary)
 
+	var withdrawals SellSummary
+	model.DB.Model(&Withdrawal{}).Select("coalesce(sum(amount), 0) as total_withdrawn, count(*) as withdrawals").
+		Where("eth_addr = ?", ethAddr).Scan(&withdrawals)
+
 	var purchaseSummary PurchaseSummary
 	model.DB.Model(&FilePreview{}).Select("sum(price) as total_paid, count(*) as purchases_files").
 		Joins("inner join purchase_orders on file_previews.id = purchase_orders.file_id").Where("purchase_orders.buyer_addr = ?", ethAddr).Scan(&purchaseSummary)
 
+	spaceUsed, err := model.GetSpaceUsed(ethAddr)
+	if err != nil {
+		return nil, err
+	}
+
 	userSummary := UserSummary{
-		Applications:  5,
+		SpaceUsed:     spaceUsed,
 		PublicUploads: int(uploads),
 		TotalUploads:  int(uploads),
 		PurchaseSummary: PurchaseSummary{
@@ -288,8 +302,10 @@ func (model *Model) GetUserSummary(ethAddr string) (*UserSummary, error) {
 			PurchasesFiles: purchaseSummary.PurchasesFiles,
 		},
 		SellSummary: SellSummary{
-			SellFiles:   sellSummary.SellFiles,
-			TotalEarned: sellSummary.TotalEarned,
+			SellFiles:      sellSummary.SellFiles,
+			TotalEarned:    sellSummary.TotalEarned,
+			Withdrawals:    withdrawals.Withdrawals,
+			TotalWithdrawn: withdrawals.TotalWithdrawn,
 		},
 	}
 	if result.Error != nil {
@@ -298,6 +314,36 @@ func (model *Model) GetUserSummary(ethAddr string) (*UserSummary, error) {
 	return &userSummary, nil
 }
 
+// GetSpaceUsed returns the bytes stored by the address: the files it is
+// uploading or which wait to be stored, and each replica of its stored files,
+// at their encrypted size for the paid ones.
+func (model *Model) GetSpaceUsed(ethAddr string) (int64, error) {
+	var uploading int64
+	err := model.DB.Model(&UploadSession{}).Select("COALESCE(SUM(size), 0)").
+		Where("eth_addr = ?", ethAddr).Scan(&uploading).Error
+	if err != nil {
+		return 0, err
+	}
+
+	var staged int64
+	err = model.DB.Model(&FilePreview{}).Select("COALESCE(SUM(size), 0)").
+		Where("eth_addr = ? AND file_id = 0 AND status IN (?, ?)", ethAddr, Uploading, UploadSuccess).Scan(&staged).Error
+	if err != nil {
+		return 0, err
+	}
+
+	// the files stored before the replicas were recorded have one
+	var stored int64
+	err = model.DB.Model(&FilePreview{}).
+		Select("COALESCE(SUM(file_infos.size * (SELECT CASE WHEN COUNT(*) = 0 THEN 1 ELSE COUNT(*) END FROM file_replicas WHERE file_replicas.file_id = file_infos.id AND file_replicas.deleted_at IS NULL)), 0)").
+		Joins("JOIN file_infos ON file_infos.id = file_previews.file_id AND file_infos.deleted_at IS NULL AND file_infos.status = ?", FileStored).
+		Where("file_previews.eth_addr = ? AND file_previews.status <> ?", ethAddr, Expired).Scan(&stored).Error
+	if err != nil {
+		return 0, err
+	}
+	return uploading + staged + stored, nil
+}
+
 func (model *Model) GetUserDashboard(limit int, offset int, ethAddr string, previewPath func(string) string, selfAddress string) (*UserDashboard, error) {
 	dashboard := UserDashboard{}
 
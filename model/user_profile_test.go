@@ -0,0 +1,49 @@
+package model
+
+import "testing"
+
+func TestGetSpaceUsed(t *testing.T) {
+	m := newTestModel(t, &UploadSession{}, &FilePreview{}, &FileInfo{}, &FileReplica{})
+	create := func(value interface{}) {
+		if err := m.DB.Create(value).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// stored on two replicas
+	create(&FileInfo{SaoModel: SaoModel{Id: 1}, Size: 100, Status: FileStored})
+	create(&FileReplica{FileId: 1, Backend: "ipfs"})
+	create(&FileReplica{FileId: 1, Backend: "local"})
+	create(&FilePreview{EthAddr: "0x01", FileId: 1, Status: UploadSuccess})
+	// stored before the replicas were recorded
+	create(&FileInfo{SaoModel: SaoModel{Id: 2}, Size: 10, Status: FileStored})
+	create(&FilePreview{EthAddr: "0x01", FileId: 2, Status: UploadSuccess})
+	// waiting to be stored
+	create(&FilePreview{EthAddr: "0x01", Size: 5, Status: UploadSuccess})
+	// expired
+	create(&FileInfo{SaoModel: SaoModel{Id: 3}, Size: 1000, Status: FileExpired})
+	create(&FilePreview{EthAddr: "0x01", FileId: 3, Status: Expired})
+	// being uploaded
+	create(&UploadSession{UploadId: "u1", EthAddr: "0x01", Size: 1})
+	// of another address
+	create(&FileInfo{SaoModel: SaoModel{Id: 4}, Size: 10000, Status: FileStored})
+	create(&FilePreview{EthAddr: "0x02", FileId: 4, Status: UploadSuccess})
+
+	cases := []struct {
+		ethAddr string
+		used    int64
+	}{
+		{"0x01", 2*100 + 10 + 5 + 1},
+		{"0x02", 10000},
+		{"0x03", 0},
+	}
+	for _, c := range cases {
+		used, err := m.GetSpaceUsed(c.ethAddr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if used != c.used {
+			t.Errorf("%s: %d bytes used, want %d", c.ethAddr, used, c.used)
+		}
+	}
+}
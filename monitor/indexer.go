@@ -0,0 +1,405 @@
+package monitor
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"sao-datastore-storage/model"
+	"time"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/gwaylib/log"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+const defaultConfirmations = 6
+
+const defaultPollInterval = 15 * time.Second
+
+// blocks whose logs are filtered at most at once
+const indexBatchSize = 2000
+
+type listingEvent struct {
+	TokenId   *big.Int
+	FileId    int64
+	Price     *big.Int
+	Timestamp int64
+}
+
+type boughtEvent struct {
+	TokenId   *big.Int
+	Buyer     ethcommon.Address
+	OrderId   *big.Int
+	Price     *big.Int
+	Timestamp int64
+}
+
+type downloadEvent struct {
+	OrderId   *big.Int
+	Timestamp int64
+}
+
+type changePriceEvent struct {
+	TokenId   *big.Int
+	Price     *big.Int
+	Timestamp int64
+}
+
+type withdrawEvent struct {
+	User      ethcommon.Address
+	Amount    *big.Int
+	Timestamp int64
+}
+
+func (m *Monitor) confirmations() uint64 {
+	if m.cfg.Confirmations > 0 {
+		return uint64(m.cfg.Confirmations)
+	}
+	return defaultConfirmations
+}
+
+func (m *Monitor) pollInterval() time.Duration {
+	if m.cfg.PollInterval > 0 {
+		return time.Duration(m.cfg.PollInterval) * time.Second
+	}
+	return defaultPollInterval
+}
+
+// startBlock returns the block before the first one whose logs are processed.
+func (m *Monitor) startBlock() uint64 {
+	if m.cfg.BlockNumber > 0 {
+		return uint64(m.cfg.BlockNumber - 1)
+	}
+	return 0
+}
+
+// runIndexer processes the logs of the contract once they are confirmed,
+// until ctx is done.
+func (m *Monitor) runIndexer(ctx context.Context) {
+	ticker := time.NewTicker(m.pollInterval())
+	defer ticker.Stop()
+	for {
+		if err := m.indexLogs(ctx); err != nil {
+			log.Error(err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// indexLogs processes the logs of the confirmed blocks after the checkpoint,
+// once the events of the blocks replaced by a reorg are rolled back.
+func (m *Monitor) indexLogs(ctx context.Context) error {
+	checkpoint, err := m.Model.GetChainCheckpoint(m.contractAddress.Hex())
+	if err != nil {
+		return err
+	}
+	if checkpoint.Id == 0 {
+		checkpoint.BlockNumber = m.startBlock()
+	}
+	if err = m.checkReorg(ctx, checkpoint); err != nil {
+		return err
+	}
+
+	head, err := m.provider.BlockNumber(ctx)
+	if err != nil {
+		return err
+	}
+	if head < m.confirmations() {
+		return nil
+	}
+	confirmed := head - m.confirmations()
+
+	for checkpoint.BlockNumber < confirmed {
+		if ctx.Err() != nil {
+			return nil
+		}
+		from := checkpoint.BlockNumber + 1
+		to := from + indexBatchSize - 1
+		if to > confirmed {
+			to = confirmed
+		}
+
+		header, err := m.provider.HeaderByNumber(ctx, to)
+		if err != nil {
+			return err
+		}
+		logs, err := m.provider.FilterBlockLogs(ctx, m.contractAddress, from, to)
+		if err != nil {
+			return err
+		}
+		// the logs are of the blocks before header if it is still on the chain
+		again, err := m.provider.HeaderByNumber(ctx, to)
+		if err != nil {
+			return err
+		}
+		if again.Hash() != header.Hash() {
+			return fmt.Errorf("block %d replaced while its logs were filtered", to)
+		}
+
+		for _, l := range logs {
+			if l.BlockNumber < checkpoint.LogBlock || (l.BlockNumber == checkpoint.LogBlock && l.Index <= checkpoint.LogIndex) {
+				continue
+			}
+			if err = m.processLog(checkpoint, l); err != nil {
+				return fmt.Errorf("log %d of block %d: %w", l.Index, l.BlockNumber, err)
+			}
+		}
+
+		checkpoint.BlockNumber = to
+		checkpoint.BlockHash = header.Hash().Hex()
+		if err = m.Model.SaveChainCheckpoint(checkpoint); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// processLog applies the event of a log and moves the checkpoint past it at
+// once, the events processed already being skipped.
+func (m *Monitor) processLog(checkpoint *model.ChainCheckpoint, l types.Log) error {
+	name, event, err := m.decodeEvent(l)
+	if err != nil {
+		return fmt.Errorf("decode event: %w", err)
+	}
+	next := *checkpoint
+	next.LogBlock = l.BlockNumber
+	next.LogIndex = l.Index
+
+	err = m.Model.Transaction(func(tx *model.Model) error {
+		if name != "" {
+			data, err := json.Marshal(event)
+			if err != nil {
+				return err
+			}
+			chainEvent := model.ChainEvent{
+				Contract:    m.contractAddress.Hex(),
+				BlockNumber: l.BlockNumber,
+				BlockHash:   l.BlockHash.Hex(),
+				LogIndex:    l.Index,
+				TxHash:      l.TxHash.Hex(),
+				Name:        name,
+				Data:        string(data),
+			}
+			created, err := tx.CreateChainEvent(&chainEvent)
+			if err != nil {
+				return err
+			}
+			if created {
+				if err = applyEvent(tx, &chainEvent, event); err != nil {
+					return err
+				}
+			}
+		}
+		return tx.SaveChainCheckpoint(&next)
+	})
+	if err != nil {
+		return err
+	}
+	*checkpoint = next
+	return nil
+}
+
+// decodeEvent returns the name and the arguments of the event of a log, or
+// "" if it is not an event the monitor processes.
+func (m *Monitor) decodeEvent(l types.Log) (string, interface{}, error) {
+	if len(l.Topics) == 0 {
+		return "", nil, nil
+	}
+	event, err := m.contractABI.EventByID(l.Topics[0])
+	if err != nil {
+		return "", nil, nil
+	}
+	switch event.Name {
+	case "Listing":
+		e, err := m.contract.ParseListing(l)
+		if err != nil {
+			return "", nil, err
+		}
+		return event.Name, &listingEvent{TokenId: e.TokenId, FileId: e.FileId.Int64(), Price: e.Price, Timestamp: e.Timestamp.Int64()}, nil
+	case "Bought":
+		e, err := m.contract.ParseBought(l)
+		if err != nil {
+			return "", nil, err
+		}
+		return event.Name, &boughtEvent{TokenId: e.TokenId, Buyer: e.Buyer, OrderId: e.OrderId, Price: e.Price, Timestamp: e.Timestamp.Int64()}, nil
+	case "Download":
+		e, err := m.contract.ParseDownload(l)
+		if err != nil {
+			return "", nil, err
+		}
+		return event.Name, &downloadEvent{OrderId: e.OrderId, Timestamp: e.Timestamp.Int64()}, nil
+	case "ChangePrice":
+		e, err := m.contract.ParseChangePrice(l)
+		if err != nil {
+			return "", nil, err
+		}
+		return event.Name, &changePriceEvent{TokenId: e.TokenId, Price: e.Price, Timestamp: e.Timestamp.Int64()}, nil
+	case "Withdraw":
+		e, err := m.contract.ParseWithdraw(l)
+		if err != nil {
+			return "", nil, err
+		}
+		return event.Name, &withdrawEvent{User: e.User, Amount: e.Amount, Timestamp: e.Timestamp.Int64()}, nil
+	}
+	return "", nil, nil
+}
+
+func applyEvent(tx *model.Model, chainEvent *model.ChainEvent, event interface{}) error {
+	switch e := event.(type) {
+	case *listingEvent:
+		// set nft/file price
+		log.Info("Listing ", e.TokenId, e.FileId, e.Price, e.Timestamp)
+		err := tx.ListFile(chainEvent.Id, e.FileId, e.TokenId, e.Price, time.Unix(e.Timestamp, 0))
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			log.Warnf("no file %d of token %s", e.FileId, e.TokenId)
+			return nil
+		}
+		return err
+	case *boughtEvent:
+		log.Info("Bought ", e.TokenId, e.Buyer, e.OrderId, e.Price, e.Timestamp)
+		filePreview, err := tx.GetFilePreviewByTokenId(e.TokenId.Int64())
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			log.Warnf("order %s: no file of token %s", e.OrderId, e.TokenId)
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		purchaseOrder := make(map[string]interface{})
+		purchaseOrder["id"] = uint(e.OrderId.Int64())
+		purchaseOrder["file_id"] = int64(filePreview.Id)
+		purchaseOrder["buyer_addr"] = e.Buyer.Hex()
+		purchaseOrder["state"] = model.ContractOrdered
+		purchaseOrder["updated_at"] = time.Now()
+		return tx.CreatePurchaseOrder(purchaseOrder)
+	case *downloadEvent:
+		log.Info("Download ", e.OrderId, e.Timestamp)
+		return tx.UpdatePurchaseOrderState(uint(e.OrderId.Int64()), model.Finish)
+	case *changePriceEvent:
+		log.Info("ChangePrice ", e.TokenId, e.Price, e.Timestamp)
+		err := tx.ChangeFilePrice(chainEvent.Id, e.TokenId.Int64(), e.Price, time.Unix(e.Timestamp, 0))
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			log.Warnf("no file of token %s", e.TokenId)
+			return nil
+		}
+		return err
+	case *withdrawEvent:
+		log.Info("Withdraw ", e.User, e.Amount, e.Timestamp)
+		return tx.CreateWithdrawal(&model.Withdrawal{
+			ChainEventId: chainEvent.Id,
+			EthAddr:      e.User.Hex(),
+			Amount:       decimal.NewFromBigInt(e.Amount, -18),
+			WithdrawnAt:  time.Unix(e.Timestamp, 0),
+			TxHash:       chainEvent.TxHash,
+		})
+	}
+	return nil
+}
+
+// checkReorg rolls the checkpoint back to the last block of an event still
+// on the chain if the block of the checkpoint was replaced, reverting the
+// events after it.
+func (m *Monitor) checkReorg(ctx context.Context, checkpoint *model.ChainCheckpoint) error {
+	if checkpoint.BlockHash == "" {
+		return nil
+	}
+	header, err := m.provider.HeaderByNumber(ctx, checkpoint.BlockNumber)
+	if err != nil {
+		return err
+	}
+	if header.Hash().Hex() == checkpoint.BlockHash {
+		return nil
+	}
+	log.Warnf("block %d %s replaced by %s, rolling back", checkpoint.BlockNumber, checkpoint.BlockHash, header.Hash().Hex())
+
+	contract := m.contractAddress.Hex()
+	ancestor := m.startBlock()
+	ancestorHash := ""
+	before := checkpoint.BlockNumber + 1
+	for {
+		event, err := m.Model.GetLastChainEventBefore(contract, before)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		header, err := m.provider.HeaderByNumber(ctx, event.BlockNumber)
+		if err != nil {
+			return err
+		}
+		if header.Hash().Hex() == event.BlockHash {
+			ancestor = event.BlockNumber
+			ancestorHash = event.BlockHash
+			break
+		}
+		before = event.BlockNumber
+	}
+
+	events, err := m.Model.GetChainEventsAfter(contract, ancestor)
+	if err != nil {
+		return err
+	}
+	return m.Model.Transaction(func(tx *model.Model) error {
+		for i := range events {
+			log.Warnf("reverting %s of block %d in tx %s", events[i].Name, events[i].BlockNumber, events[i].TxHash)
+			if err := revertEvent(tx, &events[i]); err != nil {
+				return err
+			}
+			if err := tx.DeleteChainEvent(&events[i]); err != nil {
+				return err
+			}
+		}
+		checkpoint.BlockNumber = ancestor
+		checkpoint.BlockHash = ancestorHash
+		checkpoint.LogBlock = 0
+		checkpoint.LogIndex = 0
+		return tx.SaveChainCheckpoint(checkpoint)
+	})
+}
+
+// revertEvent undoes what applyEvent did for an event no longer on the
+// chain. The event is applied again if its tx is in the new blocks.
+func revertEvent(tx *model.Model, event *model.ChainEvent) error {
+	switch event.Name {
+	case "Listing":
+		var e listingEvent
+		if err := json.Unmarshal([]byte(event.Data), &e); err != nil {
+			return err
+		}
+		// the listings indexed before their price changes were recorded
+		// only unlink the token
+		if err := tx.ClearPreviewTokenId(e.TokenId); err != nil {
+			return err
+		}
+		return tx.RevertFilePriceChange(event.Id)
+	case "Bought":
+		var e boughtEvent
+		if err := json.Unmarshal([]byte(event.Data), &e); err != nil {
+			return err
+		}
+		return tx.DeletePurchaseOrder(uint(e.OrderId.Int64()))
+	case "Download":
+		var e downloadEvent
+		if err := json.Unmarshal([]byte(event.Data), &e); err != nil {
+			return err
+		}
+		// the finish tx may not be back on the chain, it is sent again and
+		// reverts if it is
+		return tx.UpdatePurchaseOrderState(uint(e.OrderId.Int64()), model.ReadyToDownload)
+	case "ChangePrice":
+		return tx.RevertFilePriceChange(event.Id)
+	case "Withdraw":
+		return tx.DeleteWithdrawal(event.Id)
+	}
+	return nil
+}
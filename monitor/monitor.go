@@ -3,11 +3,9 @@ package monitor
 import (
 	"context"
 	"fmt"
-	"math/big"
 	"sao-datastore-storage/common"
 	"sao-datastore-storage/model"
 	"sao-datastore-storage/web3"
-	"strings"
 	"time"
 
 	ethcommon "github.com/ethereum/go-ethereum/common"
@@ -19,179 +17,109 @@ import (
 	"github.com/gwaylib/log"
 )
 
+// ChainClient is the chain the monitor reads the logs of the contract from
+// and sends the finish txs to, implemented by web3.Provider.
+type ChainClient interface {
+	TxBackend
+	BlockNumber(ctx context.Context) (uint64, error)
+	HeaderByNumber(ctx context.Context, number uint64) (*types.Header, error)
+	FilterBlockLogs(ctx context.Context, address ethcommon.Address, from uint64, to uint64) ([]types.Log, error)
+}
+
 type Monitor struct {
-	cfg      common.MonitorInfo
-	provider *web3.Provider
-	Model    *model.Model
-	Wallet   *hdwallet.Wallet
-	contract abi.ABI
+	cfg             common.MonitorInfo
+	provider        ChainClient
+	Model           *model.Model
+	Wallet          *hdwallet.Wallet
+	contractABI     *abi.ABI
+	contract        *web3.SAOFile
+	contractAddress ethcommon.Address
+	txs             *TxManager
 }
 
 func NewMonitor(cfg common.MonitorInfo, model *model.Model) (*Monitor, error) {
-	provider, _ := web3.NewProvider(cfg.Provider)
+	provider, err := web3.NewProvider(cfg.Provider)
+	if err != nil {
+		return nil, err
+	}
+	return NewMonitorWithClient(cfg, model, provider)
+}
+
+// NewMonitorWithClient returns a monitor of the chain of client rather than
+// of cfg.Provider.
+func NewMonitorWithClient(cfg common.MonitorInfo, model *model.Model, provider ChainClient) (*Monitor, error) {
 	wallet, err := hdwallet.NewFromMnemonic(cfg.Mnemonic)
 	if err != nil {
 		fmt.Println("invalid key")
 		return nil, err
 	}
+	contractABI, err := web3.SAOFileMetaData.GetAbi()
+	if err != nil {
+		return nil, err
+	}
+	contractAddress := ethcommon.HexToAddress(cfg.Contract)
+	// the logs are filtered and the txs sent through provider, the bindings
+	// only parse the logs and pack the calls
+	contract, err := web3.NewSAOFile(contractAddress, nil)
+	if err != nil {
+		return nil, err
+	}
+	derivationPath := cfg.DerivationPath
+	if derivationPath == "" {
+		derivationPath = "m/44'/60'/0'/0/0"
+	}
+	path, err := hdwallet.ParseDerivationPath(derivationPath)
+	if err != nil {
+		return nil, err
+	}
+	account, err := wallet.Derive(path, true)
+	if err != nil {
+		return nil, err
+	}
+	key, err := wallet.PrivateKey(account)
+	if err != nil {
+		return nil, err
+	}
 	monitor := Monitor{
-		provider: provider,
-		Model:    model,
-		cfg:      cfg,
-		Wallet:   wallet,
+		provider:        provider,
+		Model:           model,
+		cfg:             cfg,
+		Wallet:          wallet,
+		contractABI:     contractABI,
+		contract:        contract,
+		contractAddress: contractAddress,
+		txs:             NewTxManager(provider, model, key),
 	}
+	log.Infof("finishing the orders from %s", account.Address.Hex())
 	return &monitor, nil
 }
 
-func (m *Monitor) Run() {
-	ch := make(chan types.Log, 100)
-	addresses := make([]ethcommon.Address, 0)
-	addr := ethcommon.HexToAddress(m.cfg.Contract)
-	addresses = append(addresses, addr)
-	contract, _ := abi.JSON(strings.NewReader(web3.NFTABI))
-	m.contract = contract
-
-	latest := m.provider.GetLatestBlock()
-
-	//done := make(chan int, 1)
-	fmt.Println("filter logs")
-	logs := m.provider.FilterLogs(context.Background(), addresses, new(big.Int).SetInt64(m.cfg.BlockNumber), new(big.Int).SetUint64(latest))
-	go func(ch chan types.Log, logs []types.Log) {
-		for _, log := range logs {
-			ch <- log
-		}
-	}(ch, logs)
-
+// Run finishes the downloaded orders on chain, and processes the logs of the
+// contract from the last checkpoint, until ctx is done.
+func (m *Monitor) Run(ctx context.Context) {
 	fmt.Println("listen download status")
 	s := gocron.NewScheduler(time.UTC)
-	s.Every(5).Seconds().Do(func() {
-		finishPurchase, got := m.Model.GetNextPurchaseOrderToFinish()
-		if got {
-			err := m.Finish(int64(finishPurchase.Id))
-			if err != nil {
-				fmt.Println("##################", err)
-			}
-			if err := m.Model.UpdatePurchaseOrderState(finishPurchase.Id, model.FinishContractStarted); err != nil {
-				log.Error(err)
-			}
-		}
-	})
+	// an order is not picked again while its finish tx is being sent
+	s.Every(5).Seconds().SingletonMode().Do(m.finishOrders, ctx)
 
 	s.StartAsync()
+	defer s.Stop()
 
 	fmt.Println("listen event")
-	//go m.provider.ListenEvent(addresses, ch, new(big.Int).SetInt64(m.cfg.BlockNumber), done)
-
-	s.Every(1).Seconds().Do(func() {
-		newLatest := m.provider.GetLatestBlock()
-		if newLatest == latest {
-			return
-		}
-		fmt.Printf("latest : %d newLatest : %d \n", latest, newLatest)
-		flogs := m.provider.FilterLogs(context.Background(), addresses, new(big.Int).SetUint64(latest), new(big.Int).SetUint64(newLatest))
-		latest = newLatest
-		for _, log := range flogs {
-			ch <- log
-		}
-	})
-
-	for {
-		logger := <-ch
-		event := logger.Topics[0].Hex()
-		var buyer ethcommon.Address
-		var tokenId *big.Int
-		var price *big.Int
-		var eventName string
-		var timestamp int64
-		var fileId int64
-		var orderId *big.Int
-		switch event {
-		case "0xe96fc9a98f9b2e37b107acde0c3ba4ba52a1fb575da1acd1f15f0b6b1a35b8a9":
-			tokenId, fileId, price, timestamp = m.parseListEvent(contract, logger)
-			eventName = "ListingNFT"
-		case "0xaf6c8baebc55f540953b129965caf1e3f48010ed731c2cea5ed61e71ebc57153":
-			tokenId, buyer, orderId, price, timestamp = m.parseBuyEvent(contract, logger)
-			eventName = "BuyNFT"
-		case "0xfc677c6d4fb41b52076143bd16b0f032d45adbe706f64c08956a1829cd048986":
-			orderId, timestamp = m.parseDownloadEvent(contract, logger)
-			eventName = "DownloadFile"
-		}
-
-		switch eventName {
-		case "ListingNFT":
-			// set nft/file price
-			if err := m.Model.UpdatePreviewPriceAndTokenId(fileId, price, tokenId); err != nil {
-				log.Error(err)
-				continue
-			}
-			fmt.Println(tokenId, fileId, price, timestamp)
-		case "BuyNFT":
-			filePreview, err := m.Model.GetFilePreviewByTokenId(tokenId.Int64())
-			if err != nil {
-				log.Error(err)
-				continue
-			}
-			purchaseOrder := make(map[string]interface{})
-			purchaseOrder["id"] = uint(orderId.Int64())
-			purchaseOrder["file_id"] = int64(filePreview.Id)
-			purchaseOrder["buyer_addr"] = buyer.Hex()
-			purchaseOrder["state"] = model.ContractOrdered
-			purchaseOrder["updated_at"] = time.Now()
-			if err = m.Model.CreatePurchaseOrder(purchaseOrder); err != nil {
-				log.Error(err)
-			}
-			fmt.Println(tokenId, buyer, orderId, price, timestamp)
-		case "DownloadFile":
-			if err := m.Model.UpdatePurchaseOrderState(uint(orderId.Int64()), model.Finish); err != nil {
-				log.Error(err)
-			}
-			fmt.Println("DownloadFile", orderId, timestamp)
-		}
-	}
-}
-
-func (m *Monitor) parseBuyEvent(contractABI abi.ABI, log types.Log) (*big.Int, ethcommon.Address, *big.Int, *big.Int, int64) {
-	tokenId := new(big.Int)
-	orderId := new(big.Int)
-	var buyer ethcommon.Address
-	tokenId.SetBytes(log.Topics[1].Bytes())
-	buyer = ethcommon.BytesToAddress(log.Topics[2].Bytes())
-	orderId.SetBytes(log.Topics[3].Bytes())
-	var data []interface{}
-	data, _ = contractABI.Unpack("Bought", log.Data)
-	return tokenId, buyer, orderId, data[0].(*big.Int), data[1].(*big.Int).Int64()
+	m.runIndexer(ctx)
 }
 
-func (m *Monitor) parseListEvent(contractABI abi.ABI, log types.Log) (*big.Int, int64, *big.Int, int64) {
-	tokenId := new(big.Int)
-	fmt.Println(log.Topics[1])
-	tokenId.SetBytes(log.Topics[1].Bytes())
-	var data []interface{}
-	data, _ = contractABI.Unpack("Listing", log.Data)
-	return tokenId, data[0].(*big.Int).Int64(), data[1].(*big.Int), data[2].(*big.Int).Int64()
-}
-
-func (m *Monitor) parseDownloadEvent(contractABI abi.ABI, log types.Log) (*big.Int, int64) {
-	orderId := new(big.Int)
-	orderId.SetBytes(log.Topics[1].Bytes())
-	var data []interface{}
-	data, _ = contractABI.Unpack("Download", log.Data)
-	return orderId, data[0].(*big.Int).Int64()
-}
-
-func (m *Monitor) parseTransferEvent(contractABI abi.ABI, log types.Log) (ethcommon.Address, ethcommon.Address, *big.Int) {
-	value := new(big.Int)
-	// value indexed
-	from := ethcommon.BytesToAddress(log.Topics[1].Bytes())
-	to := ethcommon.BytesToAddress(log.Topics[2].Bytes())
-	if len(log.Topics) == 4 {
-		value.SetBytes(log.Topics[3].Bytes())
-	} else if len(log.Topics) == 3 {
-		var data []interface{}
-		data, _ = contractABI.Unpack("Transfer", log.Data)
-		if len(data) > 0 {
-			value = data[0].(*big.Int)
+// finishOrders sends the finish tx of the next downloaded order, and checks
+// the receipts of the txs sent.
+func (m *Monitor) finishOrders(ctx context.Context) {
+	finishPurchase, got := m.Model.GetNextPurchaseOrderToFinish(time.Now())
+	if got {
+		if err := m.Finish(ctx, finishPurchase.Id); err != nil {
+			log.Warnf("finish order %d: %v", finishPurchase.Id, err)
+			m.retryFinish(finishPurchase)
 		}
 	}
-	return from, to, value
+	if err := m.txs.Poll(ctx); err != nil {
+		log.Error(err)
+	}
 }
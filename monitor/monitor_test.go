@@ -0,0 +1,371 @@
+package monitor
+
+import (
+	"context"
+	"math/big"
+	"path/filepath"
+	"sao-datastore-storage/common"
+	"sao-datastore-storage/model"
+	"sao-datastore-storage/web3"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind/backends"
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	hdwallet "github.com/miguelmota/go-ethereum-hdwallet"
+	"github.com/shopspring/decimal"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+const testMnemonic = "test test test test test test test test test test test junk"
+
+// simChain is the chain of a simulated backend, whose blocks are mined by
+// Commit.
+type simChain struct {
+	simTxBackend
+}
+
+func (c *simChain) BlockNumber(ctx context.Context) (uint64, error) {
+	return c.Blockchain().CurrentBlock().NumberU64(), nil
+}
+
+func (c *simChain) HeaderByNumber(ctx context.Context, number uint64) (*types.Header, error) {
+	return c.SimulatedBackend.HeaderByNumber(ctx, new(big.Int).SetUint64(number))
+}
+
+func (c *simChain) FilterBlockLogs(ctx context.Context, address ethcommon.Address, from uint64, to uint64) ([]types.Log, error) {
+	return c.FilterLogs(ctx, ethereum.FilterQuery{
+		FromBlock: new(big.Int).SetUint64(from),
+		ToBlock:   new(big.Int).SetUint64(to),
+		Addresses: []ethcommon.Address{address},
+	})
+}
+
+type testMarket struct {
+	t        *testing.T
+	chain    *simChain
+	monitor  *Monitor
+	m        *model.Model
+	contract *web3.SAOFile
+	seller   *bind.TransactOpts
+	buyer    *bind.TransactOpts
+}
+
+func newTestKey(t *testing.T) *bind.TransactOpts {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts, err := bind.NewKeyedTransactorWithChainID(key, big.NewInt(1337))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return opts
+}
+
+// newTestMarket deploys SAOFile.sol from the account of the monitor, which
+// finishes the orders. The test fails if the bindings were generated without
+// the bytecode of the contract.
+func newTestMarket(t *testing.T) *testMarket {
+	if web3.SAOFileMetaData.Bin == "" {
+		t.Fatal("no bytecode of SAOFile.sol in the bindings, generate them with go generate ./web3")
+	}
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "test.db")), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.AutoMigrate(&model.FilePreview{}, &model.PurchaseOrder{}, &model.ChainEvent{}, &model.ChainCheckpoint{}, &model.ChainTx{}, &model.FilePriceChange{}, &model.Withdrawal{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := &model.Model{DB: db}
+
+	wallet, err := hdwallet.NewFromMnemonic(testMnemonic)
+	if err != nil {
+		t.Fatal(err)
+	}
+	account, err := wallet.Derive(hdwallet.DefaultBaseDerivationPath, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := wallet.PrivateKey(account)
+	if err != nil {
+		t.Fatal(err)
+	}
+	owner, err := bind.NewKeyedTransactorWithChainID(key, big.NewInt(1337))
+	if err != nil {
+		t.Fatal(err)
+	}
+	seller, buyer := newTestKey(t), newTestKey(t)
+	ether := new(big.Int).Exp(big.NewInt(10), big.NewInt(18), nil)
+	alloc := core.GenesisAlloc{}
+	for _, opts := range []*bind.TransactOpts{owner, seller, buyer} {
+		alloc[opts.From] = core.GenesisAccount{Balance: ether}
+	}
+	chain := &simChain{simTxBackend{SimulatedBackend: backends.NewSimulatedBackend(alloc, 8000000)}}
+	t.Cleanup(func() { chain.Close() })
+
+	contractABI, err := web3.SAOFileMetaData.GetAbi()
+	if err != nil {
+		t.Fatal(err)
+	}
+	address, _, _, err := bind.DeployContract(owner, *contractABI, ethcommon.FromHex(web3.SAOFileMetaData.Bin), chain.SimulatedBackend, "SAO File", "SAOF", "")
+	if err != nil {
+		t.Fatal("failed to deploy contract", err)
+	}
+	chain.Commit()
+	contract, err := web3.NewSAOFile(address, chain.SimulatedBackend)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	monitor, err := NewMonitorWithClient(common.MonitorInfo{
+		Contract:      address.Hex(),
+		BlockNumber:   1,
+		Mnemonic:      testMnemonic,
+		Confirmations: 1,
+	}, m, chain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testMarket{
+		t:        t,
+		chain:    chain,
+		monitor:  monitor,
+		m:        m,
+		contract: contract,
+		seller:   seller,
+		buyer:    buyer,
+	}
+}
+
+// mined mines the tx sent to the contract.
+func (tm *testMarket) mined(tx *types.Transaction, err error) {
+	tm.t.Helper()
+	if err != nil {
+		tm.t.Fatal("failed to send tx", err)
+	}
+	tm.chain.Commit()
+	receipt, err := tm.chain.TransactionReceipt(context.Background(), tx.Hash())
+	if err != nil || receipt.Status != types.ReceiptStatusSuccessful {
+		tm.t.Fatalf("tx %s failed: %v", tx.Hash().Hex(), err)
+	}
+}
+
+// index mines a block to confirm the last ones, and processes their logs.
+func (tm *testMarket) index() {
+	tm.chain.Commit()
+	if err := tm.monitor.indexLogs(context.Background()); err != nil {
+		tm.t.Fatal("failed to index logs", err)
+	}
+}
+
+func (tm *testMarket) checkOrderState(orderId uint, state model.OrderState) {
+	tm.t.Helper()
+	checkOrderState(tm.t, tm.m, orderId, state)
+}
+
+func TestPurchaseFlow(t *testing.T) {
+	tm := newTestMarket(t)
+	ctx := context.Background()
+
+	preview := model.FilePreview{EthAddr: tm.seller.From.Hex(), Status: model.UploadSuccess}
+	if err := tm.m.DB.Create(&preview).Error; err != nil {
+		t.Fatal(err)
+	}
+	price := big.NewInt(1e15)
+	tm.mined(tm.contract.Mint(tm.seller, new(big.Int).SetUint64(uint64(preview.Id)), price))
+	tm.index()
+	listed, err := tm.m.GetFilePreviewByTokenId(1)
+	if err != nil {
+		t.Fatal("file of token 1 not listed", err)
+	}
+	if listed.Id != preview.Id || !listed.Price.Equal(decimal.NewFromBigInt(price, -18)) {
+		t.Fatalf("token 1 listed as file %d at %s", listed.Id, listed.Price)
+	}
+
+	tm.buyer.Value = price
+	tm.mined(tm.contract.Buy(tm.buyer, big.NewInt(1)))
+	tm.buyer.Value = nil
+	tm.index()
+	tm.checkOrderState(0, model.ContractOrdered)
+
+	// nothing to finish before the buyer downloads the file
+	tm.monitor.finishOrders(ctx)
+	tm.checkOrderState(0, model.ContractOrdered)
+
+	// as the server does when the buyer downloads the file
+	if err = tm.m.UpdatePurchaseOrderState(0, model.ReadyToDownload); err != nil {
+		t.Fatal(err)
+	}
+	tm.monitor.finishOrders(ctx)
+	tm.checkOrderState(0, model.FinishContractStarted)
+
+	tm.chain.Commit()
+	tm.monitor.finishOrders(ctx)
+	tm.checkOrderState(0, model.FinishContractConfirmed)
+
+	tm.index()
+	tm.checkOrderState(0, model.Finish)
+	var events int64
+	if err = tm.m.DB.Model(&model.ChainEvent{}).Count(&events).Error; err != nil || events != 3 {
+		t.Fatalf("%d events recorded, %v", events, err)
+	}
+
+	// the seller buys its own file
+	tm.seller.Value = price
+	tm.mined(tm.contract.Buy(tm.seller, big.NewInt(1)))
+	tm.seller.Value = nil
+	tm.index()
+	if err = tm.m.UpdatePurchaseOrderState(1, model.ReadyToDownload); err != nil {
+		t.Fatal(err)
+	}
+
+	// the finish tx of an order finished already reverts, the order is tried
+	// again later and does not hold back the next one
+	if err = tm.m.UpdatePurchaseOrderState(0, model.ReadyToDownload); err != nil {
+		t.Fatal(err)
+	}
+	tm.monitor.finishOrders(ctx)
+	tm.checkOrderState(0, model.ReadyToDownload)
+	tm.checkOrderState(1, model.ReadyToDownload)
+	tm.monitor.finishOrders(ctx)
+	tm.checkOrderState(1, model.FinishContractStarted)
+
+	for attempts := 2; attempts <= maxFinishAttempts; attempts++ {
+		tm.checkOrderState(0, model.ReadyToDownload)
+		// the order is due again
+		if err = tm.m.DB.Model(&model.PurchaseOrder{}).Where("id = ?", 0).Update("next_finish_at", time.Now()).Error; err != nil {
+			t.Fatal(err)
+		}
+		tm.monitor.finishOrders(ctx)
+	}
+	tm.checkOrderState(0, model.FinishContractFailed)
+}
+
+func TestIndexerReorg(t *testing.T) {
+	tm := newTestMarket(t)
+
+	preview := model.FilePreview{EthAddr: tm.seller.From.Hex(), Status: model.UploadSuccess}
+	if err := tm.m.DB.Create(&preview).Error; err != nil {
+		t.Fatal(err)
+	}
+	price := big.NewInt(1e15)
+	tm.mined(tm.contract.Mint(tm.seller, new(big.Int).SetUint64(uint64(preview.Id)), price))
+	tm.index()
+	fork := tm.chain.Blockchain().CurrentBlock().Hash()
+
+	tm.buyer.Value = price
+	tm.mined(tm.contract.Buy(tm.buyer, big.NewInt(1)))
+	// the file is listed again with another token
+	tm.mined(tm.contract.Mint(tm.seller, new(big.Int).SetUint64(uint64(preview.Id)), big.NewInt(3e15)))
+	tm.index()
+	tm.checkOrderState(0, model.ContractOrdered)
+
+	// the blocks of the order and the listing are replaced by longer empty
+	// ones
+	if err := tm.chain.Fork(context.Background(), fork); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 4; i++ {
+		tm.chain.Commit()
+	}
+	tm.index()
+	var orders int64
+	if err := tm.m.DB.Model(&model.PurchaseOrder{}).Count(&orders).Error; err != nil || orders != 0 {
+		t.Fatalf("%d orders left after the reorg, %v", orders, err)
+	}
+	listed, err := tm.m.GetFilePreviewByTokenId(1)
+	if err != nil {
+		t.Fatal("listing before the fork reverted", err)
+	}
+	if !listed.Price.Equal(decimal.NewFromBigInt(price, -18)) {
+		t.Fatalf("file listed at %s after the reorg, want %s", listed.Price, decimal.NewFromBigInt(price, -18))
+	}
+
+	// the order is back on the chain
+	tm.mined(tm.contract.Buy(tm.buyer, big.NewInt(1)))
+	tm.index()
+	tm.checkOrderState(0, model.ContractOrdered)
+}
+
+func TestPriceChangeAndWithdraw(t *testing.T) {
+	tm := newTestMarket(t)
+	ctx := context.Background()
+	opts := &bind.CallOpts{Context: ctx}
+
+	preview := model.FilePreview{EthAddr: tm.seller.From.Hex(), Status: model.UploadSuccess}
+	if err := tm.m.DB.Create(&preview).Error; err != nil {
+		t.Fatal(err)
+	}
+	tokenId := big.NewInt(1)
+	tm.mined(tm.contract.Mint(tm.seller, new(big.Int).SetUint64(uint64(preview.Id)), big.NewInt(1e15)))
+	owner, err := tm.contract.OwnerOf(opts, tokenId)
+	if err != nil || owner != tm.seller.From {
+		t.Fatalf("token 1 owned by %s, %v", owner.Hex(), err)
+	}
+
+	// only the owner of the token changes its price
+	price := big.NewInt(2e15)
+	if _, err = tm.contract.ChangePrice(tm.buyer, tokenId, price); err == nil {
+		t.Fatal("price changed by the buyer")
+	}
+	tm.mined(tm.contract.ChangePrice(tm.seller, tokenId, price))
+	tm.index()
+	listed, err := tm.m.GetFilePreviewByTokenId(1)
+	if err != nil || !listed.Price.Equal(decimal.NewFromBigInt(price, -18)) {
+		t.Fatalf("file of token 1 listed at %s, %v", listed.Price, err)
+	}
+	// the listing and the change
+	changes, err := tm.m.GetFilePriceChanges(preview.Id)
+	if err != nil || len(changes) != 2 || !changes[0].OldPrice.Equal(decimal.NewFromBigInt(big.NewInt(1e15), -18)) {
+		t.Fatalf("price changes %+v, %v", changes, err)
+	}
+
+	// the token is bought at its new price
+	tm.buyer.Value = big.NewInt(1e15)
+	if _, err = tm.contract.Buy(tm.buyer, tokenId); err == nil {
+		t.Fatal("token bought at its previous price")
+	}
+	tm.buyer.Value = price
+	tm.mined(tm.contract.Buy(tm.buyer, tokenId))
+	tm.buyer.Value = nil
+	bought, err := tm.contract.Buyer(opts, tokenId, tm.buyer.From)
+	if err != nil || !bought {
+		t.Fatalf("buyer of token 1 not recorded, %v", err)
+	}
+	tm.index()
+
+	if err = tm.m.UpdatePurchaseOrderState(0, model.ReadyToDownload); err != nil {
+		t.Fatal(err)
+	}
+	tm.monitor.finishOrders(ctx)
+	tm.chain.Commit()
+	tm.monitor.finishOrders(ctx)
+	tm.checkOrderState(0, model.FinishContractConfirmed)
+
+	// the seller is paid the price less the 1% fee
+	paid := new(big.Int).Sub(price, new(big.Int).Div(price, big.NewInt(100)))
+	balance, err := tm.contract.Balances(opts, tm.seller.From)
+	if err != nil || balance.Cmp(paid) != 0 {
+		t.Fatalf("seller balance %s, expected %s, %v", balance, paid, err)
+	}
+	tm.mined(tm.contract.Withdraw(tm.seller))
+	tm.index()
+	var withdrawal model.Withdrawal
+	if err = tm.m.DB.First(&withdrawal).Error; err != nil {
+		t.Fatal("withdrawal not recorded", err)
+	}
+	if withdrawal.EthAddr != tm.seller.From.Hex() || !withdrawal.Amount.Equal(decimal.NewFromBigInt(paid, -18)) {
+		t.Fatalf("withdrawal of %s by %s", withdrawal.Amount, withdrawal.EthAddr)
+	}
+	if _, err = tm.contract.Withdraw(tm.seller); err == nil {
+		t.Fatal("withdrew twice")
+	}
+}
@@ -1,37 +1,67 @@
 package monitor
 
 import (
-	"bytes"
-	"encoding/hex"
-	"fmt"
-	"log"
+	"context"
 	"math/big"
+	"sao-datastore-storage/model"
+	"time"
 
-	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	ethcommon "github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
-	hdwallet "github.com/miguelmota/go-ethereum-hdwallet"
+	"github.com/gwaylib/log"
 )
 
-func (m *Monitor) Finish(orderId int64) error {
-	fullpath := fmt.Sprintf("m/44'/60'/0'/0/%d", 0)
-	path := hdwallet.MustParseDerivationPath(fullpath)
-	account, _ := m.Wallet.Derive(path, true)
-	fmt.Println(account.Address.Hex())
-	nonce, _ := m.provider.GetNonce(account.Address)
-	fmt.Println(nonce)
-	gas_price, _ := m.provider.Getgasprice()
+const (
+	// attempts to send the finish tx of an order before it is failed
+	maxFinishAttempts = 5
+	// delay before the second attempt, doubled after each attempt
+	finishRetryInterval = time.Minute
+)
+
+// Finish sends the tx finishing the order on chain, which pays its seller.
+func (m *Monitor) Finish(ctx context.Context, orderId uint) error {
+	data, err := calldata(ctx, func(opts *bind.TransactOpts) (*types.Transaction, error) {
+		return m.contract.Finish(opts, new(big.Int).SetUint64(uint64(orderId)))
+	})
+	if err != nil {
+		return err
+	}
+	_, err = m.txs.Send(ctx, m.contractAddress, data, &orderId)
+	return err
+}
+
+// retryFinish delays the order whose finish tx failed to be sent, so that the
+// next orders are finished meanwhile. It is failed after maxFinishAttempts.
+func (m *Monitor) retryFinish(order *model.PurchaseOrder) {
+	attempts := order.FinishAttempts + 1
+	var err error
+	if attempts >= maxFinishAttempts {
+		log.Warnf("giving up order %d after %d attempts to finish it", order.Id, attempts)
+		err = m.Model.FailFinishPurchaseOrder(order.Id, attempts)
+	} else {
+		err = m.Model.DelayFinishPurchaseOrder(order.Id, attempts, time.Now().Add(finishRetryInterval<<(attempts-1)))
+	}
+	if err != nil {
+		log.Error(err)
+	}
+}
 
-	to := common.HexToAddress(m.cfg.Contract)
-	var buf bytes.Buffer
-	buf.Write(m.contract.Methods["finish"].ID)
-	paramId, _ := hex.DecodeString(fmt.Sprintf("%064x", orderId))
-	buf.Write(paramId)
-	tx := types.NewTransaction(nonce, to, big.NewInt(0), big.NewInt(1000000).Uint64(), gas_price, buf.Bytes())
-	signedTx, signErr := m.Wallet.SignTx(account, tx, nil)
-	if signErr != nil {
-		log.Panicln("signer with signature error:", signErr)
-		return signErr
+// calldata returns the input of the tx of a method of the bindings, which is
+// signed and sent by the tx manager rather than by the bindings.
+func calldata(ctx context.Context, transact func(opts *bind.TransactOpts) (*types.Transaction, error)) ([]byte, error) {
+	tx, err := transact(&bind.TransactOpts{
+		Nonce:    new(big.Int),
+		GasPrice: new(big.Int),
+		GasLimit: 1,
+		Signer: func(_ ethcommon.Address, tx *types.Transaction) (*types.Transaction, error) {
+			return tx, nil
+		},
+		Context: ctx,
+		NoSend:  true,
+	})
+	if err != nil {
+		return nil, err
 	}
-	fmt.Println(signedTx.Hash().String())
-	return m.provider.SendTx(signedTx)
+	return tx.Data(), nil
 }
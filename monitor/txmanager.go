@@ -0,0 +1,332 @@
+package monitor
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"math/big"
+	"sao-datastore-storage/model"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/gwaylib/log"
+)
+
+// a tx pending for longer is sent again with higher fees
+const defaultBumpInterval = 3 * time.Minute
+
+// TxBackend is the chain the txs are sent to, implemented by web3.Provider.
+type TxBackend interface {
+	ChainID(ctx context.Context) (*big.Int, error)
+	// GetNonce returns the next nonce of the address, pending txs included
+	GetNonce(address ethcommon.Address) (uint64, error)
+	EstamateGas(from, to ethcommon.Address, data []byte) (uint64, error)
+	SuggestGasTipCap(ctx context.Context) (*big.Int, error)
+	LatestHeader(ctx context.Context) (*types.Header, error)
+	SendTx(tx *types.Transaction) error
+	TransactionReceipt(ctx context.Context, hash ethcommon.Hash) (*types.Receipt, error)
+}
+
+// TxManager sends EIP-1559 txs from an account, tracking its nonce locally so
+// that several txs are pending at once. The txs are recorded before they are
+// sent, then their receipts are polled, and the ones stuck in the mempool are
+// sent again with higher fees.
+type TxManager struct {
+	backend TxBackend
+	m       *model.Model
+	key     *ecdsa.PrivateKey
+	from    ethcommon.Address
+
+	BumpInterval time.Duration
+
+	lk      sync.Mutex
+	chainId *big.Int
+	// next nonce, 0 until loaded
+	nonce       uint64
+	nonceLoaded bool
+}
+
+func NewTxManager(backend TxBackend, m *model.Model, key *ecdsa.PrivateKey) *TxManager {
+	return &TxManager{
+		backend:      backend,
+		m:            m,
+		key:          key,
+		from:         crypto.PubkeyToAddress(key.PublicKey),
+		BumpInterval: defaultBumpInterval,
+	}
+}
+
+func (t *TxManager) From() ethcommon.Address {
+	return t.from
+}
+
+// Send sends a tx calling to with data, which finishes the order orderId if
+// not nil.
+func (t *TxManager) Send(ctx context.Context, to ethcommon.Address, data []byte, orderId *uint) (*model.ChainTx, error) {
+	t.lk.Lock()
+	defer t.lk.Unlock()
+
+	nonce, err := t.nextNonce()
+	if err != nil {
+		return nil, err
+	}
+	gasLimit, err := t.backend.EstamateGas(t.from, to, data)
+	if err != nil {
+		return nil, fmt.Errorf("estimate gas: %w", err)
+	}
+	tip, feeCap, err := t.suggestFees(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	record := model.ChainTx{
+		FromAddr:  t.from.Hex(),
+		Nonce:     nonce,
+		ToAddr:    to.Hex(),
+		Data:      hexutil.Encode(data),
+		GasLimit:  gasLimit * 6 / 5,
+		GasTipCap: tip.String(),
+		GasFeeCap: feeCap.String(),
+		Status:    model.ChainTxPending,
+		SentAt:    time.Now(),
+		OrderId:   orderId,
+	}
+	signed, err := t.sign(ctx, &record)
+	if err != nil {
+		return nil, err
+	}
+	err = t.m.Transaction(func(tx *model.Model) error {
+		if err := tx.CreateChainTx(&record); err != nil {
+			return err
+		}
+		if orderId != nil {
+			return tx.StartFinishPurchaseOrder(*orderId, record.Hash)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err = t.backend.SendTx(signed); err != nil {
+		if isNonceTooLow(err) {
+			t.nonceLoaded = false
+		}
+		// the nonce is used by the next tx
+		if err := t.m.DeleteChainTx(&record); err != nil {
+			log.Error(err)
+		}
+		if orderId != nil {
+			if err := t.m.UpdatePurchaseOrderStateFrom(*orderId, model.FinishContractStarted, model.ReadyToDownload); err != nil {
+				log.Error(err)
+			}
+		}
+		return nil, err
+	}
+	t.nonce = nonce + 1
+	log.Infof("sent tx %s nonce %d to %s", record.Hash, nonce, to.Hex())
+	return &record, nil
+}
+
+// nextNonce returns the nonce after the last one used by the account, on
+// chain or by the txs recorded.
+func (t *TxManager) nextNonce() (uint64, error) {
+	pending, err := t.backend.GetNonce(t.from)
+	if err != nil {
+		return 0, err
+	}
+	if !t.nonceLoaded {
+		recorded, err := t.m.GetNextChainTxNonce(t.from.Hex())
+		if err != nil {
+			return 0, err
+		}
+		t.nonce = recorded
+		t.nonceLoaded = true
+	}
+	// the account sent txs on its own
+	if pending > t.nonce {
+		t.nonce = pending
+	}
+	return t.nonce, nil
+}
+
+// suggestFees returns the tip and the fee cap of a tx included even if the
+// base fee doubles.
+func (t *TxManager) suggestFees(ctx context.Context) (*big.Int, *big.Int, error) {
+	tip, err := t.backend.SuggestGasTipCap(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	head, err := t.backend.LatestHeader(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	if head.BaseFee == nil {
+		return nil, nil, errors.New("the chain has no base fee, EIP-1559 is not enabled")
+	}
+	feeCap := new(big.Int).Mul(head.BaseFee, big.NewInt(2))
+	return tip, feeCap.Add(feeCap, tip), nil
+}
+
+// sign signs the tx of record, setting its hash.
+func (t *TxManager) sign(ctx context.Context, record *model.ChainTx) (*types.Transaction, error) {
+	if t.chainId == nil {
+		chainId, err := t.backend.ChainID(ctx)
+		if err != nil {
+			return nil, err
+		}
+		t.chainId = chainId
+	}
+	data, err := hexutil.Decode(record.Data)
+	if err != nil {
+		return nil, err
+	}
+	tip, ok := new(big.Int).SetString(record.GasTipCap, 10)
+	if !ok {
+		return nil, fmt.Errorf("invalid gas tip cap %q", record.GasTipCap)
+	}
+	feeCap, ok := new(big.Int).SetString(record.GasFeeCap, 10)
+	if !ok {
+		return nil, fmt.Errorf("invalid gas fee cap %q", record.GasFeeCap)
+	}
+	to := ethcommon.HexToAddress(record.ToAddr)
+	signed, err := types.SignNewTx(t.key, types.LatestSignerForChainID(t.chainId), &types.DynamicFeeTx{
+		ChainID:   t.chainId,
+		Nonce:     record.Nonce,
+		GasTipCap: tip,
+		GasFeeCap: feeCap,
+		Gas:       record.GasLimit,
+		To:        &to,
+		Data:      data,
+	})
+	if err != nil {
+		return nil, err
+	}
+	record.Hash = signed.Hash().Hex()
+	return signed, nil
+}
+
+// Poll checks the receipts of the pending txs, and sends again with higher
+// fees the ones pending for longer than BumpInterval.
+func (t *TxManager) Poll(ctx context.Context) error {
+	t.lk.Lock()
+	defer t.lk.Unlock()
+
+	txs, err := t.m.GetPendingChainTxs(t.from.Hex())
+	if err != nil {
+		return err
+	}
+	for i := range txs {
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err = t.checkTx(ctx, &txs[i]); err != nil {
+			log.Warnf("tx %s nonce %d: %v", txs[i].Hash, txs[i].Nonce, err)
+		}
+	}
+	return nil
+}
+
+func (t *TxManager) checkTx(ctx context.Context, record *model.ChainTx) error {
+	// any of the txs of the nonce may be mined
+	for _, hash := range record.Hashes() {
+		receipt, err := t.backend.TransactionReceipt(ctx, ethcommon.HexToHash(hash))
+		if errors.Is(err, ethereum.NotFound) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		return t.settle(record, hash, receipt)
+	}
+
+	if time.Since(record.SentAt) < t.BumpInterval {
+		return nil
+	}
+	return t.bump(ctx, record)
+}
+
+// settle records the outcome of the mined tx of record, and moves its order
+// to a confirmed or failed state.
+func (t *TxManager) settle(record *model.ChainTx, hash string, receipt *types.Receipt) error {
+	record.Hash = hash
+	from, to := model.FinishContractStarted, model.FinishContractConfirmed
+	if receipt.Status == types.ReceiptStatusSuccessful {
+		record.Status = model.ChainTxConfirmed
+		log.Infof("tx %s confirmed in block %s", hash, receipt.BlockNumber)
+	} else {
+		record.Status = model.ChainTxFailed
+		to = model.FinishContractFailed
+		log.Warnf("tx %s reverted in block %s", hash, receipt.BlockNumber)
+	}
+	return t.m.Transaction(func(tx *model.Model) error {
+		if err := tx.SaveChainTx(record); err != nil {
+			return err
+		}
+		if record.OrderId != nil {
+			return tx.UpdatePurchaseOrderStateFrom(*record.OrderId, from, to)
+		}
+		return nil
+	})
+}
+
+// bump replaces the tx of record by one with the same nonce and fees 1/8
+// higher at least, as nodes require to replace a tx.
+func (t *TxManager) bump(ctx context.Context, record *model.ChainTx) error {
+	tip, feeCap, err := t.suggestFees(ctx)
+	if err != nil {
+		return err
+	}
+	oldTip, _ := new(big.Int).SetString(record.GasTipCap, 10)
+	oldFeeCap, _ := new(big.Int).SetString(record.GasFeeCap, 10)
+	tip = maxBig(tip, bumpFee(oldTip))
+	feeCap = maxBig(feeCap, bumpFee(oldFeeCap))
+	feeCap = maxBig(feeCap, tip)
+
+	replaced := record.Hash
+	record.GasTipCap = tip.String()
+	record.GasFeeCap = feeCap.String()
+	signed, err := t.sign(ctx, record)
+	if err != nil {
+		return err
+	}
+	if record.ReplacedHashes != "" {
+		record.ReplacedHashes += ","
+	}
+	record.ReplacedHashes += replaced
+	record.SentAt = time.Now()
+	// recorded first, the replaced tx is still looked for if this one is
+	// never sent
+	if err = t.m.SaveChainTx(record); err != nil {
+		return err
+	}
+	log.Infof("tx %s nonce %d stuck, replacing it by %s with tip %s fee cap %s", replaced, record.Nonce, record.Hash, tip, feeCap)
+	return t.backend.SendTx(signed)
+}
+
+func bumpFee(fee *big.Int) *big.Int {
+	if fee == nil {
+		return new(big.Int)
+	}
+	bumped := new(big.Int).Div(fee, big.NewInt(8))
+	bumped.Add(bumped, fee)
+	return bumped.Add(bumped, big.NewInt(1))
+}
+
+func maxBig(a, b *big.Int) *big.Int {
+	if a.Cmp(b) >= 0 {
+		return a
+	}
+	return b
+}
+
+func isNonceTooLow(err error) bool {
+	return strings.Contains(err.Error(), "nonce too low")
+}
@@ -0,0 +1,168 @@
+package monitor
+
+import (
+	"context"
+	"math/big"
+	"path/filepath"
+	"sao-datastore-storage/model"
+	"testing"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind/backends"
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type simTxBackend struct {
+	*backends.SimulatedBackend
+	// sends dropped, as if the txs were stuck in the mempool
+	drop int
+}
+
+func (b *simTxBackend) ChainID(ctx context.Context) (*big.Int, error) {
+	return b.Blockchain().Config().ChainID, nil
+}
+
+func (b *simTxBackend) GetNonce(address ethcommon.Address) (uint64, error) {
+	return b.PendingNonceAt(context.Background(), address)
+}
+
+func (b *simTxBackend) EstamateGas(from, to ethcommon.Address, data []byte) (uint64, error) {
+	return b.EstimateGas(context.Background(), ethereum.CallMsg{From: from, To: &to, Data: data})
+}
+
+func (b *simTxBackend) LatestHeader(ctx context.Context) (*types.Header, error) {
+	return b.HeaderByNumber(ctx, nil)
+}
+
+func (b *simTxBackend) SendTx(tx *types.Transaction) error {
+	if b.drop > 0 {
+		b.drop--
+		return nil
+	}
+	return b.SendTransaction(context.Background(), tx)
+}
+
+func newTestTxManager(t *testing.T) (*TxManager, *simTxBackend, *model.Model) {
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "test.db")), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = db.AutoMigrate(&model.ChainTx{}, &model.PurchaseOrder{}); err != nil {
+		t.Fatal(err)
+	}
+	m := &model.Model{DB: db}
+
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	alloc := core.GenesisAlloc{crypto.PubkeyToAddress(key.PublicKey): {Balance: big.NewInt(1e18)}}
+	backend := &simTxBackend{SimulatedBackend: backends.NewSimulatedBackend(alloc, 8000000)}
+	t.Cleanup(func() { backend.Close() })
+	return NewTxManager(backend, m, key), backend, m
+}
+
+func checkOrderState(t *testing.T, m *model.Model, orderId uint, state model.OrderState) {
+	var order model.PurchaseOrder
+	if err := m.DB.Where("id = ?", orderId).First(&order).Error; err != nil {
+		t.Fatal(err)
+	}
+	if order.State != state {
+		t.Fatalf("order %d is %s, expected %s", orderId, order.State, state)
+	}
+}
+
+func TestTxManager(t *testing.T) {
+	txs, backend, m := newTestTxManager(t)
+	ctx := context.Background()
+	to := ethcommon.HexToAddress("0x0000000000000000000000000000000000005a0f")
+
+	for _, orderId := range []uint{0, 1} {
+		order := model.PurchaseOrder{Id: orderId, State: model.ReadyToDownload}
+		if err := m.DB.Create(&order).Error; err != nil {
+			t.Fatal(err)
+		}
+		record, err := txs.Send(ctx, to, []byte{byte(orderId)}, &orderId)
+		if err != nil {
+			t.Fatal("failed to send tx", err)
+		}
+		if record.Nonce != uint64(orderId) {
+			t.Fatalf("tx of order %d sent with nonce %d", orderId, record.Nonce)
+		}
+		checkOrderState(t, m, orderId, model.FinishContractStarted)
+	}
+
+	// not mined yet
+	if err := txs.Poll(ctx); err != nil {
+		t.Fatal(err)
+	}
+	checkOrderState(t, m, 0, model.FinishContractStarted)
+
+	backend.Commit()
+	if err := txs.Poll(ctx); err != nil {
+		t.Fatal(err)
+	}
+	for _, orderId := range []uint{0, 1} {
+		checkOrderState(t, m, orderId, model.FinishContractConfirmed)
+	}
+	pending, err := m.GetPendingChainTxs(txs.From().Hex())
+	if err != nil || len(pending) > 0 {
+		t.Fatalf("%d txs still pending, %v", len(pending), err)
+	}
+
+	// the next nonce is loaded from the records
+	again := NewTxManager(backend, m, txs.key)
+	record, err := again.Send(ctx, to, nil, nil)
+	if err != nil {
+		t.Fatal("failed to send tx", err)
+	}
+	if record.Nonce != 2 {
+		t.Fatalf("tx sent with nonce %d, expected 2", record.Nonce)
+	}
+}
+
+func TestTxManagerBump(t *testing.T) {
+	txs, backend, m := newTestTxManager(t)
+	txs.BumpInterval = 0
+	ctx := context.Background()
+	to := ethcommon.HexToAddress("0x0000000000000000000000000000000000005a0f")
+
+	backend.drop = 1
+	sent, err := txs.Send(ctx, to, nil, nil)
+	if err != nil {
+		t.Fatal("failed to send tx", err)
+	}
+	if err = txs.Poll(ctx); err != nil {
+		t.Fatal(err)
+	}
+	pending, err := m.GetPendingChainTxs(txs.From().Hex())
+	if err != nil || len(pending) != 1 {
+		t.Fatalf("%d txs pending, %v", len(pending), err)
+	}
+	bumped := pending[0]
+	if bumped.Nonce != sent.Nonce || bumped.Hash == sent.Hash || bumped.ReplacedHashes != sent.Hash {
+		t.Fatalf("tx %s nonce %d not replaced by %s nonce %d", sent.Hash, sent.Nonce, bumped.Hash, bumped.Nonce)
+	}
+	oldTip, _ := new(big.Int).SetString(sent.GasTipCap, 10)
+	tip, _ := new(big.Int).SetString(bumped.GasTipCap, 10)
+	if tip.Cmp(oldTip) <= 0 {
+		t.Fatalf("tip %s not bumped from %s", tip, oldTip)
+	}
+
+	backend.Commit()
+	if err = txs.Poll(ctx); err != nil {
+		t.Fatal(err)
+	}
+	var record model.ChainTx
+	if err = m.DB.First(&record, bumped.Id).Error; err != nil {
+		t.Fatal(err)
+	}
+	if record.Status != model.ChainTxConfirmed || record.Hash != bumped.Hash {
+		t.Fatalf("tx %s is %s", record.Hash, record.Status)
+	}
+}
@@ -0,0 +1,168 @@
+package proc
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"golang.org/x/xerrors"
+	"sao-datastore-storage/model"
+	"sao-datastore-storage/util"
+)
+
+// MaxAuthMessageValidity bounds the expiry of an auth message, so that a
+// signature seen by the ds server is not replayable for long.
+const MaxAuthMessageValidity = time.Hour
+
+// AuthMessage returns the message the client signs to have the proc nodes
+// procPeers decrypt the file fileId for it until expiry.
+func AuthMessage(fileId string, procPeers []string, expiry time.Time) string {
+	return fmt.Sprintf("SAO file access\nfile: %s\nproc nodes: %s\nexpires: %d", fileId, strings.Join(procPeers, ","), expiry.Unix())
+}
+
+// parseAuthMessage returns the file, proc nodes and expiry of message, which
+// must be built by AuthMessage.
+func parseAuthMessage(message string) (string, []string, time.Time, error) {
+	var fileId, procPeers string
+	var expiry int64
+	_, err := fmt.Sscanf(message, "SAO file access\nfile: %s\nproc nodes: %s\nexpires: %d", &fileId, &procPeers, &expiry)
+	if err != nil {
+		return "", nil, time.Time{}, xerrors.Errorf("invalid auth message: %w", err)
+	}
+	peers := strings.Split(procPeers, ",")
+	if AuthMessage(fileId, peers, time.Unix(expiry, 0)) != message {
+		return "", nil, time.Time{}, errors.New("invalid auth message")
+	}
+	return fileId, peers, time.Unix(expiry, 0), nil
+}
+
+// AuthMessageExpiry returns when the auth message signed by a client
+// expires, the proc nodes refuse it afterwards.
+func AuthMessageExpiry(signatureMessage string) (time.Time, error) {
+	message, _ := url.QueryUnescape(signatureMessage)
+	_, _, expiry, err := parseAuthMessage(message)
+	return expiry, err
+}
+
+// verifyClient checks the request is signed by the client, with an unexpired
+// auth message for the file fileId and the proc node procPeer, and returns
+// the client public key.
+func verifyClient(clientId string, fileId string, procPeer string, auth ClientAuth, now time.Time) (*ecdsa.PublicKey, error) {
+	message, _ := url.QueryUnescape(auth.SignatureMessage)
+	signedFileId, procPeers, expiry, err := parseAuthMessage(message)
+	if err != nil {
+		return nil, err
+	}
+	if signedFileId != fileId {
+		return nil, xerrors.Errorf("auth message is signed for file %s", signedFileId)
+	}
+	if !containsString(procPeers, procPeer) {
+		return nil, xerrors.Errorf("auth message is not signed for proc node %s", procPeer)
+	}
+	if !now.Before(expiry) {
+		return nil, xerrors.Errorf("auth message expired at %s", expiry)
+	}
+	if expiry.After(now.Add(MaxAuthMessageValidity)) {
+		return nil, xerrors.Errorf("auth message expires at %s, later than %s", expiry, MaxAuthMessageValidity)
+	}
+
+	pub, err := util.RecoverEthereumPubkey(clientId, auth.Signature, message)
+	if err != nil {
+		return nil, xerrors.Errorf("invalid client signature: %w", err)
+	}
+	return pub, nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// authorize checks the client either owns the file or bought it on chain.
+// The proc node has no access to the purchase orders of the ds server, so
+// purchases are checked against the contract configured in Monitor.
+func (p *ProcNode) authorize(ctx context.Context, fileId string, clientId string, keyStore *model.KeyStore, auth ClientAuth) error {
+	if keyStore.Owner != "" && strings.EqualFold(keyStore.Owner, clientId) {
+		return nil
+	}
+
+	if p.contract == nil {
+		return errors.New("not purchased, no chain provider configured")
+	}
+	if auth.TokenId <= 0 {
+		return errors.New("not purchased, missing token id")
+	}
+	if !ethcommon.IsHexAddress(keyStore.Owner) {
+		return errors.New("not purchased, file owner unknown")
+	}
+	tokenId := big.NewInt(auth.TokenId)
+
+	// anyone may mint a token for any file id, so the token must have been
+	// minted by the file owner, and listed for this file when minted
+	start := uint64(0)
+	if p.config.Monitor.BlockNumber > 0 {
+		start = uint64(p.config.Monitor.BlockNumber)
+	}
+	filterOpts := &bind.FilterOpts{Start: start, Context: ctx}
+	mints, err := p.contract.FilterTransfer(filterOpts, []ethcommon.Address{{}}, []ethcommon.Address{ethcommon.HexToAddress(keyStore.Owner)}, []*big.Int{tokenId})
+	if err != nil {
+		return xerrors.Errorf("filter transfer logs: %w", err)
+	}
+	defer mints.Close()
+	minted := mints.Next()
+	if err = mints.Error(); err != nil {
+		return xerrors.Errorf("filter transfer logs: %w", err)
+	}
+	if !minted {
+		return xerrors.Errorf("token %d is not minted by the file owner %s", auth.TokenId, keyStore.Owner)
+	}
+
+	listings, err := p.contract.FilterListing(filterOpts, []*big.Int{tokenId})
+	if err != nil {
+		return xerrors.Errorf("filter listing logs: %w", err)
+	}
+	defer listings.Close()
+	listed := false
+	for listings.Next() {
+		if listings.Event.FileId.String() == fileId && listings.Event.Raw.TxHash == mints.Event.Raw.TxHash {
+			listed = true
+			break
+		}
+	}
+	if err = listings.Error(); err != nil {
+		return xerrors.Errorf("filter listing logs: %w", err)
+	}
+	if !listed {
+		return xerrors.Errorf("token %d is not minted for file %s", auth.TokenId, fileId)
+	}
+
+	client := ethcommon.HexToAddress(clientId)
+	opts := &bind.CallOpts{Context: ctx}
+	bought, err := p.contract.Buyer(opts, tokenId, client)
+	if err != nil {
+		return xerrors.Errorf("call buyer: %w", err)
+	}
+	if bought {
+		return nil
+	}
+
+	owner, err := p.contract.OwnerOf(opts, tokenId)
+	if err != nil {
+		return xerrors.Errorf("call ownerOf: %w", err)
+	}
+	if owner == client {
+		return nil
+	}
+	return xerrors.Errorf("%s has not purchased token %d", clientId, auth.TokenId)
+}
@@ -0,0 +1,201 @@
+package proc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sao-datastore-storage/common"
+	"sao-datastore-storage/model"
+	"sao-datastore-storage/util"
+	"sao-datastore-storage/web3"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// fakeSAOFile answers the log queries and the calls authorize makes to the
+// SAOFile contract.
+type fakeSAOFile struct {
+	bind.ContractBackend
+	abi    *abi.ABI
+	logs   []types.Log
+	owners map[int64]ethcommon.Address
+	buyers map[int64][]ethcommon.Address
+}
+
+// mint logs the mint of tokenId for fileId by to, as SAOFile.mint does.
+func (f *fakeSAOFile) mint(t *testing.T, to ethcommon.Address, tokenId int64, fileId int64) {
+	txHash := ethcommon.BigToHash(big.NewInt(tokenId))
+	f.logs = append(f.logs, types.Log{
+		Topics: []ethcommon.Hash{f.abi.Events["Transfer"].ID, {}, to.Hash(), ethcommon.BigToHash(big.NewInt(tokenId))},
+		TxHash: txHash,
+	})
+	listing := f.abi.Events["Listing"]
+	data, err := listing.Inputs.NonIndexed().Pack(big.NewInt(fileId), big.NewInt(1e15), big.NewInt(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.logs = append(f.logs, types.Log{
+		Topics: []ethcommon.Hash{listing.ID, ethcommon.BigToHash(big.NewInt(tokenId))},
+		Data:   data,
+		TxHash: txHash,
+	})
+	f.owners[tokenId] = to
+}
+
+func (f *fakeSAOFile) FilterLogs(ctx context.Context, query ethereum.FilterQuery) ([]types.Log, error) {
+	var logs []types.Log
+	for _, l := range f.logs {
+		if matchTopics(l.Topics, query.Topics) {
+			logs = append(logs, l)
+		}
+	}
+	return logs, nil
+}
+
+func matchTopics(topics []ethcommon.Hash, query [][]ethcommon.Hash) bool {
+	if len(query) > len(topics) {
+		return false
+	}
+	for i, alternatives := range query {
+		if len(alternatives) == 0 {
+			continue
+		}
+		matched := false
+		for _, topic := range alternatives {
+			matched = matched || topic == topics[i]
+		}
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
+
+func (f *fakeSAOFile) CallContract(ctx context.Context, call ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	method, err := f.abi.MethodById(call.Data[:4])
+	if err != nil {
+		return nil, err
+	}
+	args, err := method.Inputs.Unpack(call.Data[4:])
+	if err != nil {
+		return nil, err
+	}
+	tokenId := args[0].(*big.Int).Int64()
+	switch method.Name {
+	case "buyer":
+		for _, buyer := range f.buyers[tokenId] {
+			if buyer == args[1].(ethcommon.Address) {
+				return method.Outputs.Pack(true)
+			}
+		}
+		return method.Outputs.Pack(false)
+	case "ownerOf":
+		owner, ok := f.owners[tokenId]
+		if !ok {
+			return nil, errors.New("execution reverted: ERC721: invalid token ID")
+		}
+		return method.Outputs.Pack(owner)
+	}
+	return nil, fmt.Errorf("unexpected call to %s", method.Name)
+}
+
+func TestAuthorize(t *testing.T) {
+	contractABI, err := web3.SAOFileMetaData.GetAbi()
+	if err != nil {
+		t.Fatal(err)
+	}
+	chain := &fakeSAOFile{
+		abi:    contractABI,
+		owners: make(map[int64]ethcommon.Address),
+		buyers: make(map[int64][]ethcommon.Address),
+	}
+	contract, err := web3.NewSAOFile(ethcommon.HexToAddress("0x5a0"), chain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := &ProcNode{config: &common.Config{}, contract: contract}
+
+	owner := ethcommon.HexToAddress("0x01")
+	buyer := ethcommon.HexToAddress("0x02")
+	attacker := ethcommon.HexToAddress("0x03")
+	keyStore := &model.KeyStore{FileId: "7", Owner: owner.Hex()}
+	chain.mint(t, owner, 1, 7)
+	chain.buyers[1] = []ethcommon.Address{buyer}
+	// the attacker mints a token for the file of the owner, then buys it
+	chain.mint(t, attacker, 2, 7)
+	chain.buyers[2] = []ethcommon.Address{attacker}
+
+	cases := []struct {
+		name    string
+		client  ethcommon.Address
+		tokenId int64
+		fileId  string
+		ok      bool
+	}{
+		{"file owner", owner, 0, "7", true},
+		{"buyer", buyer, 1, "7", true},
+		{"buyer of another file", buyer, 1, "8", false},
+		{"not bought", attacker, 1, "7", false},
+		{"token minted by a non-owner", attacker, 2, "7", false},
+		{"unknown token", buyer, 3, "7", false},
+	}
+	for _, c := range cases {
+		err := p.authorize(context.Background(), c.fileId, c.client.Hex(), keyStore, ClientAuth{TokenId: c.tokenId})
+		if (err == nil) != c.ok {
+			t.Errorf("%s: authorized %v, %v", c.name, err == nil, err)
+		}
+	}
+}
+
+func TestVerifyClient(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	clientId := crypto.PubkeyToAddress(key.PublicKey).Hex()
+	sign := func(message string) ClientAuth {
+		sig, err := crypto.Sign(util.TextHash([]byte(message)), key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sig[crypto.RecoveryIDOffset] += 27
+		return ClientAuth{Signature: hexutil.Encode(sig), SignatureMessage: message}
+	}
+	now := time.Unix(1700000000, 0)
+	procPeers := []string{"QmProc1", "QmProc2"}
+
+	cases := []struct {
+		name    string
+		message string
+		ok      bool
+	}{
+		{"valid", AuthMessage("7", procPeers, now.Add(time.Minute)), true},
+		{"other file", AuthMessage("8", procPeers, now.Add(time.Minute)), false},
+		{"other proc node", AuthMessage("7", []string{"QmProc1"}, now.Add(time.Minute)), false},
+		{"expired", AuthMessage("7", procPeers, now.Add(-time.Minute)), false},
+		{"expiring too late", AuthMessage("7", procPeers, now.Add(2*MaxAuthMessageValidity)), false},
+		{"not canonical", AuthMessage("7", procPeers, now.Add(time.Minute)) + "\n", false},
+		{"arbitrary", "hello", false},
+	}
+	for _, c := range cases {
+		_, err := verifyClient(clientId, "7", "QmProc2", sign(c.message), now)
+		if (err == nil) != c.ok {
+			t.Errorf("%s: verified %v, %v", c.name, err == nil, err)
+		}
+	}
+
+	// signed by another account
+	auth := sign(AuthMessage("7", procPeers, now.Add(time.Minute)))
+	if _, err = verifyClient(ethcommon.HexToAddress("0x01").Hex(), "7", "QmProc2", auth, now); err == nil {
+		t.Error("verified the signature of another client")
+	}
+}
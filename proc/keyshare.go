@@ -0,0 +1,343 @@
+package proc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/libp2p/go-libp2p-core/network"
+	"github.com/libp2p/go-libp2p-core/peer"
+	"golang.org/x/xerrors"
+	"sao-datastore-storage/model"
+	"sao-datastore-storage/util"
+	"sao-datastore-storage/util/fileprocess"
+	"sao-datastore-storage/util/shamir"
+)
+
+// File keys are split into shares held by different proc nodes, any
+// Threshold of which recover the key. Shares are only exchanged between proc
+// nodes listed in Libp2p.DirectPeers, so the key never leaves the proc nodes.
+const FileKeyShareProtocolDraft = "/sao/file/keyshare/0.0.1"
+const FileKeyShareStoreProtocolDraft = "/sao/file/keyshare/store/0.0.1"
+const FileKeyShareProtocol = "/sao/file/keyshare/1.0.0"
+const FileKeyShareStoreProtocol = "/sao/file/keyshare/store/1.0.0"
+
+func (p *ProcNode) isProcPeer(peerId peer.ID) bool {
+	return containsPeer(p.config.Libp2p.DirectPeers, peerId)
+}
+
+// isDsPeer reports whether peerId is one of the ds servers allowed to request
+// the encryption of chunks.
+func (p *ProcNode) isDsPeer(peerId peer.ID) bool {
+	return containsPeer(p.config.Libp2p.DsPeers, peerId)
+}
+
+func containsPeer(configPeers []string, peerId peer.ID) bool {
+	for _, configPeer := range configPeers {
+		addrInfo, err := peer.AddrInfoFromString(configPeer)
+		if err != nil {
+			continue
+		}
+		if addrInfo.ID == peerId {
+			return true
+		}
+	}
+	return false
+}
+
+// shareFileKey splits the file key between the share peers and returns the
+// peers which stored a share.
+func (p *ProcNode) shareFileKey(ctx context.Context, req FileEncryptReq, size uint64, key []byte, nonce []byte) ([]string, int, error) {
+	sharePeers := req.SharePeers
+	if len(sharePeers) == 0 {
+		sharePeers = []string{p.host.ID().String()}
+	}
+	threshold := req.Threshold
+	if threshold <= 0 || threshold > len(sharePeers) {
+		threshold = len(sharePeers)/2 + 1
+	}
+
+	shares, err := shamir.Split(key, len(sharePeers), threshold)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var lk sync.Mutex
+	var stored []string
+	var wg sync.WaitGroup
+	for i, sharePeer := range sharePeers {
+		keyStore := model.KeyStore{
+			Id:        uuid.New().String(),
+			FileId:    req.FileId,
+			Offset:    req.Offset,
+			Size:      size,
+			Key:       shares[i],
+			Nonce:     nonce,
+			Threshold: threshold,
+			Owner:     req.ClientId,
+			// EncryptStream seals the chunk in the current format
+			EncryptionVersion: fileprocess.EncryptionVersion,
+		}
+
+		wg.Add(1)
+		go func(sharePeer string) {
+			defer wg.Done()
+			var err error
+			if sharePeer == p.host.ID().String() {
+				err = p.model.ReplaceKeyStore(&keyStore)
+			} else {
+				err = p.storeKeyShare(ctx, sharePeer, keyStore)
+			}
+			if err != nil {
+				log.Warnw("storing file key share", "peer", sharePeer, "err", err)
+				return
+			}
+			lk.Lock()
+			stored = append(stored, sharePeer)
+			lk.Unlock()
+		}(sharePeer)
+	}
+	wg.Wait()
+
+	if len(stored) < threshold {
+		return nil, 0, fmt.Errorf("only %d of %d required key shares stored", len(stored), threshold)
+	}
+	return stored, threshold, nil
+}
+
+func (p *ProcNode) storeKeyShare(ctx context.Context, sharePeer string, keyStore model.KeyStore) error {
+	peerId, err := peer.Decode(sharePeer)
+	if err != nil {
+		return err
+	}
+	if !p.isProcPeer(peerId) {
+		return fmt.Errorf("peer %s is not a proc node", sharePeer)
+	}
+
+	s, format, err := NewStream(ctx, p.host, peerId, FileKeyShareStoreProtocols)
+	if err != nil {
+		return xerrors.Errorf("failed to open stream to peer %s: %w", peerId, err)
+	}
+	defer s.Close()
+
+	req := FileKeyShareStoreReq{
+		FileId:            keyStore.FileId,
+		Offset:            keyStore.Offset,
+		Size:              keyStore.Size,
+		Share:             keyStore.Key,
+		Nonce:             keyStore.Nonce,
+		Threshold:         keyStore.Threshold,
+		Owner:             keyStore.Owner,
+		EncryptionVersion: keyStore.EncryptionVersion,
+	}
+	var resp FileKeyShareStoreResp
+	if err = util.DoRpc(ctx, s, &req, &resp, format); err != nil {
+		return xerrors.Errorf("send key share rpc: %w", err)
+	}
+	if !resp.Accepted {
+		return fmt.Errorf("key share rejected by peer %s", peerId)
+	}
+	return nil
+}
+
+// recoverFileKey gathers key shares of a file chunk from the share peers and
+// combines them.
+func (p *ProcNode) recoverFileKey(ctx context.Context, req FileDecryptReq) (*model.KeyStore, error) {
+	condition := map[string]interface{}{"file_id": req.FileId, "size": req.Size}
+	keyStore, err := p.model.GetKeyStore(condition, req.Offset)
+	if err == nil && keyStore.Threshold == 0 {
+		// the whole key is stored on this node
+		return &keyStore, nil
+	}
+
+	threshold := req.Threshold
+	var shares [][]byte
+	var nonce []byte
+	var owner string
+	var version int
+	if err == nil {
+		threshold = keyStore.Threshold
+		shares = append(shares, keyStore.Key)
+		nonce = keyStore.Nonce
+		owner = keyStore.Owner
+		version = keyStore.EncryptionVersion
+	}
+	if threshold <= 0 {
+		return nil, errors.New("no key share found")
+	}
+
+	type shareResult struct {
+		resp *FileKeyShareResp
+		err  error
+	}
+	gctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	results := make(chan shareResult, len(req.SharePeers))
+	requested := 0
+	for _, sharePeer := range req.SharePeers {
+		if sharePeer == p.host.ID().String() {
+			continue
+		}
+		requested++
+		go func(sharePeer string) {
+			resp, err := p.fetchKeyShare(gctx, sharePeer, req)
+			results <- shareResult{resp: resp, err: err}
+		}(sharePeer)
+	}
+
+	for ; requested > 0 && len(shares) < threshold; requested-- {
+		result := <-results
+		if result.err != nil {
+			log.Warnw("fetching file key share", "err", result.err)
+			continue
+		}
+		if result.resp.Threshold != threshold {
+			log.Warnw("file key share threshold mismatch", "expected", threshold, "got", result.resp.Threshold)
+			continue
+		}
+		shares = append(shares, result.resp.Share)
+		nonce = result.resp.Nonce
+		version = result.resp.EncryptionVersion
+		if owner == "" {
+			owner = result.resp.Owner
+		}
+	}
+	if len(shares) < threshold {
+		return nil, fmt.Errorf("only %d of %d required key shares found", len(shares), threshold)
+	}
+
+	key, err := shamir.Combine(shares[:threshold])
+	if err != nil {
+		return nil, err
+	}
+	return &model.KeyStore{
+		FileId:            req.FileId,
+		Offset:            req.Offset,
+		Size:              req.Size,
+		Key:               key,
+		Nonce:             nonce,
+		Owner:             owner,
+		EncryptionVersion: version,
+	}, nil
+}
+
+func (p *ProcNode) fetchKeyShare(ctx context.Context, sharePeer string, decryptReq FileDecryptReq) (*FileKeyShareResp, error) {
+	peerId, err := peer.Decode(sharePeer)
+	if err != nil {
+		return nil, err
+	}
+	if !p.isProcPeer(peerId) {
+		return nil, fmt.Errorf("peer %s is not a proc node", sharePeer)
+	}
+
+	s, format, err := NewStream(ctx, p.host, peerId, FileKeyShareProtocols)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to open stream to peer %s: %w", peerId, err)
+	}
+	defer s.Close()
+
+	req := FileKeyShareReq{
+		FileId: decryptReq.FileId,
+		Offset: decryptReq.Offset,
+		Size:   decryptReq.Size,
+	}
+	var resp FileKeyShareResp
+	if err = util.DoRpc(ctx, s, &req, &resp, format); err != nil {
+		return nil, xerrors.Errorf("send key share rpc: %w", err)
+	}
+	if !resp.Accepted {
+		return nil, fmt.Errorf("no key share on peer %s", peerId)
+	}
+	return &resp, nil
+}
+
+func (p *ProcNode) handleFileKeyShareRequest(s network.Stream) {
+	defer s.Close()
+	format := FormatOf(s.Protocol())
+
+	_ = s.SetReadDeadline(time.Now().Add(processReadDeadline))
+	defer s.SetReadDeadline(time.Time{}) // nolint
+
+	var resp = &FileKeyShareResp{
+		Accepted: false,
+	}
+	if !p.isProcPeer(s.Conn().RemotePeer()) {
+		log.Warnw("key share requested by unknown peer", "peer", s.Conn().RemotePeer())
+		resp.Marshal(s, format)
+		return
+	}
+
+	var req FileKeyShareReq
+	err := req.Unmarshal(s, format)
+	if err != nil {
+		log.Warnw("reading key share req from stream", "err", err)
+		resp.Marshal(s, format)
+		return
+	}
+
+	condition := map[string]interface{}{"file_id": req.FileId, "size": req.Size}
+	keyStore, err := p.model.GetKeyStore(condition, req.Offset)
+	if err != nil || keyStore.Threshold == 0 {
+		log.Warnw("no key share found", "id", req.FileId, "offset", req.Offset, "err", err)
+		resp.Marshal(s, format)
+		return
+	}
+
+	resp = &FileKeyShareResp{
+		Share:             keyStore.Key,
+		Nonce:             keyStore.Nonce,
+		Threshold:         keyStore.Threshold,
+		Owner:             keyStore.Owner,
+		EncryptionVersion: keyStore.EncryptionVersion,
+		Accepted:          true,
+	}
+	resp.Marshal(s, format)
+}
+
+func (p *ProcNode) handleFileKeyShareStoreRequest(s network.Stream) {
+	defer s.Close()
+	format := FormatOf(s.Protocol())
+
+	_ = s.SetReadDeadline(time.Now().Add(processReadDeadline))
+	defer s.SetReadDeadline(time.Time{}) // nolint
+
+	var resp = &FileKeyShareStoreResp{
+		Accepted: false,
+	}
+	if !p.isProcPeer(s.Conn().RemotePeer()) {
+		log.Warnw("key share sent by unknown peer", "peer", s.Conn().RemotePeer())
+		resp.Marshal(s, format)
+		return
+	}
+
+	var req FileKeyShareStoreReq
+	err := req.Unmarshal(s, format)
+	if err != nil || req.Threshold <= 0 {
+		log.Warnw("reading key share store req from stream", "err", err)
+		resp.Marshal(s, format)
+		return
+	}
+
+	keyStore := model.KeyStore{
+		Id:                uuid.New().String(),
+		FileId:            req.FileId,
+		Offset:            req.Offset,
+		Size:              req.Size,
+		Key:               req.Share,
+		Nonce:             req.Nonce,
+		Threshold:         req.Threshold,
+		Owner:             req.Owner,
+		EncryptionVersion: req.EncryptionVersion,
+	}
+	if err = p.model.ReplaceKeyStore(&keyStore); err != nil {
+		log.Error(err)
+		resp.Marshal(s, format)
+		return
+	}
+
+	resp.Accepted = true
+	resp.Marshal(s, format)
+}
@@ -0,0 +1,79 @@
+package proc
+
+import (
+	"crypto/ecdsa"
+	"crypto/rand"
+	"time"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/crypto/ecies"
+	"github.com/libp2p/go-libp2p-core/network"
+)
+
+// The file key of a chunk is wrapped for the buyer so that the buyer decrypts
+// the chunk itself and no plaintext leaves the proc nodes or the ds server.
+const FileKeyWrapProtocolDraft = "/sao/file/keywrap/0.0.1"
+const FileKeyWrapProtocol = "/sao/file/keywrap/1.0.0"
+
+func (p *ProcNode) handleFileKeyWrapRequest(s network.Stream) {
+	defer s.Close()
+	format := FormatOf(s.Protocol())
+
+	_ = s.SetReadDeadline(time.Now().Add(processReadDeadline))
+	defer s.SetReadDeadline(time.Time{}) // nolint
+
+	var resp = &FileKeyWrapResp{
+		Accepted: false,
+	}
+
+	var req FileKeyWrapReq
+	err := req.Unmarshal(s, format)
+	if err != nil {
+		log.Warnw("reading key wrap req from stream", "err", err)
+		resp.Marshal(s, format)
+		return
+	}
+
+	pub, err := verifyClient(req.ClientId, req.FileId, p.host.ID().String(), req.Auth, time.Now())
+	if err != nil {
+		log.Warnw("unauthorized key wrap request", "id", req.FileId, "client", req.ClientId, "err", err)
+		resp.Marshal(s, format)
+		return
+	}
+
+	keyStore, err := p.recoverFileKey(p.ctx, FileDecryptReq{
+		FileId:     req.FileId,
+		ClientId:   req.ClientId,
+		Offset:     req.Offset,
+		Size:       req.Size,
+		SharePeers: req.SharePeers,
+		Threshold:  req.Threshold,
+	})
+	if err != nil {
+		log.Warnw("no key store record found", "err", err)
+		resp.Marshal(s, format)
+		return
+	}
+
+	if err = p.authorize(p.ctx, req.FileId, req.ClientId, keyStore, req.Auth); err != nil {
+		log.Warnw("unauthorized key wrap request", "id", req.FileId, "client", req.ClientId, "err", err)
+		resp.Marshal(s, format)
+		return
+	}
+
+	// the recovered key uses the btcec curve, ecies only knows the go-ethereum one
+	buyerKey := ecies.ImportECDSAPublic(&ecdsa.PublicKey{Curve: crypto.S256(), X: pub.X, Y: pub.Y})
+	wrappedKey, err := ecies.Encrypt(rand.Reader, buyerKey, keyStore.Key, nil, nil)
+	if err != nil {
+		log.Warnw("wrapping file key", "err", err)
+		resp.Marshal(s, format)
+		return
+	}
+
+	resp = &FileKeyWrapResp{
+		WrappedKey:        wrappedKey,
+		EncryptionVersion: keyStore.EncryptionVersion,
+		Accepted:          true,
+	}
+	resp.Marshal(s, format)
+}
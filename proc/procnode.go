@@ -1,15 +1,19 @@
 package proc
 
 import (
+	"bufio"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	ethcommon "github.com/ethereum/go-ethereum/common"
 	"github.com/filecoin-project/lotus/chain/wallet"
 	"github.com/google/uuid"
 	logging "github.com/ipfs/go-log/v2"
 	"github.com/libp2p/go-libp2p-core/host"
 	"github.com/libp2p/go-libp2p-core/network"
+	"golang.org/x/xerrors"
+	"io"
 	"os"
 	"path/filepath"
 	"sao-datastore-storage/common"
@@ -19,6 +23,7 @@ import (
 	"sao-datastore-storage/util/transport"
 	"sao-datastore-storage/util/transport/httptransport"
 	"sao-datastore-storage/util/transport/types"
+	"sao-datastore-storage/web3"
 	"time"
 )
 
@@ -26,6 +31,8 @@ var log = logging.Logger("proc")
 
 const FileProcessEncryptionProtocolDraft = "/sao/file/encrypt/0.0.1"
 const FileProcessDecryptionProtocolDraft = "/sao/file/decrypt/0.0.1"
+const FileProcessEncryptionProtocol = "/sao/file/encrypt/1.0.0"
+const FileProcessDecryptionProtocol = "/sao/file/decrypt/1.0.0"
 const processReadDeadline = 10 * time.Second
 const processWriteDeadline = 5 * 60 * time.Second
 
@@ -38,63 +45,138 @@ type ProcNode struct {
 	config    *common.Config
 	transport transport.Transport
 	repodir   string
+	// contract to check purchases, nil if Monitor is not configured
+	contract *web3.SAOFile
+	// serve the encrypted and decrypted chunks to the ds server
+	stagedFiles *httptransport.StagedFileServer
+	stagedCars  *httptransport.StagedCarServer
 }
 
 func NewProcNode(ctx context.Context, host host.Host, wallet *wallet.LocalWallet, m *model.Model, config *common.Config, repodir string) *ProcNode {
+	var contract *web3.SAOFile
+	if config.Monitor.Provider != "" {
+		provider, err := web3.NewProvider(config.Monitor.Provider)
+		if err == nil {
+			contract, err = provider.SAOFile(ethcommon.HexToAddress(config.Monitor.Contract))
+		}
+		if err != nil {
+			log.Warnw("connecting chain provider, only file owners can decrypt", "err", err)
+		}
+	}
+
 	return &ProcNode{
-		ctx:       ctx,
-		host:      host,
-		wallet:    wallet,
-		model:     m,
-		config:    config,
-		transport: httptransport.New(host),
-		repodir:   repodir,
+		ctx:         ctx,
+		host:        host,
+		wallet:      wallet,
+		model:       m,
+		config:      config,
+		transport:   httptransport.New(host),
+		repodir:     repodir,
+		contract:    contract,
+		stagedFiles: httptransport.NewStagedFileServer(node.StageProcPath(repodir), config.Transport.MaxTransferDuration*time.Second),
+		stagedCars:  httptransport.NewStagedCarServer(host, config.Transport.MaxTransferDuration*time.Second),
 	}
 }
 
-func (p *ProcNode) Start() {
-	p.host.SetStreamHandler(FileProcessEncryptionProtocolDraft, p.handleFileEncryptionRequest)
-	p.host.SetStreamHandler(FileProcessDecryptionProtocolDraft, p.handleFileDecryptionRequest)
+func (p *ProcNode) Start() error {
+	if err := p.stagedCars.Start(p.ctx); err != nil {
+		return err
+	}
+
+	p.setStreamHandler(FileProcessEncryptionProtocols, p.handleFileEncryptionRequest)
+	p.setStreamHandler(FileProcessDecryptionProtocols, p.handleFileDecryptionRequest)
+	p.setStreamHandler(FileKeyShareProtocols, p.handleFileKeyShareRequest)
+	p.setStreamHandler(FileKeyShareStoreProtocols, p.handleFileKeyShareStoreRequest)
+	p.setStreamHandler(FileKeyWrapProtocols, p.handleFileKeyWrapRequest)
 	p.serverAPI()
+	return nil
 }
 
 func (p *ProcNode) Stop(ctx context.Context) error {
-	p.host.RemoveStreamHandler(FileProcessEncryptionProtocolDraft)
-	p.host.RemoveStreamHandler(FileProcessDecryptionProtocolDraft)
-	return nil
+	p.removeStreamHandler(FileProcessEncryptionProtocols)
+	p.removeStreamHandler(FileProcessDecryptionProtocols)
+	p.removeStreamHandler(FileKeyShareProtocols)
+	p.removeStreamHandler(FileKeyShareStoreProtocols)
+	p.removeStreamHandler(FileKeyWrapProtocols)
+	return p.stagedCars.Stop(ctx)
+}
+
+// chunkTransfer authorizes the ds server to fetch the staged chunk at path,
+// either from the proc api server or as a CAR over libp2p.
+func (p *ProcNode) chunkTransfer(ctx context.Context, path string, kind string, size uint64) (types.Transfer, error) {
+	if p.config.Transport.Type == "libp2p" {
+		return p.stagedCars.Authorize(ctx, path)
+	}
+
+	headers, err := p.stagedFiles.Authorize(ctx, path)
+	if err != nil {
+		return types.Transfer{}, err
+	}
+	url := fmt.Sprintf("%s%s/api/v1/proc/%s/%s", p.config.ApiServer.ExposedPath, p.config.ApiServer.ContextPath, kind, filepath.Base(path))
+	paramsBytes, err := json.Marshal(types.HttpRequest{
+		URL:     url,
+		Headers: headers,
+	})
+	if err != nil {
+		return types.Transfer{}, xerrors.Errorf("marshalling request parameters: %w", err)
+	}
+	return types.Transfer{
+		Type:   "http",
+		Size:   size,
+		Params: paramsBytes,
+	}, nil
 }
 
 func (p *ProcNode) handleFileDecryptionRequest(s network.Stream) {
 	defer s.Close()
+	format := FormatOf(s.Protocol())
 
 	// Set a deadline on reading from the stream so it doesn't hang
 	_ = s.SetReadDeadline(time.Now().Add(processReadDeadline))
 	defer s.SetReadDeadline(time.Time{}) // nolint
 
 	var req FileDecryptReq
-	err := req.Unmarshal(s, "json")
+	err := req.Unmarshal(s, format)
 	if err != nil {
 		log.Warnw("reading fileproc req from stream", "err", err)
 		var resp = &FileDecryptResp{
 			Accepted: false,
 		}
 
-		resp.Marshal(s, "json")
+		resp.Marshal(s, format)
 		return
 	}
 
-	// lookup in database if this node has encrypted key for this offset.
-	var keyStore model.KeyStore
+	if _, err = verifyClient(req.ClientId, req.FileId, p.host.ID().String(), req.Auth, time.Now()); err != nil {
+		log.Warnw("unauthorized decryption request", "id", req.FileId, "client", req.ClientId, "err", err)
+		var resp = &FileDecryptResp{
+			Accepted: false,
+		}
+
+		resp.Marshal(s, format)
+		return
+	}
 
-	condition := map[string]interface{}{"file_id": req.FileId, "size": req.Size}
-	keyStore, err = p.model.GetKeyStore(condition, req.Offset)
+	// lookup in database if this node has encrypted key for this offset,
+	// or gather enough key shares from the other proc nodes.
+	keyStore, err := p.recoverFileKey(p.ctx, req)
 	if err != nil {
 		log.Warnw("no key store record found", "err", err)
 		var resp = &FileDecryptResp{
 			Accepted: false,
 		}
 
-		resp.Marshal(s, "json")
+		resp.Marshal(s, format)
+		return
+	}
+
+	if err = p.authorize(p.ctx, req.FileId, req.ClientId, keyStore, req.Auth); err != nil {
+		log.Warnw("unauthorized decryption request", "id", req.FileId, "client", req.ClientId, "err", err)
+		var resp = &FileDecryptResp{
+			Accepted: false,
+		}
+
+		resp.Marshal(s, format)
 		return
 	}
 
@@ -112,70 +194,89 @@ func (p *ProcNode) handleFileDecryptionRequest(s network.Stream) {
 				Accepted: false,
 			}
 
-			resp.Marshal(s, "json")
+			resp.Marshal(s, format)
 			return
 		}
 	}
 
-	var size int
+	var size int64
 	if decryptFileInfo, err := os.Stat(outFilePath); errors.Is(err, os.ErrNotExist) {
 		// path does not exist
-		size, err = fileprocess.DecryptFile(outFilePath+ENCRYPT_SUFFIX, outFilePath, keyStore.Key)
+		size, err = processChunk(outFilePath+ENCRYPT_SUFFIX, outFilePath, func(r io.Reader, w io.Writer) (int64, error) {
+			return fileprocess.DecryptStreamVersion(r, w, keyStore.Key, keyStore.EncryptionVersion)
+		})
 		if err != nil {
 			log.Warnw("decryption error", "err", err)
 			var resp = &FileDecryptResp{
 				Accepted: false,
 			}
 
-			resp.Marshal(s, "json")
+			resp.Marshal(s, format)
 			return
 		}
 	} else {
-		size = int(decryptFileInfo.Size())
+		size = decryptFileInfo.Size()
 	}
 
-	url := fmt.Sprintf("%s%s/api/v1/proc/decrypt/%s", p.config.ApiServer.ExposedPath, p.config.ApiServer.ContextPath, filepath.Base(outFilePath))
-	paramsBytes, err := json.Marshal(types.HttpRequest{
-		URL: url,
-	})
+	transfer, err := p.chunkTransfer(p.ctx, outFilePath, "decrypt", uint64(size))
+	if err != nil {
+		log.Warnw("authorizing decrypted chunk transfer", "err", err)
+		var resp = &FileDecryptResp{
+			Accepted: false,
+		}
+
+		resp.Marshal(s, format)
+		return
+	}
 
 	var resp = &FileDecryptResp{
-		Transfer: types.Transfer{
-			Type:   "http",
-			Size:   uint64(size),
-			Params: paramsBytes,
-		},
+		FileId:   req.FileId,
+		Offset:   req.Offset,
+		Size:     uint64(size),
+		Transfer: transfer,
 		Accepted: true,
 	}
 
-	err = resp.Marshal(s, "json")
+	err = resp.Marshal(s, format)
 	if err != nil {
 		log.Warnw("decryption error", "err", err)
 		var resp = &FileDecryptResp{
 			Accepted: false,
 		}
 
-		resp.Marshal(s, "json")
+		resp.Marshal(s, format)
 		return
 	}
 }
 
 func (p *ProcNode) handleFileEncryptionRequest(s network.Stream) {
 	defer s.Close()
+	format := FormatOf(s.Protocol())
 
 	// Set a deadline on reading from the stream so it doesn't hang
 	_ = s.SetReadDeadline(time.Now().Add(processReadDeadline))
 	defer s.SetReadDeadline(time.Time{}) // nolint
 
+	// only the ds servers encrypt chunks, the keys they store replace the
+	// keys of previous attempts
+	if !p.isDsPeer(s.Conn().RemotePeer()) {
+		log.Warnw("encryption requested by unknown peer", "peer", s.Conn().RemotePeer())
+		var resp = &FileEncryptResp{
+			Accepted: false,
+		}
+		resp.Marshal(s, format)
+		return
+	}
+
 	// decode request
 	var req FileEncryptReq
-	err := req.Unmarshal(s, "json")
+	err := req.Unmarshal(s, format)
 	if err != nil {
 		log.Warnw("reading fileproc req from stream", "err", err)
 		var resp = &FileEncryptResp{
 			Accepted: false,
 		}
-		resp.Marshal(s, "json")
+		resp.Marshal(s, format)
 		return
 	}
 	log.Infow("received file encryption request", "id", req.FileId, "client-peer", s.Conn().RemotePeer())
@@ -191,58 +292,58 @@ func (p *ProcNode) handleFileEncryptionRequest(s network.Stream) {
 		var resp = &FileEncryptResp{
 			Accepted: false,
 		}
-		resp.Marshal(s, "json")
+		resp.Marshal(s, format)
 		return
 	}
 
-	key, nonce, encryptedSize, err := fileprocess.EncryptFile(outFilePath, outFilePath+ENCRYPT_SUFFIX)
+	var key, nonce []byte
+	encryptedSize, err := processChunk(outFilePath, outFilePath+ENCRYPT_SUFFIX, func(r io.Reader, w io.Writer) (int64, error) {
+		var size int64
+		var err error
+		key, nonce, size, err = fileprocess.EncryptStream(r, w)
+		return size, err
+	})
+	// the raw chunk is not served
+	if err := os.Remove(outFilePath); err != nil {
+		log.Warnw("removing raw chunk", "err", err)
+	}
 	if err != nil {
+		log.Warnw("encryption error", "err", err)
 		var resp = &FileEncryptResp{
 			Accepted: false,
 		}
-		resp.Marshal(s, "json")
+		resp.Marshal(s, format)
 		return
 	}
 
 	keyId := uuid.New().String()
-	keyStore := model.KeyStore{
-		Id:     keyId,
-		FileId: req.FileId,
-		Offset: req.Offset,
-		Size:   uint64(encryptedSize),
-		Key:    key,
-		Nonce:  nonce,
-	}
-	if err = p.model.CreateKeyStore(&keyStore); err != nil {
+	sharePeers, threshold, err := p.shareFileKey(tctx, req, uint64(encryptedSize), key, nonce)
+	if err != nil {
 		log.Error(err)
 		var resp = &FileEncryptResp{
 			Accepted: false,
 		}
-		resp.Marshal(s, "json")
+		resp.Marshal(s, format)
 		return
 	}
 
-	url := fmt.Sprintf("%s%s/api/v1/proc/encrypt/%s", p.config.ApiServer.ExposedPath, p.config.ApiServer.ContextPath, fmt.Sprintf("%s_%d%s", req.FileId, req.Offset, ENCRYPT_SUFFIX))
-	paramsBytes, err := json.Marshal(types.HttpRequest{
-		URL: url,
-	})
+	transfer, err := p.chunkTransfer(p.ctx, outFilePath+ENCRYPT_SUFFIX, "encrypt", uint64(encryptedSize))
 	if err != nil {
-		log.Errorf("marshalling request parameters: %w", err)
+		log.Errorf("authorizing encrypted chunk transfer: %v", err)
 		var resp = &FileEncryptResp{
 			Accepted: false,
 		}
-		resp.Marshal(s, "json")
+		resp.Marshal(s, format)
 		return
 	}
 
 	var resp = &FileEncryptResp{
-		FileKey: keyId,
-		Transfer: types.Transfer{
-			Type:   "http",
-			Size:   uint64(encryptedSize),
-			Params: paramsBytes,
-		},
-		Accepted: true,
+		FileKey:    keyId,
+		Transfer:   transfer,
+		Accepted:   true,
+		Size:       uint64(encryptedSize),
+		SharePeers: sharePeers,
+		Threshold:  threshold,
 	}
 
 	// Set a deadline on writing to the stream so it doesn't hang
@@ -250,12 +351,41 @@ func (p *ProcNode) handleFileEncryptionRequest(s network.Stream) {
 	defer s.SetWriteDeadline(time.Time{}) // nolint
 
 	// Write the response to the client
-	resp.Marshal(s, "json")
+	resp.Marshal(s, format)
 	if err != nil {
 		var resp = &FileEncryptResp{
 			Accepted: false,
 		}
-		resp.Marshal(s, "json")
+		resp.Marshal(s, format)
 		return
 	}
 }
+
+// processChunk streams the chunk staged at inPath through process into
+// outPath, from where it is served to the ds server. Nothing is left at
+// outPath on error.
+func processChunk(inPath string, outPath string, process func(r io.Reader, w io.Writer) (int64, error)) (int64, error) {
+	in, err := os.Open(inPath)
+	if err != nil {
+		return 0, err
+	}
+	defer in.Close()
+
+	out, err := os.Create(outPath)
+	if err != nil {
+		return 0, err
+	}
+	bw := bufio.NewWriter(out)
+	size, err := process(bufio.NewReader(in), bw)
+	if err == nil {
+		err = bw.Flush()
+	}
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(outPath)
+		return 0, err
+	}
+	return size, nil
+}
@@ -3,16 +3,17 @@ package proc
 import (
 	"fmt"
 	"github.com/gin-gonic/gin"
-	"path/filepath"
-	"sao-datastore-storage/cmd"
 )
 
 func (p *ProcNode) serverAPI() {
 	r := gin.Default()
-	// TODO authorization
-	procDir := filepath.Join(p.repodir, cmd.FsStaging, "proc")
-	r.Static(p.config.ApiServer.ContextPath + "/api/v1/proc/encrypt", procDir)
-	r.Static(p.config.ApiServer.ContextPath + "/api/v1/proc/decrypt", procDir)
+	// chunks are only served with the auth token sent in the rpc response,
+	// until they are sent whole
+	stagedFiles := gin.WrapH(p.stagedFiles)
+	for _, path := range []string{"/api/v1/proc/encrypt/:name", "/api/v1/proc/decrypt/:name"} {
+		r.GET(p.config.ApiServer.ContextPath+path, stagedFiles)
+		r.HEAD(p.config.ApiServer.ContextPath+path, stagedFiles)
+	}
 
 	listen := fmt.Sprintf("%s:%d", p.config.ApiServer.Ip, p.config.ApiServer.Port)
 	go func() {
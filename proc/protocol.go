@@ -0,0 +1,59 @@
+package proc
+
+import (
+	"context"
+
+	"github.com/libp2p/go-libp2p-core/host"
+	"github.com/libp2p/go-libp2p-core/network"
+	"github.com/libp2p/go-libp2p-core/peer"
+	"github.com/libp2p/go-libp2p-core/protocol"
+)
+
+// Every proc protocol has a json draft version and a cbor version. Clients
+// offer both, cbor first, and the messages use the format of the protocol
+// negotiated with the peer.
+var (
+	FileProcessEncryptionProtocols = []protocol.ID{FileProcessEncryptionProtocol, FileProcessEncryptionProtocolDraft}
+	FileProcessDecryptionProtocols = []protocol.ID{FileProcessDecryptionProtocol, FileProcessDecryptionProtocolDraft}
+	FileKeyShareProtocols          = []protocol.ID{FileKeyShareProtocol, FileKeyShareProtocolDraft}
+	FileKeyShareStoreProtocols     = []protocol.ID{FileKeyShareStoreProtocol, FileKeyShareStoreProtocolDraft}
+	FileKeyWrapProtocols           = []protocol.ID{FileKeyWrapProtocol, FileKeyWrapProtocolDraft}
+)
+
+var cborProtocols = map[protocol.ID]bool{
+	FileProcessEncryptionProtocol: true,
+	FileProcessDecryptionProtocol: true,
+	FileKeyShareProtocol:          true,
+	FileKeyShareStoreProtocol:     true,
+	FileKeyWrapProtocol:           true,
+}
+
+// FormatOf returns the message format of a proc protocol.
+func FormatOf(id protocol.ID) string {
+	if cborProtocols[id] {
+		return FormatCbor
+	}
+	return FormatJson
+}
+
+// NewStream opens a stream to peerId with the first of protocols the peer
+// supports and returns the message format to use on it.
+func NewStream(ctx context.Context, h host.Host, peerId peer.ID, protocols []protocol.ID) (network.Stream, string, error) {
+	s, err := h.NewStream(ctx, peerId, protocols...)
+	if err != nil {
+		return nil, "", err
+	}
+	return s, FormatOf(s.Protocol()), nil
+}
+
+func (p *ProcNode) setStreamHandler(protocols []protocol.ID, handler network.StreamHandler) {
+	for _, id := range protocols {
+		p.host.SetStreamHandler(id, handler)
+	}
+}
+
+func (p *ProcNode) removeStreamHandler(protocols []protocol.ID) {
+	for _, id := range protocols {
+		p.host.RemoveStreamHandler(id)
+	}
+}
@@ -1,17 +1,72 @@
 package proc
 
 import (
-	"bytes"
 	"encoding/json"
+	"fmt"
 	"io"
 	"sao-datastore-storage/util/transport/types"
 
 	cborutil "github.com/filecoin-project/go-cbor-util"
+	cbor "github.com/ipfs/go-ipld-cbor"
 )
 
 const ENCRYPT_SUFFIX = ".encrypt"
 const DECRYPT_SUFFIX = ".decrypt"
 
+// wire formats of the proc rpc messages, see FormatOf
+const (
+	FormatJson = "json"
+	FormatCbor = "cbor"
+)
+
+func init() {
+	// cbor messages are maps keyed by the field names, like the json ones
+	cbor.RegisterCborType(types.Transfer{})
+	cbor.RegisterCborType(ClientAuth{})
+	cbor.RegisterCborType(FileEncryptReq{})
+	cbor.RegisterCborType(FileEncryptResp{})
+	cbor.RegisterCborType(FileDecryptReq{})
+	cbor.RegisterCborType(FileDecryptResp{})
+	cbor.RegisterCborType(FileKeyShareReq{})
+	cbor.RegisterCborType(FileKeyShareResp{})
+	cbor.RegisterCborType(FileKeyShareStoreReq{})
+	cbor.RegisterCborType(FileKeyShareStoreResp{})
+	cbor.RegisterCborType(FileKeyWrapReq{})
+	cbor.RegisterCborType(FileKeyWrapResp{})
+}
+
+func marshal(w io.Writer, format string, v interface{}) error {
+	switch format {
+	case FormatCbor:
+		return cborutil.WriteCborRPC(w, v)
+	case FormatJson:
+		return json.NewEncoder(w).Encode(v)
+	default:
+		return fmt.Errorf("unsupported rpc format %s", format)
+	}
+}
+
+// unmarshal reads a single message so that the stream does not have to be
+// closed by the peer before the message is decoded.
+func unmarshal(r io.Reader, format string, v interface{}) error {
+	switch format {
+	case FormatCbor:
+		return cborutil.ReadCborRPC(r, v)
+	case FormatJson:
+		return json.NewDecoder(r).Decode(v)
+	default:
+		return fmt.Errorf("unsupported rpc format %s", format)
+	}
+}
+
+// ClientAuth proves the client is allowed to get the file: the request is
+// signed by the client, who owns the file or bought the TokenId minted for it.
+type ClientAuth struct {
+	TokenId          int64
+	Signature        string
+	SignatureMessage string
+}
+
 type FileEncryptReq struct {
 	// file unique id
 	FileId string
@@ -23,61 +78,37 @@ type FileEncryptReq struct {
 	Size uint64
 	// transfer method
 	Transfer types.Transfer
+	// proc nodes to hold the file key shares
+	SharePeers []string
+	// number of shares required to recover the file key
+	Threshold int
 }
 
-func (f *FileEncryptReq) Unmarshal(r io.Reader, format string) (err error) {
-	if format == "json" {
-		buf := &bytes.Buffer{}
-		buf.ReadFrom(r)
-		err = json.Unmarshal(buf.Bytes(), f)
-	} else {
-		// TODO: CBOR marshal
-	}
-	return nil
+func (f *FileEncryptReq) Marshal(w io.Writer, format string) error {
+	return marshal(w, format, f)
 }
 
-func (f *FileEncryptReq) Marshal(w io.Writer, format string) error {
-	if format == "json" {
-		bytes, err := json.Marshal(f)
-		if err != nil {
-			return err
-		}
-		w.Write(bytes)
-		return nil
-	} else {
-		// TODO: CBOR marshal
-	}
-	return nil
+func (f *FileEncryptReq) Unmarshal(r io.Reader, format string) error {
+	return unmarshal(r, format, f)
 }
 
 type FileEncryptResp struct {
 	FileKey  string
 	Transfer types.Transfer
 	Accepted bool
+	// encrypted chunk size, the transfer size differs for libp2p transfers
+	Size uint64
+	// proc nodes holding the file key shares
+	SharePeers []string
+	Threshold  int
 }
 
 func (f *FileEncryptResp) Marshal(w io.Writer, format string) error {
-	if format == "cbor" {
-		err := cborutil.WriteCborRPC(w, f)
-		return err
-	} else {
-		bytes, err := json.Marshal(f)
-		if err != nil {
-			return err
-		}
-		w.Write(bytes)
-		return nil
-	}
+	return marshal(w, format, f)
 }
-func (f *FileEncryptResp) Unmarshal(r io.Reader, format string) (err error) {
-	if format == "json" {
-		buf := &bytes.Buffer{}
-		buf.ReadFrom(r)
-		err = json.Unmarshal(buf.Bytes(), f)
-	} else {
-		// TODO: CBOR marshal
-	}
-	return nil
+
+func (f *FileEncryptResp) Unmarshal(r io.Reader, format string) error {
+	return unmarshal(r, format, f)
 }
 
 type FileDecryptReq struct {
@@ -91,31 +122,19 @@ type FileDecryptReq struct {
 	Size uint64
 	// transfer method
 	Transfer types.Transfer
+	// proc nodes holding the file key shares
+	SharePeers []string
+	// number of shares required to recover the file key
+	Threshold int
+	Auth      ClientAuth
 }
 
-func (f *FileDecryptReq) Unmarshal(r io.Reader, format string) (err error) {
-	if format == "json" {
-		buf := &bytes.Buffer{}
-		buf.ReadFrom(r)
-		err = json.Unmarshal(buf.Bytes(), f)
-	} else {
-		// TODO: CBOR marshal
-	}
-	return nil
+func (f *FileDecryptReq) Marshal(w io.Writer, format string) error {
+	return marshal(w, format, f)
 }
 
-func (f *FileDecryptReq) Marshal(w io.Writer, format string) error {
-	if format == "json" {
-		bytes, err := json.Marshal(f)
-		if err != nil {
-			return err
-		}
-		w.Write(bytes)
-		return nil
-	} else {
-		// TODO: CBOR marshal
-	}
-	return nil
+func (f *FileDecryptReq) Unmarshal(r io.Reader, format string) error {
+	return unmarshal(r, format, f)
 }
 
 type FileDecryptResp struct {
@@ -127,25 +146,122 @@ type FileDecryptResp struct {
 }
 
 func (f *FileDecryptResp) Marshal(w io.Writer, format string) error {
-	if format == "cbor" {
-		err := cborutil.WriteCborRPC(w, f)
-		return err
-	} else {
-		bytes, err := json.Marshal(f)
-		if err != nil {
-			return err
-		}
-		w.Write(bytes)
-		return nil
-	}
+	return marshal(w, format, f)
 }
-func (f *FileDecryptResp) Unmarshal(r io.Reader, format string) (err error) {
-	if format == "json" {
-		buf := &bytes.Buffer{}
-		buf.ReadFrom(r)
-		err = json.Unmarshal(buf.Bytes(), f)
-	} else {
-		// TODO: CBOR marshal
-	}
-	return nil
+
+func (f *FileDecryptResp) Unmarshal(r io.Reader, format string) error {
+	return unmarshal(r, format, f)
+}
+
+type FileKeyShareReq struct {
+	// file unique id
+	FileId string
+	// process file start offset
+	Offset uint64
+	// encrypted file chunk bytes
+	Size uint64
+}
+
+func (f *FileKeyShareReq) Marshal(w io.Writer, format string) error {
+	return marshal(w, format, f)
+}
+
+func (f *FileKeyShareReq) Unmarshal(r io.Reader, format string) error {
+	return unmarshal(r, format, f)
+}
+
+type FileKeyShareResp struct {
+	Share     []byte
+	Nonce     []byte
+	Threshold int
+	// file owner address
+	Owner string
+	// format of the encrypted chunk
+	EncryptionVersion int
+	Accepted          bool
+}
+
+func (f *FileKeyShareResp) Marshal(w io.Writer, format string) error {
+	return marshal(w, format, f)
+}
+
+func (f *FileKeyShareResp) Unmarshal(r io.Reader, format string) error {
+	return unmarshal(r, format, f)
+}
+
+type FileKeyShareStoreReq struct {
+	// file unique id
+	FileId string
+	// process file start offset
+	Offset uint64
+	// encrypted file chunk bytes
+	Size      uint64
+	Share     []byte
+	Nonce     []byte
+	Threshold int
+	// file owner address
+	Owner string
+	// format of the encrypted chunk
+	EncryptionVersion int
+}
+
+func (f *FileKeyShareStoreReq) Marshal(w io.Writer, format string) error {
+	return marshal(w, format, f)
+}
+
+func (f *FileKeyShareStoreReq) Unmarshal(r io.Reader, format string) error {
+	return unmarshal(r, format, f)
+}
+
+type FileKeyShareStoreResp struct {
+	Accepted bool
+}
+
+func (f *FileKeyShareStoreResp) Marshal(w io.Writer, format string) error {
+	return marshal(w, format, f)
+}
+
+func (f *FileKeyShareStoreResp) Unmarshal(r io.Reader, format string) error {
+	return unmarshal(r, format, f)
+}
+
+type FileKeyWrapReq struct {
+	// file unique id
+	FileId string
+	// buyer address
+	ClientId string
+	// process file start offset
+	Offset uint64
+	// encrypted file chunk bytes
+	Size uint64
+	// proc nodes holding the file key shares
+	SharePeers []string
+	// number of shares required to recover the file key
+	Threshold int
+	// the file key is wrapped for the public key recovered from the signature
+	Auth ClientAuth
+}
+
+func (f *FileKeyWrapReq) Marshal(w io.Writer, format string) error {
+	return marshal(w, format, f)
+}
+
+func (f *FileKeyWrapReq) Unmarshal(r io.Reader, format string) error {
+	return unmarshal(r, format, f)
+}
+
+type FileKeyWrapResp struct {
+	// file key encrypted with ECIES for the buyer public key
+	WrappedKey []byte
+	// format of the encrypted chunk
+	EncryptionVersion int
+	Accepted          bool
+}
+
+func (f *FileKeyWrapResp) Marshal(w io.Writer, format string) error {
+	return marshal(w, format, f)
+}
+
+func (f *FileKeyWrapResp) Unmarshal(r io.Reader, format string) error {
+	return unmarshal(r, format, f)
 }
@@ -0,0 +1,89 @@
+package proc
+
+import (
+	"io"
+	"reflect"
+	"sao-datastore-storage/util/transport/types"
+	"testing"
+	"time"
+
+	"github.com/libp2p/go-libp2p-core/protocol"
+)
+
+type message interface {
+	Marshal(io.Writer, string) error
+	Unmarshal(io.Reader, string) error
+}
+
+func TestMessageRoundTrip(t *testing.T) {
+	transfer := types.Transfer{
+		Type:     "libp2p",
+		ClientID: "client",
+		Params:   []byte(`{"URL":"libp2p:///ip4/127.0.0.1/tcp/4001/p2p/QmPeer"}`),
+		Size:     1 << 20,
+	}
+	auth := ClientAuth{
+		TokenId:          7,
+		Signature:        "0xsignature",
+		SignatureMessage: "message",
+	}
+	messages := []message{
+		&FileEncryptReq{FileId: "1", ClientId: "0xclient", Offset: 10, Size: 20, Transfer: transfer, SharePeers: []string{"peer1", "peer2"}, Threshold: 2},
+		&FileEncryptResp{FileKey: "key", Transfer: transfer, Accepted: true, Size: 30, SharePeers: []string{"peer1"}, Threshold: 1},
+		&FileDecryptReq{FileId: "1", ClientId: "0xclient", Offset: 10, Size: 20, Transfer: transfer, SharePeers: []string{"peer1", "peer2"}, Threshold: 2, Auth: auth},
+		&FileDecryptResp{FileId: "1", Offset: 10, Size: 20, Transfer: transfer, Accepted: true},
+		&FileKeyShareReq{FileId: "1", Offset: 10, Size: 20},
+		&FileKeyShareResp{Share: []byte{1, 2, 3}, Nonce: []byte{4, 5}, Threshold: 2, Owner: "0xowner", EncryptionVersion: 1, Accepted: true},
+		&FileKeyShareStoreReq{FileId: "1", Offset: 10, Size: 20, Share: []byte{1, 2, 3}, Nonce: []byte{4, 5}, Threshold: 2, Owner: "0xowner", EncryptionVersion: 1},
+		&FileKeyShareStoreResp{Accepted: true},
+		&FileKeyWrapReq{FileId: "1", ClientId: "0xclient", Offset: 10, Size: 20, SharePeers: []string{"peer1"}, Threshold: 1, Auth: auth},
+		&FileKeyWrapResp{WrappedKey: []byte{6, 7, 8}, EncryptionVersion: 1, Accepted: true},
+	}
+
+	for _, format := range []string{FormatJson, FormatCbor} {
+		for _, msg := range messages {
+			// the peer keeps the stream open while waiting for the response,
+			// so a message must be decoded without reaching EOF
+			r, w := io.Pipe()
+			go func(msg message) {
+				if err := msg.Marshal(w, format); err != nil {
+					w.CloseWithError(err)
+				}
+			}(msg)
+
+			decoded := reflect.New(reflect.TypeOf(msg).Elem()).Interface().(message)
+			done := make(chan error, 1)
+			go func() {
+				done <- decoded.Unmarshal(r, format)
+			}()
+			select {
+			case err := <-done:
+				if err != nil {
+					t.Fatalf("%s %T: failed to unmarshal: %v", format, msg, err)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatalf("%s %T: unmarshal did not return before EOF", format, msg)
+			}
+			w.Close()
+
+			if !reflect.DeepEqual(msg, decoded) {
+				t.Fatalf("%s %T: round trip mismatch\n%+v\n%+v", format, msg, msg, decoded)
+			}
+		}
+	}
+}
+
+func TestFormatOf(t *testing.T) {
+	for _, protocols := range [][]protocol.ID{
+		FileProcessEncryptionProtocols,
+		FileProcessDecryptionProtocols,
+		FileKeyShareProtocols,
+		FileKeyShareStoreProtocols,
+		FileKeyWrapProtocols,
+	} {
+		// cbor is offered first
+		if FormatOf(protocols[0]) != FormatCbor || FormatOf(protocols[1]) != FormatJson {
+			t.Fatalf("unexpected formats for %v", protocols)
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sao-datastore-storage/model"
+	"time"
+)
+
+const fileExpiryPollInterval = time.Hour
+
+// owners are warned this long before their files expire
+const fileExpiryWarning = 7 * 24 * time.Hour
+
+// files expired or warned about at most at once
+const fileExpiryBatchSize = 100
+
+// StartExpiryWorker warns the owners of the files about to expire, and
+// deletes the expired files from their stores, until ctx is done.
+func (s *Server) StartExpiryWorker(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(fileExpiryPollInterval)
+		defer ticker.Stop()
+		for {
+			now := time.Now()
+			if err := s.warnExpiringFiles(now); err != nil {
+				log.Error(err)
+			}
+			if err := s.expireFiles(ctx, now); err != nil {
+				log.Error(err)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+func (s *Server) warnExpiringFiles(now time.Time) error {
+	// the expired files are deleted instead
+	files, err := s.Model.GetFilesToWarn(now.UnixMilli(), now.Add(fileExpiryWarning).UnixMilli(), fileExpiryBatchSize)
+	if err != nil {
+		return err
+	}
+	warned := make(map[uint]bool)
+	for _, file := range files {
+		err = s.Model.CreateNotification(&model.Notification{
+			EthAddr: file.EthAddr,
+			Type:    model.NotifyFileExpiring,
+			FileId:  file.PreviewId,
+			Message: fmt.Sprintf("%s expires at %s, renew it to keep it", expiringFileName(file), time.UnixMilli(file.ExpireAt).UTC().Format(time.RFC1123)),
+		})
+		if err != nil {
+			return err
+		}
+		warned[file.FileId] = true
+	}
+	for fileId := range warned {
+		if err = s.Model.SetExpiryWarned(fileId); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// expireFiles deletes the expired files from their stores, and tells their
+// owners.
+func (s *Server) expireFiles(ctx context.Context, now time.Time) error {
+	files, err := s.Model.GetExpiredFiles(now.UnixMilli(), fileExpiryBatchSize)
+	if err != nil {
+		return err
+	}
+	expired := make(map[uint]bool)
+	for _, file := range files {
+		if ctx.Err() != nil {
+			return nil
+		}
+		if !expired[file.FileId] {
+			if err = s.expireFile(ctx, file.FileId); err != nil {
+				log.Errorf("expiring file %d: %v", file.FileId, err)
+				continue
+			}
+			expired[file.FileId] = true
+		}
+
+		err = s.Model.CreateNotification(&model.Notification{
+			EthAddr: file.EthAddr,
+			Type:    model.NotifyFileExpired,
+			FileId:  file.PreviewId,
+			Message: fmt.Sprintf("%s expired and was deleted", expiringFileName(file)),
+		})
+		if err != nil {
+			log.Error(err)
+		}
+	}
+	return nil
+}
+
+func (s *Server) expireFile(ctx context.Context, fileId uint) error {
+	file, err := s.Model.GetFileInfoById(fileId)
+	if err != nil {
+		return err
+	}
+	log.Infof("file %d expired, deleting it", fileId)
+	if err = s.StoreService.DeleteFile(ctx, file); err != nil {
+		return err
+	}
+	return s.Model.ExpireFile(fileId)
+}
+
+// renewFile keeps the file of the preview for duration more days from its
+// expiry, or forever if duration is 0.
+func (s *Server) renewFile(previewId uint, ethAddress string, duration int64) (int64, error) {
+	filePreview, err := s.Model.GetFilePreviewById(previewId)
+	if err != nil {
+		return 0, errors.New("get file failed")
+	}
+	if filePreview.EthAddr != ethAddress {
+		return 0, errors.New("invalid fileId")
+	}
+	if filePreview.FileId == 0 {
+		return 0, errors.New("file is not stored yet")
+	}
+	file, err := s.Model.GetFileInfoById(filePreview.FileId)
+	if err != nil {
+		return 0, errors.New("get file failed")
+	}
+	if file.Status == model.FileExpired {
+		return 0, errors.New("file already expired")
+	}
+
+	if file.ExpireAt < 0 {
+		// kept forever already
+		return file.ExpireAt, nil
+	}
+
+	expireAt := int64(-1)
+	if duration > 0 {
+		from := time.Now()
+		if file.ExpireAt > from.UnixMilli() {
+			from = time.UnixMilli(file.ExpireAt)
+		}
+		expireAt = from.Add(time.Duration(duration) * 24 * time.Hour).UnixMilli()
+	}
+	if err = s.Model.RenewFile(file.Id, expireAt); err != nil {
+		return 0, errors.New("database error")
+	}
+	return expireAt, nil
+}
+
+func expiringFileName(file model.ExpiringFile) string {
+	if file.Title != "" {
+		return file.Title
+	}
+	return file.Filename
+}
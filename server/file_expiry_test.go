@@ -0,0 +1,96 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sao-datastore-storage/common"
+	"sao-datastore-storage/model"
+	"testing"
+	"time"
+)
+
+func TestFileExpiry(t *testing.T) {
+	storage := common.StorageInfo{
+		Default: "a",
+		Stores:  []common.StoreInfo{{Name: "a", Type: "local", Params: map[string]string{"path": "a"}}},
+	}
+	day := 24 * time.Hour
+
+	cases := []struct {
+		name string
+		// the file expires in expireIn, or never if 0
+		expireIn time.Duration
+		warned   bool
+		previews int
+
+		warnings int
+		expired  bool
+	}{
+		{"kept forever", 0, false, 2, 0, false},
+		{"far from expiry", 30 * day, false, 1, 0, false},
+		{"about to expire", 3 * day, false, 2, 2, false},
+		{"already warned", 3 * day, true, 2, 0, false},
+		{"expired", -time.Hour, false, 2, 0, true},
+		{"expired once warned", -time.Hour, true, 1, 0, true},
+	}
+	for _, c := range cases {
+		s := newTestServer(t, storage, common.QuotaInfo{})
+		ctx := context.Background()
+
+		file, err := s.StoreService.StoreFile(ctx, bytes.NewReader([]byte("content")), "text/plain", 7, "file", -1, "0x01", "file.txt", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		expireAt := int64(-1)
+		if c.expireIn != 0 {
+			expireAt = time.Now().Add(c.expireIn).UnixMilli()
+		}
+		err = s.Model.DB.Model(file).Updates(map[string]interface{}{"expireAt": expireAt, "expiryWarned": c.warned}).Error
+		if err != nil {
+			t.Fatal(err)
+		}
+		// the file is stored once for each of its owners
+		for i := 0; i < c.previews; i++ {
+			preview := model.FilePreview{EthAddr: fmt.Sprintf("0x%02d", i+1), FileId: file.Id, Filename: "file.txt", Status: model.PlacedToIpfs}
+			if err = s.Model.CreateFilePreview(&preview); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		// the owners are warned and told once
+		for run := 0; run < 2; run++ {
+			if err = s.warnExpiringFiles(time.Now()); err != nil {
+				t.Fatalf("%s: %v", c.name, err)
+			}
+			if err = s.expireFiles(ctx, time.Now()); err != nil {
+				t.Fatalf("%s: %v", c.name, err)
+			}
+		}
+
+		var warnings, expiries int64
+		s.Model.DB.Model(&model.Notification{}).Where("type = ?", model.NotifyFileExpiring).Count(&warnings)
+		s.Model.DB.Model(&model.Notification{}).Where("type = ?", model.NotifyFileExpired).Count(&expiries)
+		if warnings != int64(c.warnings) {
+			t.Errorf("%s: %d owners warned, want %d", c.name, warnings, c.warnings)
+		}
+		wantExpiries := int64(0)
+		if c.expired {
+			wantExpiries = int64(c.previews)
+		}
+		if expiries != wantExpiries {
+			t.Errorf("%s: %d owners told the file expired, want %d", c.name, expiries, wantExpiries)
+		}
+
+		stored, err := s.Model.GetFileInfoById(file.Id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if expired := stored.Status == model.FileExpired; expired != c.expired {
+			t.Errorf("%s: file expired %v, want %v", c.name, expired, c.expired)
+		}
+		if stored.ExpiryWarned != (c.warned || c.warnings > 0) {
+			t.Errorf("%s: file warned %v", c.name, stored.ExpiryWarned)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"sao-datastore-storage/common"
+	"strings"
+)
+
+// applications of the tiers which do not set them
+const defaultApplications = 5
+
+var errQuotaExceeded = errors.New("storage quota exceeded")
+
+// Quotas tells the quota tier of each address.
+type Quotas struct {
+	tiers       map[string]common.QuotaTier
+	users       map[string]common.QuotaTier
+	defaultTier common.QuotaTier
+}
+
+func NewQuotas(info common.QuotaInfo) (Quotas, error) {
+	quotas := Quotas{
+		tiers: make(map[string]common.QuotaTier),
+		users: make(map[string]common.QuotaTier),
+	}
+	for _, tier := range info.Tiers {
+		if _, ok := quotas.tiers[tier.Name]; ok || tier.Name == "" {
+			return Quotas{}, fmt.Errorf("invalid or duplicated quota tier name %q", tier.Name)
+		}
+		if tier.Space < 0 {
+			return Quotas{}, fmt.Errorf("quota tier %s: negative space", tier.Name)
+		}
+		if tier.Applications == 0 {
+			tier.Applications = defaultApplications
+		}
+		quotas.tiers[tier.Name] = tier
+	}
+
+	quotas.defaultTier = common.QuotaTier{Applications: defaultApplications}
+	if info.DefaultTier != "" {
+		tier, ok := quotas.tiers[info.DefaultTier]
+		if !ok {
+			return Quotas{}, fmt.Errorf("no quota tier %q", info.DefaultTier)
+		}
+		quotas.defaultTier = tier
+	}
+	for ethAddr, name := range info.Users {
+		tier, ok := quotas.tiers[name]
+		if !ok {
+			return Quotas{}, fmt.Errorf("address %s: no quota tier %q", ethAddr, name)
+		}
+		quotas.users[strings.ToLower(ethAddr)] = tier
+	}
+	return quotas, nil
+}
+
+// Tier returns the quota tier of the address.
+func (q Quotas) Tier(ethAddress string) common.QuotaTier {
+	if tier, ok := q.users[strings.ToLower(ethAddress)]; ok {
+		return tier
+	}
+	return q.defaultTier
+}
+
+// remainingSpace returns the bytes the address may still store, or -1 if its
+// space is unlimited.
+func (s *Server) remainingSpace(ethAddress string) (int64, error) {
+	tier := s.Quotas.Tier(ethAddress)
+	if tier.Space == 0 {
+		return -1, nil
+	}
+	used, err := s.Model.GetSpaceUsed(ethAddress)
+	if err != nil {
+		return 0, err
+	}
+	if used >= tier.Space {
+		return 0, nil
+	}
+	return tier.Space - used, nil
+}
+
+// checkQuota tells whether the address may store size more bytes.
+func (s *Server) checkQuota(ethAddress string, size int64) error {
+	remaining, err := s.remainingSpace(ethAddress)
+	if err != nil {
+		return err
+	}
+	if remaining >= 0 && size > remaining {
+		return quotaExceeded(s.Quotas.Tier(ethAddress), remaining)
+	}
+	return nil
+}
+
+func quotaExceeded(tier common.QuotaTier, remaining int64) error {
+	return fmt.Errorf("%w: %d of the %d bytes of tier %s left", errQuotaExceeded, remaining, tier.Space, tier.Name)
+}
@@ -0,0 +1,123 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"sao-datastore-storage/common"
+	"sao-datastore-storage/model"
+	"sao-datastore-storage/store"
+	"testing"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// newTestServer returns a server on a sqlite database, storing files on the
+// local stores of storage.
+func newTestServer(t *testing.T, storage common.StorageInfo, quota common.QuotaInfo) *Server {
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "test.db")), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.AutoMigrate(&model.FilePreview{}, &model.FileInfo{}, &model.FileReplica{}, &model.McsInfo{}, &model.UploadSession{}, &model.UploadJob{}, &model.FileChunkMetadata{}, &model.Notification{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := &model.Model{DB: db}
+
+	repodir := t.TempDir()
+	storeService, err := store.NewStoreService(&common.Config{Storage: storage}, m, nil, repodir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	quotas, err := NewQuotas(quota)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &Server{
+		StoreService: storeService,
+		Model:        m,
+		Quotas:       quotas,
+		Repodir:      repodir,
+		uploadEvents: newUploadEvents(),
+		runningJobs:  make(map[uint]struct{}),
+	}
+}
+
+func TestStoreFileWithPreviewQuota(t *testing.T) {
+	storage := common.StorageInfo{
+		Default: "a",
+		Stores: []common.StoreInfo{
+			{Name: "a", Type: "local", Params: map[string]string{"path": "a"}},
+			{Name: "b", Type: "local", Params: map[string]string{"path": "b"}},
+			{Name: "c", Type: "local", Params: map[string]string{"path": "c"}},
+		},
+		Policies: []common.PolicyInfo{
+			{Name: "two", Stores: []string{"a", "b"}},
+			{Name: "three", Stores: []string{"a", "b", "c"}},
+		},
+	}
+	quota := common.QuotaInfo{
+		DefaultTier: "small",
+		Tiers:       []common.QuotaTier{{Name: "small", Space: 250}},
+		Users:       map[string]string{},
+	}
+
+	cases := []struct {
+		name        string
+		replication string
+		// bytes already stored by the address
+		stored   int64
+		rejected bool
+	}{
+		{"one replica", "", 0, false},
+		{"two replicas", "two", 0, false},
+		{"three replicas", "three", 0, true},
+		{"two replicas and stored files", "two", 100, true},
+	}
+	for _, c := range cases {
+		s := newTestServer(t, storage, quota)
+		if c.stored > 0 {
+			file := model.FileInfo{Size: c.stored, Status: model.FileStored}
+			if err := s.Model.DB.Create(&file).Error; err != nil {
+				t.Fatal(err)
+			}
+			if err := s.Model.CreateFilePreview(&model.FilePreview{EthAddr: "0x01", FileId: file.Id, Status: model.UploadSuccess}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		// the staged file of 100 bytes is counted once
+		filePreview := model.FilePreview{
+			EthAddr: "0x01",
+			Size:    100,
+			TmpPath: filepath.Join(t.TempDir(), "file"),
+			Status:  model.Uploading,
+		}
+		if err := s.Model.CreateFilePreview(&filePreview); err != nil {
+			t.Fatal(err)
+		}
+
+		preview := model.FilePreview{SaoModel: model.SaoModel{Id: filePreview.Id}, Replication: c.replication}
+		_, err := s.StoreFileWithPreview(context.Background(), preview, "0x01")
+		if rejected := errors.Is(err, errQuotaExceeded); rejected != c.rejected {
+			t.Errorf("%s: rejected %v, want %v: %v", c.name, rejected, c.rejected, err)
+			continue
+		}
+		if !c.rejected && err != nil {
+			t.Errorf("%s: %v", c.name, err)
+		}
+
+		stored, err := s.Model.GetFilePreviewById(filePreview.Id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := model.UploadSuccess
+		if c.rejected {
+			want = model.Uploading
+		}
+		if stored.Status != want {
+			t.Errorf("%s: preview status %d, want %d", c.name, stored.Status, want)
+		}
+	}
+}
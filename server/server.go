@@ -4,12 +4,11 @@ package server
 
 import (
 	"fmt"
-	"path/filepath"
-	"sao-datastore-storage/cmd"
 	"sao-datastore-storage/common"
 	"sao-datastore-storage/model"
 	"sao-datastore-storage/store"
 	"sao-datastore-storage/util"
+	"sync"
 
 	logging "github.com/ipfs/go-log/v2"
 
@@ -23,7 +22,17 @@ type Server struct {
 	StoreService store.StoreService
 	Model        *model.Model
 	Config       common.ApiServerInfo
+	FileProcess  common.FileProcessInfo
+	Quotas       Quotas
 	Repodir      string
+
+	uploadJobs    chan struct{}
+	runningJobsLk sync.Mutex
+	runningJobs   map[uint]struct{}
+	uploadEvents  *uploadEvents
+
+	uploadSessionsLk sync.Mutex
+	uploadSessions   map[string]struct{}
 }
 
 func (s *Server) ServeAPI(listen string, contextPath string, swagHandler gin.HandlerFunc) {
@@ -36,13 +45,22 @@ func (s *Server) ServeAPI(listen string, contextPath string, swagHandler gin.Han
 		hackathon.POST("/file/upload", s.UploadFile)
 		hackathon.POST("/file/addFileWithPreview", s.AddFileWithPreview)
 		hackathon.DELETE("/file/upload/:previewId", s.DeleteUploaded)
+		hackathon.GET("/file/upload/:previewId/events", s.UploadEvents)
+		hackathon.POST("/file/uploads", s.CreateUpload)
+		hackathon.HEAD("/file/uploads/:uploadId", s.UploadOffset)
+		hackathon.PATCH("/file/uploads/:uploadId", s.PatchUpload)
+		hackathon.DELETE("/file/uploads/:uploadId", s.DeleteUpload)
 		hackathon.GET("/file/order/download/:fileId", s.Download)
+		hackathon.GET("/file/order/download/:fileId/encrypted", s.DownloadEncrypted)
+		hackathon.GET("/file/order/download/:fileId/authMessage", s.DownloadAuthMessage)
 		hackathon.DELETE("/file/:fileId", s.DeleteFile)
+		hackathon.POST("/file/renew", s.RenewFile)
 		hackathon.POST("/fileStar", s.StarFile)
 		hackathon.DELETE("/fileStar", s.DeleteStarFile)
 
 		hackathon.POST("/user", s.UpdateUserProfile)
 		hackathon.GET("/user/summary", s.GetUserSummary)
+		hackathon.GET("/user/notifications", s.GetUserNotifications)
 		hackathon.POST("/user/follow/:address", s.FollowUser)
 		hackathon.DELETE("/user/follow/:address", s.UnFollowUser)
 
@@ -86,8 +104,11 @@ func (s *Server) ServeAPI(listen string, contextPath string, swagHandler gin.Han
 
 	fmt.Println(s.Config.PreviewsPath)
 	r.Static(contextPath + "/previews", s.Config.PreviewsPath)
-	procDir := filepath.Join(s.Repodir, cmd.FsStaging, "proc")
-	r.Static(contextPath + "/api/v1/proc/file", procDir)
+	// file chunks are only served with the auth token sent to the proc node,
+	// until they are sent whole
+	stagedFiles := gin.WrapH(s.StoreService.StagedFileHandler())
+	r.GET(contextPath+"/api/v1/proc/file/:name", stagedFiles)
+	r.HEAD(contextPath+"/api/v1/proc/file/:name", stagedFiles)
 
 	// swagger
 	r.GET(contextPath+ "/swagger/*any", swagHandler)
@@ -1,12 +1,15 @@
 package server
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
-	"fmt"
 	"github.com/google/uuid"
 	"github.com/shopspring/decimal"
 	"golang.org/x/xerrors"
+	"hash"
 	"io"
 	"io/ioutil"
 	"os"
@@ -14,13 +17,12 @@ import (
 	"path/filepath"
 	"sao-datastore-storage/cmd"
 	"sao-datastore-storage/model"
-	"sao-datastore-storage/proc"
 	"sao-datastore-storage/util"
-	"sao-datastore-storage/util/fileprocess"
 	"strings"
-	"time"
 )
 
+var errUploadTooLarge = errors.New("file is larger than the max upload size")
+
 var fileCategories = map[string]model.FileCategory{
 	"image/avif":      model.Image,
 	"image/gif":       model.Image,
@@ -83,12 +85,83 @@ var fileExtensionCategories = map[string]model.FileCategory{
 	".csv":  model.Document,
 }
 
-func (s *Server) uploadFile(reader io.Reader, filename string, contentType string, ethAddress string, additionalInfo string) (*model.FileInfoInMarket, error) {
+const defaultMaxUploadSize int64 = 4 << 30
+
+// only the first 512 bytes are used to sniff the content type
+const sniffLen = 512
+
+func (s *Server) maxUploadSize() int64 {
+	if s.Config.MaxUploadSize > 0 {
+		return s.Config.MaxUploadSize
+	}
+	return defaultMaxUploadSize
+}
+
+// uploadDigest hashes the bytes written to it and keeps the first of them to
+// sniff the content type.
+type uploadDigest struct {
+	hash hash.Hash
+	head []byte
+	size int64
+}
+
+func newUploadDigest() *uploadDigest {
+	return &uploadDigest{hash: sha256.New()}
+}
+
+func (d *uploadDigest) Write(p []byte) (int, error) {
+	if n := sniffLen - len(d.head); n > 0 {
+		if n > len(p) {
+			n = len(p)
+		}
+		d.head = append(d.head, p[:n]...)
+	}
+	d.size += int64(len(p))
+	return d.hash.Write(p)
+}
+
+func (d *uploadDigest) sum() string {
+	return hex.EncodeToString(d.hash.Sum(nil))
+}
+
+func (d *uploadDigest) contentType() string {
+	contentType, err := util.DetectReaderType(bytes.NewReader(d.head))
+	if err != nil {
+		log.Errorf("detect file type error: %s", err)
+	}
+	return contentType
+}
+
+// stagedUpload is a file written to the staging dir of its owner
+type stagedUpload struct {
+	Path        string
+	Size        int64
+	Sha256      string
+	ContentType string
+}
+
+func (s *Server) stagingDir(ethAddress string) (string, error) {
 	tmpPath := filepath.Join(s.Repodir, cmd.FsStaging, ethAddress)
-	var filInfo model.FileInfoInMarket
+	return tmpPath, os.MkdirAll(tmpPath, os.ModePerm)
+}
+
+// stageUpload streams reader into a new file of the staging dir of
+// ethAddress, hashing it and sniffing its content type on the way. The file
+// is removed if it is larger than the max upload size, or than what is left
+// of the storage quota of ethAddress.
+func (s *Server) stageUpload(reader io.Reader, ethAddress string) (*stagedUpload, error) {
+	maxSize := s.maxUploadSize()
+	tooLarge := errUploadTooLarge
+	remaining, err := s.remainingSpace(ethAddress)
+	if err != nil {
+		return nil, err
+	}
+	if remaining >= 0 && remaining < maxSize {
+		maxSize = remaining
+		tooLarge = quotaExceeded(s.Quotas.Tier(ethAddress), remaining)
+	}
 
-	// create dir
-	err := os.MkdirAll(tmpPath, os.ModePerm)
+	tmpPath, err := s.stagingDir(ethAddress)
 	if err != nil {
 		return nil, err
 	}
@@ -102,19 +175,35 @@ func (s *Server) uploadFile(reader io.Reader, filename string, contentType strin
 	}
 	defer tempFile.Close()
 
-	// read all of the contents of our uploaded file into a
-	// byte array
-	fileBytes, err := ioutil.ReadAll(reader)
+	digest := newUploadDigest()
+	_, err = io.Copy(io.MultiWriter(tempFile, digest), io.LimitReader(reader, maxSize+1))
+	if err == nil && digest.size > maxSize {
+		err = tooLarge
+	}
 	if err != nil {
-		log.Error(err)
+		tempFile.Close()
+		if err := os.Remove(tempFile.Name()); err != nil {
+			log.Error(err)
+		}
 		return nil, err
 	}
-	// write this byte array to our temporary file
-	tempFile.Write(fileBytes)
-	// return that we have successfully uploaded our file!
 	log.Info("Successfully staged File\n")
 
-	tempFileName := tempFile.Name()
+	return &stagedUpload{
+		Path:        tempFile.Name(),
+		Size:        digest.size,
+		Sha256:      digest.sum(),
+		ContentType: digest.contentType(),
+	}, nil
+}
+
+// createFilePreview creates the preview of a staged file, removing the file
+// if it fails.
+func (s *Server) createFilePreview(staged *stagedUpload, filename string, ethAddress string, additionalInfo string) (*model.FileInfoInMarket, error) {
+	var filInfo model.FileInfoInMarket
+
+	tempFileName := staged.Path
+	contentType := staged.ContentType
 
 	fileCategory := getFileCategory(filepath.Ext(filename))
 
@@ -126,6 +215,8 @@ func (s *Server) uploadFile(reader io.Reader, filename string, contentType strin
 		Filename:       filename,
 		FileCategory:   fileCategory,
 		AdditionalInfo: additionalInfo,
+		Sha256:         staged.Sha256,
+		Size:           staged.Size,
 	}
 
 	preview, imgFilePath, err := util.GenerateImgPreview(contentType, tempFileName)
@@ -140,6 +231,9 @@ func (s *Server) uploadFile(reader io.Reader, filename string, contentType strin
 	}
 
 	if err = s.Model.CreateFilePreview(&filePreview); err != nil {
+		if err := os.Remove(tempFileName); err != nil {
+			log.Error(err)
+		}
 		return nil, xerrors.New("database error")
 	}
 
@@ -175,15 +269,16 @@ func (s *Server) StoreFileWithPreview(ctx context.Context, preview model.FilePre
 	dir := path.Dir(filePreview.TmpPath)
 	os.MkdirAll(dir, 0666)
 
-	willEncrypt := preview.Price.Cmp(decimal.NewFromInt(0)) > 0
-
-	// TODO
-	if willEncrypt {
-		go s.processFileSplitAndEncryption(filePreview)
-	} else {
-		go s.processFreeFile(ctx, filePreview)
+	// the staged file is counted once already, the replicas are not
+	if filePreview.Status == model.Uploading {
+		replicas := s.StoreService.PolicyReplicas(preview.Replication)
+		if err = s.checkQuota(ethAddress, filePreview.Size*int64(replicas-1)); err != nil {
+			return nil, err
+		}
 	}
 
+	willEncrypt := preview.Price.Cmp(decimal.NewFromInt(0)) > 0
+
 	updateMap := map[string]interface{}{
 		"Labels":         preview.Labels,
 		"Price":          preview.Price,
@@ -192,12 +287,21 @@ func (s *Server) StoreFileWithPreview(ctx context.Context, preview model.FilePre
 		"Description":    preview.Description,
 		"Type":           preview.Type,
 		"AdditionalInfo": preview.AdditionalInfo,
+		"Replication":    preview.Replication,
+		"Duration":       preview.Duration,
 		"Status":         model.UploadSuccess,
 	}
 	if err = s.Model.UpdatePreview(preview.Id, updateMap); err != nil {
 		return nil, xerrors.New("database error")
 	}
 
+	// the file is split, encrypted and stored by the upload worker
+	if err = s.Model.SubmitUploadJob(preview.Id, willEncrypt); err != nil {
+		return nil, xerrors.New("database error")
+	}
+	s.uploadEvents.publish(UploadEvent{PreviewId: preview.Id, Type: UploadEventStaged})
+	s.notifyUploadWorker()
+
 	fileInfoInMarket := model.FileInfoInMarket{Id: filePreview.Id,
 		CreatedAt:      filePreview.CreatedAt,
 		UpdatedAt:      filePreview.UpdatedAt,
@@ -227,147 +331,6 @@ func (s *Server) StoreFileWithPreview(ctx context.Context, preview model.FilePre
 	return &fileInfoInMarket, nil
 }
 
-func (s *Server) processFileSplitAndEncryption(filePreview *model.FilePreview) {
-	file, err := os.Open(filePreview.TmpPath)
-	if err != nil {
-		log.Error(err)
-		return
-	}
-	defer file.Close()
-
-	fileStat, err := file.Stat()
-	if err != nil {
-		log.Error(err)
-		return
-	}
-	fileSize := fileStat.Size()
-
-	ctx, _ := context.WithTimeout(context.Background(), 60*time.Second)
-	// split file
-	log.Infof("start split %s ...", filePreview.TmpPath)
-	splitFileBasePath := filepath.Join(s.Repodir, cmd.FsStaging, "proc", fmt.Sprintf("%d_%s", filePreview.Id, filepath.Base(file.Name())))
-	splitFileInfos, err := fileprocess.SplitFile(file, fileSize, splitFileBasePath)
-	if err != nil {
-		log.Error(err)
-		return
-	}
-	log.Infof("complete split file into %d chunks", len(splitFileInfos))
-
-	// encrypt file chunks
-	log.Infof("start encrypting chunks...")
-	var encryptedFileChunkPaths []string
-	var chunkMetadatas []model.FileChunkMetadata
-	var encryptedOffset int64 = 0
-	for _, splitFileInfo := range splitFileInfos {
-		encryptFilePath, encryptedSize, err := s.StoreService.EncryptFileChunk(ctx, filePreview, splitFileInfo, encryptedOffset)
-		if err != nil {
-			log.Error(err)
-			return
-		}
-		log.Infof("chunk %s is encrypted into %s", splitFileInfo.FilePath, encryptFilePath)
-
-		encryptedFileChunkPaths = append(encryptedFileChunkPaths, encryptFilePath)
-		chunkMetadatas = append(chunkMetadatas, model.FileChunkMetadata{
-			FileId:          filePreview.Id,
-			Offset:          splitFileInfo.Offset,
-			Size:            splitFileInfo.Size,
-			EncryptedOffset: encryptedOffset,
-			EncryptedSize:   int64(encryptedSize),
-		})
-		encryptedOffset += int64(encryptedSize)
-	}
-	log.Infof("complete encrypting chunks")
-
-	// combine encrypted file chunks
-	combinedEncryptedPath := filePreview.TmpPath + proc.ENCRYPT_SUFFIX
-	log.Infof("start combining ecnrypted chunks into %s", combinedEncryptedPath)
-	if err = fileprocess.CombineFile(encryptedFileChunkPaths, combinedEncryptedPath); err != nil {
-		log.Error(err)
-		return
-	}
-	log.Infow("complete combining.")
-
-	//  start storing combined encrypted file.
-	storeFile, err := os.Open(combinedEncryptedPath)
-	if err != nil {
-		log.Error(err)
-		return
-	}
-	encryptFileStat, err := storeFile.Stat()
-	if err != nil {
-		log.Error(err)
-		return
-	}
-
-	log.Infof("uploading to ipfs/filecoin...")
-	duration := int64(-1)
-	dsFile, err := s.StoreService.StoreFile(ctx, storeFile, filePreview.ContentType, encryptFileStat.Size(), file.Name(), duration, filePreview.EthAddr, filePreview.Filename)
-	if err != nil {
-		log.Error(err)
-		return
-	}
-
-	err = s.Model.StoreFileMetadata(chunkMetadatas, dsFile.Id, int64(filePreview.Id))
-	if err != nil {
-		log.Error(err)
-		return
-	}
-
-	defer func() {
-		err = os.Remove(filePreview.TmpPath)
-		if err != nil {
-			log.Error(err)
-		}
-		err = os.Remove(encryptFileStat.Name())
-		if err != nil {
-			log.Error(err)
-		}
-	}()
-
-	return
-}
-
-func (s *Server) processFreeFile(ctx context.Context, filePreview *model.FilePreview) {
-	file, err := os.Open(filePreview.TmpPath)
-	if err != nil {
-		log.Error(err)
-		return
-	}
-	defer file.Close()
-
-	fileStat, err := file.Stat()
-	if err != nil {
-		log.Error(err)
-		return
-	}
-	fileSize := fileStat.Size()
-
-	log.Infof("uploading to ipfs/filecoin...")
-	duration := int64(-1)
-	dsFile, err := s.StoreService.StoreFile(ctx, file, filePreview.ContentType, fileSize, file.Name(), duration, filePreview.EthAddr, filePreview.Filename)
-	if err != nil {
-		log.Error(err)
-		return
-	}
-
-	updateMap := map[string]interface{}{
-		"Status": model.PlacedToIpfs,
-		"FileId": dsFile.Id,
-	}
-	err = s.Model.UpdatePreviewLinkedWithIpfs(filePreview.Id, updateMap)
-	if err != nil {
-		log.Error(err)
-		return
-	}
-
-	defer func() {
-		err = os.Remove(filePreview.TmpPath)
-		if err != nil {
-			log.Error(err)
-		}
-	}()
-}
-
 func (s *Server) deleteUploaded(previewId uint, ethAddress string) error {
 	filePreview, err := s.Model.GetFilePreviewById(previewId)
 	if err != nil {
@@ -402,13 +365,13 @@ func (s *Server) deleteFile(ctx context.Context, fileId uint, ethAddress string)
 		return errors.New("invalid fileId")
 	}
 
-	ipfsHash, err := s.Model.DeleteFile(filePreview)
+	file, err := s.Model.DeleteFile(filePreview)
 	if err != nil {
 		log.Error(err)
 		return errors.New("delete file failed")
 	}
 
-	err = s.StoreService.DeleteFile(ctx, ipfsHash)
+	err = s.StoreService.DeleteFile(ctx, file)
 	if err != nil {
 		log.Error(err)
 	}
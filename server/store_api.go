@@ -3,23 +3,35 @@ package server
 import (
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"image/gif"
 	"image/jpeg"
 	"image/png"
 	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
 	"os"
 	"sao-datastore-storage/model"
 	"sao-datastore-storage/util"
 	"sao-datastore-storage/util/api"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/fogleman/gg"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 )
 
+// max size of the form fields sent along with an uploaded file
+const maxUploadFieldSize = 1 << 20
+
+// UploadFile stages the multipart "file" as it is received, without holding
+// it in memory. The "Filename" and "AdditionalInfo" fields may come before or
+// after the file.
 func (s *Server) UploadFile(ctx *gin.Context) {
 	ethAddress, _ := ctx.Get("User")
 	if ethAddress.(string) == "" {
@@ -27,44 +39,202 @@ func (s *Server) UploadFile(ctx *gin.Context) {
 		return
 	}
 
-	file, err := ctx.FormFile("file")
+	ctx.Request.Body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, s.maxUploadSize()+4*maxUploadFieldSize)
+	reader, err := ctx.Request.MultipartReader()
 	if err != nil {
 		api.BadRequest(ctx, "invalid.param.file", err.Error())
 		return
 	}
 
-	f, err := file.Open()
+	var staged *stagedUpload
+	var partFilename, filename, additionalInfo string
+	removeStaged := func() {
+		if staged != nil {
+			if err := os.Remove(staged.Path); err != nil {
+				log.Error(err)
+			}
+		}
+	}
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			removeStaged()
+			api.BadRequest(ctx, "invalid.param.file", err.Error())
+			return
+		}
+
+		switch part.FormName() {
+		case "file":
+			if staged != nil {
+				removeStaged()
+				api.BadRequest(ctx, "invalid.param.file", "more than one file")
+				return
+			}
+			partFilename = part.FileName()
+			log.Infof("%s: staging file", partFilename)
+			staged, err = s.stageUpload(part, ethAddress.(string))
+			if errors.Is(err, errUploadTooLarge) {
+				api.TooLarge(ctx, "invalid.param.file", err.Error())
+				return
+			}
+			if errors.Is(err, errQuotaExceeded) {
+				api.TooLarge(ctx, "quota.exceeded", err.Error())
+				return
+			}
+		case "Filename":
+			filename, err = readUploadField(part)
+		case "AdditionalInfo":
+			additionalInfo, err = readUploadField(part)
+		}
+		if err != nil {
+			removeStaged()
+			api.BadRequest(ctx, "invalid.param.file", err.Error())
+			return
+		}
+	}
+	if staged == nil {
+		api.BadRequest(ctx, "invalid.param.file", "missing file")
+		return
+	}
+
+	if filename == "" {
+		filename = partFilename
+	}
+
+	fi, err := s.createFilePreview(staged, filename, ethAddress.(string), additionalInfo)
 	if err != nil {
-		api.BadRequest(ctx, "invalid.param.file", err.Error())
+		api.ServerError(ctx, "uploadfile.error", err.Error())
 		return
 	}
-	defer f.Close()
+	api.Success(ctx, fi)
+}
 
-	contentType := ctx.ContentType()
-	contentType, err = util.DetectReaderType(f)
+func readUploadField(part *multipart.Part) (string, error) {
+	value, err := ioutil.ReadAll(io.LimitReader(part, maxUploadFieldSize+1))
 	if err != nil {
-		log.Errorf("detect file type error: %s", err)
+		return "", err
+	}
+	if len(value) > maxUploadFieldSize {
+		return "", fmt.Errorf("%s is too long", part.FormName())
 	}
+	return string(value), nil
+}
 
-	f.Seek(0, 0)
+// CreateUpload starts a resumable upload of a file of Size bytes, sent by
+// PatchUpload.
+func (s *Server) CreateUpload(ctx *gin.Context) {
+	ethAddress, _ := ctx.Get("User")
+	if ethAddress.(string) == "" {
+		api.Unauthorized(ctx, "invalid.signature", "invalid signature")
+		return
+	}
 
-	filename := ctx.PostForm("Filename")
-	if filename == "" {
-		filename = file.Filename
+	var req UploadSessionReq
+	if err := json.NewDecoder(ctx.Request.Body).Decode(&req); err != nil {
+		api.BadRequest(ctx, "invalid.param", err.Error())
+		return
+	}
+	if req.Filename == "" || req.Size < 0 {
+		api.BadRequest(ctx, "invalid.param", "invalid filename or size")
+		return
+	}
+	if req.Size > s.maxUploadSize() {
+		api.TooLarge(ctx, "invalid.param.size", errUploadTooLarge.Error())
+		return
 	}
 
-	additionalInfo := ctx.PostForm("AdditionalInfo")
+	session, err := s.createUploadSession(req, ethAddress.(string))
+	if errors.Is(err, errQuotaExceeded) {
+		api.TooLarge(ctx, "quota.exceeded", err.Error())
+		return
+	}
+	if err != nil {
+		api.ServerError(ctx, "createUpload.error", err.Error())
+		return
+	}
+	api.Success(ctx, session)
+}
 
-	log.Infof("%s: staging file", filename)
+// UploadOffset returns the Upload-Offset to resume a resumable upload from.
+func (s *Server) UploadOffset(ctx *gin.Context) {
+	ethAddress, _ := ctx.Get("User")
+	if ethAddress.(string) == "" {
+		ctx.Status(http.StatusUnauthorized)
+		return
+	}
 
-	fi, err := s.uploadFile(f, filename, contentType, ethAddress.(string), additionalInfo)
+	session, err := s.getUploadSession(ctx.Param("uploadId"), ethAddress.(string))
 	if err != nil {
-		api.ServerError(ctx, "uploadfile.error", err.Error())
+		ctx.Status(http.StatusNotFound)
+		return
+	}
+	ctx.Header("Upload-Offset", strconv.FormatInt(session.Offset, 10))
+	ctx.Header("Upload-Length", strconv.FormatInt(session.Size, 10))
+	ctx.Header("Cache-Control", "no-store")
+	ctx.Status(http.StatusOK)
+}
+
+// PatchUpload appends the body to a resumable upload at the Upload-Offset
+// header, which must be the current offset of the upload. Once all the bytes
+// are received, the file preview is created and returned like UploadFile.
+func (s *Server) PatchUpload(ctx *gin.Context) {
+	ethAddress, _ := ctx.Get("User")
+	if ethAddress.(string) == "" {
+		api.Unauthorized(ctx, "invalid.signature", "invalid signature")
+		return
+	}
+
+	offset, err := strconv.ParseInt(ctx.GetHeader("Upload-Offset"), 10, 64)
+	if err != nil {
+		api.BadRequest(ctx, "invalid.param.offset", err.Error())
+		return
+	}
+
+	session, fi, err := s.appendUpload(ctx.Param("uploadId"), ethAddress.(string), offset, ctx.Request.Body)
+	if session != nil {
+		ctx.Header("Upload-Offset", strconv.FormatInt(session.Offset, 10))
+	}
+	if errors.Is(err, errUploadOffset) || errors.Is(err, errUploadBusy) {
+		api.Conflict(ctx, "patchUpload.conflict", err.Error())
+		return
+	}
+	if errors.Is(err, errUploadTooLarge) {
+		api.TooLarge(ctx, "patchUpload.error", err.Error())
+		return
+	}
+	if errors.Is(err, errUploadNotFound) {
+		api.BadRequest(ctx, "invalid.param.uploadId", err.Error())
+		return
+	}
+	if err != nil {
+		api.ServerError(ctx, "patchUpload.error", err.Error())
+		return
+	}
+	if fi == nil {
+		api.Success(ctx, session)
 		return
 	}
 	api.Success(ctx, fi)
 }
 
+// DeleteUpload cancels a resumable upload.
+func (s *Server) DeleteUpload(ctx *gin.Context) {
+	ethAddress, _ := ctx.Get("User")
+	if ethAddress.(string) == "" {
+		api.Unauthorized(ctx, "invalid.signature", "invalid signature")
+		return
+	}
+
+	if err := s.deleteUploadSession(ctx.Param("uploadId"), ethAddress.(string)); err != nil {
+		api.ServerError(ctx, "deleteUpload.error", err.Error())
+		return
+	}
+	api.Success(ctx, nil)
+}
+
 func (s *Server) AddFileWithPreview(ctx *gin.Context) {
 	ethAddress, _ := ctx.Get("User")
 	if ethAddress.(string) == "" {
@@ -84,6 +254,14 @@ func (s *Server) AddFileWithPreview(ctx *gin.Context) {
 		api.BadRequest(ctx, "invalid.param", "id must be specified")
 		return
 	}
+	if filePreview.Duration < 0 {
+		api.BadRequest(ctx, "invalid.param.duration", "duration must not be negative")
+		return
+	}
+	if !s.StoreService.HasPolicy(filePreview.Replication) {
+		api.BadRequest(ctx, "invalid.param.replication", "unknown replication policy")
+		return
+	}
 
 	var imageType string
 	idx := strings.Index(filePreview.Preview, ";base64,")
@@ -131,6 +309,10 @@ func (s *Server) AddFileWithPreview(ctx *gin.Context) {
 	}
 
 	fi, err := s.StoreFileWithPreview(ctx.Request.Context(), filePreview, ethAddress.(string))
+	if errors.Is(err, errQuotaExceeded) {
+		api.TooLarge(ctx, "quota.exceeded", err.Error())
+		return
+	}
 	if err != nil {
 		api.ServerError(ctx, "addFileWithPreview.error", err.Error())
 		return
@@ -159,6 +341,55 @@ func (s *Server) DeleteUploaded(ctx *gin.Context) {
 	api.Success(ctx, nil)
 }
 
+// UploadEvents streams the upload pipeline events of a preview as
+// server-sent events, until the upload is done or failed.
+func (s *Server) UploadEvents(ctx *gin.Context) {
+	ethAddress, _ := ctx.Get("User")
+	if ethAddress.(string) == "" {
+		api.Unauthorized(ctx, "invalid.signature", "invalid signature")
+		return
+	}
+
+	previewId, err := strconv.ParseUint(ctx.Param("previewId"), 10, 0)
+	if err != nil {
+		api.BadRequest(ctx, "invalid.param", "")
+		return
+	}
+
+	// subscribe before reading the status so that no event is missed
+	events := s.uploadEvents.subscribe(uint(previewId))
+	defer s.uploadEvents.unsubscribe(uint(previewId), events)
+
+	filePreview, err := s.Model.GetFilePreviewById(uint(previewId))
+	if err != nil || filePreview.EthAddr != ethAddress.(string) {
+		api.BadRequest(ctx, "invalid.param", "invalid previewId")
+		return
+	}
+
+	switch filePreview.Status {
+	case model.PlacedToIpfs:
+		ctx.SSEvent(string(UploadEventDone), UploadEvent{PreviewId: filePreview.Id, Type: UploadEventDone, Time: filePreview.UpdatedAt})
+		return
+	case model.UploadFailed:
+		evt := UploadEvent{PreviewId: filePreview.Id, Type: UploadEventFailed, Time: filePreview.UpdatedAt}
+		if job, err := s.Model.GetUploadJobByPreviewId(filePreview.Id); err == nil {
+			evt.Error = job.Error
+		}
+		ctx.SSEvent(string(UploadEventFailed), evt)
+		return
+	}
+
+	ctx.Stream(func(w io.Writer) bool {
+		select {
+		case evt := <-events:
+			ctx.SSEvent(string(evt.Type), evt)
+			return !evt.final()
+		case <-ctx.Request.Context().Done():
+			return false
+		}
+	})
+}
+
 func (s *Server) DeleteFile(ctx *gin.Context) {
 	ethAddress, _ := ctx.Get("User")
 	if ethAddress.(string) == "" {
@@ -179,6 +410,38 @@ func (s *Server) DeleteFile(ctx *gin.Context) {
 	api.Success(ctx, nil)
 }
 
+type RenewFileReq struct {
+	FileId uint
+	// days to keep the file for, forever if 0
+	Duration int64
+}
+
+// RenewFile extends the expiry of a file of the user.
+func (s *Server) RenewFile(ctx *gin.Context) {
+	ethAddress, _ := ctx.Get("User")
+	if ethAddress.(string) == "" {
+		api.Unauthorized(ctx, "invalid.signature", "invalid signature")
+		return
+	}
+
+	var req RenewFileReq
+	if err := json.NewDecoder(ctx.Request.Body).Decode(&req); err != nil {
+		api.BadRequest(ctx, "invalid.param", err.Error())
+		return
+	}
+	if req.Duration < 0 {
+		api.BadRequest(ctx, "invalid.param.duration", "duration must not be negative")
+		return
+	}
+
+	expireAt, err := s.renewFile(req.FileId, ethAddress.(string), req.Duration)
+	if err != nil {
+		api.ServerError(ctx, "renewFile.error", err.Error())
+		return
+	}
+	api.Success(ctx, map[string]int64{"ExpireAt": expireAt})
+}
+
 func (s *Server) FileInfo(ctx *gin.Context) {
 	ethAddress := ctx.GetHeader("address")
 	util.VerifySignature(ctx)
@@ -283,7 +546,32 @@ func (s *Server) FileInfos(ctx *gin.Context) {
 	api.Success(ctx, fi)
 }
 
-// TODO download optimazation
+// DownloadAuthMessage returns the message the buyer signs, and sends in the
+// signature headers of Download and DownloadEncrypted of a paid file.
+func (s *Server) DownloadAuthMessage(ctx *gin.Context) {
+	ethAddress, _ := ctx.Get("User")
+	if ethAddress.(string) == "" {
+		api.Unauthorized(ctx, "invalid.signature", "invalid signature")
+		return
+	}
+
+	fileIdParam := ctx.Param("fileId")
+	fileId, err := strconv.ParseUint(fileIdParam, 10, 0)
+	if err != nil {
+		api.BadRequest(ctx, "invalid.param.fileId", err.Error())
+		return
+	}
+
+	message, err := s.StoreService.DownloadAuthMessage(uint(fileId))
+	if err != nil {
+		api.ServerError(ctx, "authmessage.error", err.Error())
+		return
+	}
+	api.Success(ctx, message)
+}
+
+// Download streams the original file, paid files are decrypted on the way.
+// Range requests only fetch and decrypt the chunks covering the range.
 func (s *Server) Download(ctx *gin.Context) {
 	ethAddress, _ := ctx.Get("User")
 	if ethAddress.(string) == "" {
@@ -304,25 +592,129 @@ func (s *Server) Download(ctx *gin.Context) {
 		return
 	}
 
-	fileInfo, reader, err := s.StoreService.GetFile(ctx, uint(fileId), ethAddress.(string))
+	file, err := s.StoreService.GetFile(ctx, uint(fileId), ethAddress.(string), ctx.GetHeader("signature"), ctx.GetHeader("signatureMessage"))
 	if err != nil {
 		api.ServerError(ctx, "getfile.error", err.Error())
 		return
 	}
+	reader := file.NewReader(ctx)
 	defer reader.Close()
 
+	// open the file at the requested range before writing the response, so
+	// that a failed decryption is still reported as an error
+	if ctx.GetHeader("If-None-Match") != file.ETag {
+		if _, err = reader.Seek(rangeStart(ctx.GetHeader("Range"), file.Size), io.SeekStart); err == nil {
+			err = reader.Open()
+		}
+		if err != nil {
+			api.ServerError(ctx, "getfile.error", err.Error())
+			return
+		}
+	}
+
 	contentType := "application/octet-stream"
-	if fileInfo.ContentType != "" {
-		contentType = fileInfo.ContentType
+	if file.Info.ContentType != "" {
+		contentType = file.Info.ContentType
 	}
 	ctx.Writer.Header().Add("Content-type", contentType)
+	ctx.Writer.Header().Add("access-control-expose-headers", "Content-Disposition, Content-Range, Accept-Ranges, ETag")
+	ctx.Writer.Header().Add("Content-Disposition", "attachment;filename="+file.Info.Filename)
+	ctx.Writer.Header().Set("ETag", file.ETag)
+	// serves the Range, If-Range and If-None-Match requests
+	http.ServeContent(ctx.Writer, ctx.Request, file.Info.Filename, time.Time{}, reader)
+}
+
+// rangeStart returns the offset of the first range of a Range header, 0 if
+// there is none.
+func rangeStart(header string, size int64) int64 {
+	if !strings.HasPrefix(header, "bytes=") {
+		return 0
+	}
+	spec := strings.TrimSpace(strings.Split(strings.TrimPrefix(header, "bytes="), ",")[0])
+	i := strings.Index(spec, "-")
+	if i < 0 {
+		return 0
+	}
+	if i == 0 {
+		// the last bytes of the file
+		n, err := strconv.ParseInt(spec[1:], 10, 64)
+		if err != nil || n >= size {
+			return 0
+		}
+		return size - n
+	}
+	start, err := strconv.ParseInt(spec[:i], 10, 64)
+	if err != nil || start < 0 {
+		return 0
+	}
+	return start
+}
+
+// DownloadEncrypted streams a paid file as stored, in a multipart/mixed body.
+// The first part is the JSON list of chunks with their file keys wrapped for
+// the buyer's public key, the second part is the encrypted file. The client
+// unwraps each key with its private key and decrypts the chunks itself.
+func (s *Server) DownloadEncrypted(ctx *gin.Context) {
+	ethAddress, _ := ctx.Get("User")
+	if ethAddress.(string) == "" {
+		api.Unauthorized(ctx, "invalid.signature", "invalid signature")
+		return
+	}
+
+	fileIdParam := ctx.Param("fileId")
+	fileId, err := strconv.ParseUint(fileIdParam, 10, 0)
+	if err != nil {
+		api.BadRequest(ctx, "invalid.param.fileId", err.Error())
+		return
+	}
+
+	err = s.checkFileStatus(uint(fileId), ethAddress.(string))
+	if err != nil {
+		api.ServerError(ctx, "download.error", err.Error())
+		return
+	}
+
+	fileInfo, chunkKeys, reader, err := s.StoreService.GetEncryptedFile(ctx, uint(fileId), ethAddress.(string), ctx.GetHeader("signature"), ctx.GetHeader("signatureMessage"))
+	if err != nil {
+		api.ServerError(ctx, "getfile.error", err.Error())
+		return
+	}
+	defer reader.Close()
+
+	mw := multipart.NewWriter(ctx.Writer)
+	ctx.Writer.Header().Add("Content-type", "multipart/mixed; boundary="+mw.Boundary())
 	ctx.Writer.Header().Add("access-control-expose-headers", "Content-Disposition")
 	ctx.Writer.Header().Add("Content-Disposition", "attachment;filename="+fileInfo.Filename)
-	_, err = io.Copy(ctx.Writer, reader)
+
+	keysPart, err := mw.CreatePart(textproto.MIMEHeader{"Content-Type": {"application/json"}})
 	if err != nil {
 		api.ServerError(ctx, "getfile.error", err.Error())
 		return
 	}
+	if err = json.NewEncoder(keysPart).Encode(chunkKeys); err != nil {
+		log.Error(err)
+		return
+	}
+
+	contentType := "application/octet-stream"
+	if fileInfo.ContentType != "" {
+		contentType = fileInfo.ContentType
+	}
+	filePart, err := mw.CreatePart(textproto.MIMEHeader{
+		"Content-Type":            {"application/octet-stream"},
+		"X-Original-Content-Type": {contentType},
+	})
+	if err != nil {
+		log.Error(err)
+		return
+	}
+	if _, err = io.Copy(filePart, reader); err != nil {
+		log.Error(err)
+		return
+	}
+	if err = mw.Close(); err != nil {
+		log.Error(err)
+	}
 }
 
 
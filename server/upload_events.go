@@ -0,0 +1,141 @@
+package server
+
+import (
+	"io"
+	"sync"
+	"time"
+)
+
+type UploadEventType string
+
+const (
+	// the upload is submitted to the upload worker
+	UploadEventStaged UploadEventType = "staged"
+	// the file is split into Chunks chunks
+	UploadEventSplit UploadEventType = "split"
+	// Bytes of the encrypted chunk Chunk are received from its proc node
+	UploadEventChunkTransfer UploadEventType = "chunkTransfer"
+	// the chunk Chunk is encrypted
+	UploadEventChunkEncrypted UploadEventType = "chunkEncrypted"
+	// Bytes of the file are sent to ipfs/mcs
+	UploadEventStoring UploadEventType = "storing"
+	// a step failed and is retried at RetryAt
+	UploadEventRetrying UploadEventType = "retrying"
+	// the file is placed to ipfs, the preview status is PlacedToIpfs
+	UploadEventDone UploadEventType = "done"
+	// the upload gave up, the preview status is UploadFailed
+	UploadEventFailed UploadEventType = "failed"
+)
+
+// UploadEvent reports the progress of a preview in the upload pipeline.
+type UploadEvent struct {
+	PreviewId uint
+	Type      UploadEventType
+	Chunk     int
+	Chunks    int        `json:",omitempty"`
+	Bytes     int64      `json:",omitempty"`
+	Total     int64      `json:",omitempty"`
+	Error     string     `json:",omitempty"`
+	RetryAt   *time.Time `json:",omitempty"`
+	Time      time.Time
+}
+
+func (e UploadEvent) final() bool {
+	return e.Type == UploadEventDone || e.Type == UploadEventFailed
+}
+
+const uploadEventBuffer = 64
+
+// uploadEvents fans the upload events out to the subscribers of each preview.
+type uploadEvents struct {
+	lk   sync.Mutex
+	subs map[uint]map[chan UploadEvent]struct{}
+	// last event of the uploads in progress, sent first to new subscribers
+	last map[uint]UploadEvent
+}
+
+func newUploadEvents() *uploadEvents {
+	return &uploadEvents{
+		subs: make(map[uint]map[chan UploadEvent]struct{}),
+		last: make(map[uint]UploadEvent),
+	}
+}
+
+func (u *uploadEvents) publish(evt UploadEvent) {
+	evt.Time = time.Now()
+
+	u.lk.Lock()
+	defer u.lk.Unlock()
+
+	if evt.final() {
+		delete(u.last, evt.PreviewId)
+	} else {
+		u.last[evt.PreviewId] = evt
+	}
+	for ch := range u.subs[evt.PreviewId] {
+		select {
+		case ch <- evt:
+		default:
+			// slow subscriber, drop progress events but never the final one
+			if evt.final() {
+				select {
+				case <-ch:
+				default:
+				}
+				ch <- evt
+			}
+		}
+	}
+}
+
+// subscribe returns the channel of the events of previewId, starting with
+// the last one published if the upload is in progress.
+func (u *uploadEvents) subscribe(previewId uint) chan UploadEvent {
+	ch := make(chan UploadEvent, uploadEventBuffer)
+
+	u.lk.Lock()
+	defer u.lk.Unlock()
+
+	if u.subs[previewId] == nil {
+		u.subs[previewId] = make(map[chan UploadEvent]struct{})
+	}
+	u.subs[previewId][ch] = struct{}{}
+	if evt, ok := u.last[previewId]; ok {
+		ch <- evt
+	}
+	return ch
+}
+
+func (u *uploadEvents) unsubscribe(previewId uint, ch chan UploadEvent) {
+	u.lk.Lock()
+	defer u.lk.Unlock()
+
+	delete(u.subs[previewId], ch)
+	if len(u.subs[previewId]) == 0 {
+		delete(u.subs, previewId)
+	}
+}
+
+// progressReader publishes the storing progress of a file as it is read.
+type progressReader struct {
+	io.Reader
+	events    *uploadEvents
+	previewId uint
+	total     int64
+	read      int64
+	lastPct   int64
+}
+
+func (r *progressReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	r.read += int64(n)
+	pct := int64(100)
+	if r.total > 0 {
+		pct = 100 * r.read / r.total
+	}
+	if pct != r.lastPct {
+		r.lastPct = pct
+		r.events.publish(UploadEvent{PreviewId: r.previewId, Type: UploadEventStoring, Bytes: r.read, Total: r.total})
+	}
+	return n, err
+}
@@ -0,0 +1,67 @@
+package server
+
+import "testing"
+
+func TestUploadEvents(t *testing.T) {
+	events := func(previewId uint, types ...UploadEventType) []UploadEvent {
+		var evts []UploadEvent
+		for _, typ := range types {
+			evts = append(evts, UploadEvent{PreviewId: previewId, Type: typ})
+		}
+		return evts
+	}
+	storing := make([]UploadEventType, uploadEventBuffer+10)
+	for i := range storing {
+		storing[i] = UploadEventStoring
+	}
+
+	cases := []struct {
+		name string
+		// published before and after preview 1 is subscribed to
+		before []UploadEvent
+		after  []UploadEvent
+		// number of events received and the type of the last one
+		received int
+		last     UploadEventType
+	}{
+		{"from the start", nil, events(1, UploadEventSplit, UploadEventChunkEncrypted, UploadEventDone), 3, UploadEventDone},
+		{"in progress", events(1, UploadEventSplit, UploadEventChunkEncrypted), events(1, UploadEventDone), 2, UploadEventDone},
+		{"retrying", events(1, UploadEventStoring, UploadEventRetrying), nil, 1, UploadEventRetrying},
+		{"done", events(1, UploadEventSplit, UploadEventDone), nil, 0, ""},
+		{"failed", events(1, UploadEventRetrying, UploadEventFailed), nil, 0, ""},
+		{"other preview", events(2, UploadEventSplit), events(2, UploadEventDone), 0, ""},
+		{"slow subscriber", nil, append(events(1, storing...), events(1, UploadEventDone)...), uploadEventBuffer, UploadEventDone},
+	}
+	for _, c := range cases {
+		u := newUploadEvents()
+		for _, evt := range c.before {
+			u.publish(evt)
+		}
+		ch := u.subscribe(1)
+		for _, evt := range c.after {
+			u.publish(evt)
+		}
+		u.unsubscribe(1, ch)
+
+		var received []UploadEvent
+	drain:
+		for {
+			select {
+			case evt := <-ch:
+				received = append(received, evt)
+			default:
+				break drain
+			}
+		}
+		if len(received) != c.received {
+			t.Errorf("%s: received %d events, want %d", c.name, len(received), c.received)
+			continue
+		}
+		if len(received) > 0 && received[len(received)-1].Type != c.last {
+			t.Errorf("%s: last event %q, want %q", c.name, received[len(received)-1].Type, c.last)
+		}
+		if len(u.subs) != 0 {
+			t.Errorf("%s: subscribers left after unsubscribing", c.name)
+		}
+	}
+}
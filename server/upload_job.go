@@ -0,0 +1,392 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sao-datastore-storage/cmd"
+	"sao-datastore-storage/model"
+	"sao-datastore-storage/proc"
+	"sao-datastore-storage/util/fileprocess"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jpillora/backoff"
+	"github.com/libp2p/go-libp2p-core/peer"
+	"golang.org/x/sync/errgroup"
+	"golang.org/x/xerrors"
+	"gorm.io/gorm"
+)
+
+const uploadJobPollInterval = 10 * time.Second
+const defaultMaxUploadAttempts = 5
+
+var uploadJobBackoff = backoff.Backoff{
+	Min:    10 * time.Second,
+	Max:    10 * time.Minute,
+	Factor: 2,
+}
+
+// StartUploadWorker resumes the upload jobs left by a previous run, and runs
+// the ones submitted by StoreFileWithPreview until ctx is done.
+func (s *Server) StartUploadWorker(ctx context.Context) {
+	s.uploadJobs = make(chan struct{}, 1)
+	s.uploadEvents = newUploadEvents()
+	s.runningJobs = make(map[uint]struct{})
+	go s.runUploadJobs(ctx)
+}
+
+// notifyUploadWorker wakes the upload worker up to run a new job
+func (s *Server) notifyUploadWorker() {
+	select {
+	case s.uploadJobs <- struct{}{}:
+	default:
+	}
+}
+
+func (s *Server) runUploadJobs(ctx context.Context) {
+	for {
+		jobs, err := s.Model.GetPendingUploadJobs(time.Now())
+		if err != nil {
+			log.Error(err)
+		}
+		for _, job := range jobs {
+			if !s.startUploadJob(job.Id) {
+				continue
+			}
+			go func(job model.UploadJob) {
+				defer s.endUploadJob(job.Id)
+				s.runUploadJob(ctx, &job)
+			}(job)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.uploadJobs:
+		case <-time.After(uploadJobPollInterval):
+		}
+	}
+}
+
+func (s *Server) startUploadJob(id uint) bool {
+	s.runningJobsLk.Lock()
+	defer s.runningJobsLk.Unlock()
+
+	if _, ok := s.runningJobs[id]; ok {
+		return false
+	}
+	s.runningJobs[id] = struct{}{}
+	return true
+}
+
+func (s *Server) endUploadJob(id uint) {
+	s.runningJobsLk.Lock()
+	defer s.runningJobsLk.Unlock()
+
+	delete(s.runningJobs, id)
+}
+
+// runUploadJob runs the job up to Linked, on error it schedules a retry with
+// backoff or marks the job as failed.
+func (s *Server) runUploadJob(ctx context.Context, job *model.UploadJob) {
+	err := s.advanceUploadJob(ctx, job)
+	if err == nil {
+		log.Infof("file preview %d is uploaded as file %d", job.PreviewId, job.FileId)
+		s.uploadEvents.publish(UploadEvent{PreviewId: job.PreviewId, Type: UploadEventDone})
+		return
+	}
+	if ctx.Err() != nil {
+		// shutting down, resumed on next start
+		return
+	}
+
+	job.Attempts++
+	job.Error = err.Error()
+	maxAttempts := s.FileProcess.MaxUploadAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = defaultMaxUploadAttempts
+	}
+	if job.Attempts >= maxAttempts {
+		log.Errorw("upload failed", "preview", job.PreviewId, "state", job.State, "attempts", job.Attempts, "err", err)
+		if err = s.Model.FailUploadJob(job); err != nil {
+			log.Error(err)
+		}
+		s.uploadEvents.publish(UploadEvent{PreviewId: job.PreviewId, Type: UploadEventFailed, Error: job.Error})
+		return
+	}
+
+	delay := uploadJobBackoff.ForAttempt(float64(job.Attempts - 1))
+	log.Warnw("upload failed, retrying", "preview", job.PreviewId, "state", job.State, "attempt", job.Attempts, "delay", delay, "err", err)
+	job.NextAttemptAt = time.Now().Add(delay)
+	if err = s.Model.SaveUploadJob(job); err != nil {
+		log.Error(err)
+	}
+	s.uploadEvents.publish(UploadEvent{PreviewId: job.PreviewId, Type: UploadEventRetrying, Error: job.Error, RetryAt: &job.NextAttemptAt})
+}
+
+func (s *Server) advanceUploadJob(ctx context.Context, job *model.UploadJob) error {
+	filePreview, err := s.Model.GetFilePreviewById(job.PreviewId)
+	if err != nil {
+		return err
+	}
+
+	for job.State != model.UploadJobLinked {
+		log.Infow("processing upload", "preview", job.PreviewId, "state", job.State)
+		switch job.State {
+		case model.UploadJobCreated:
+			if job.Encrypt {
+				err = s.splitUploadFile(job, filePreview)
+			} else {
+				err = s.storeUploadFile(ctx, job, filePreview, filePreview.TmpPath)
+			}
+		case model.UploadJobSplit, model.UploadJobEncrypting:
+			err = s.encryptUploadFile(ctx, job, filePreview)
+		case model.UploadJobCombined:
+			err = s.storeUploadFile(ctx, job, filePreview, filePreview.TmpPath+proc.ENCRYPT_SUFFIX)
+		case model.UploadJobUploaded:
+			err = s.linkUploadFile(job, filePreview)
+		default:
+			err = fmt.Errorf("unknown upload state %s", job.State)
+		}
+		if err != nil {
+			return xerrors.Errorf("%s: %w", job.State, err)
+		}
+	}
+	return nil
+}
+
+func (s *Server) splitFileBasePath(filePreview *model.FilePreview) string {
+	return filepath.Join(s.Repodir, cmd.FsStaging, "proc", fmt.Sprintf("%d_%s", filePreview.Id, filepath.Base(filePreview.TmpPath)))
+}
+
+// splitUploadFile splits a paid file into the chunks encrypted by the proc
+// nodes.
+func (s *Server) splitUploadFile(job *model.UploadJob, filePreview *model.FilePreview) error {
+	file, err := os.Open(filePreview.TmpPath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	fileStat, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	fileSize := fileStat.Size()
+
+	log.Infof("start split %s ...", filePreview.TmpPath)
+	partsNum := job.ChunkCount
+	if partsNum <= 0 {
+		partsNum = fileprocess.PartsNum(fileSize, s.FileProcess.ChunkSize, s.FileProcess.ChunkCount)
+	}
+	splitFileInfos, err := fileprocess.SplitFile(file, fileSize, s.splitFileBasePath(filePreview), partsNum)
+	if err != nil {
+		return err
+	}
+	log.Infof("complete split file into %d chunks", len(splitFileInfos))
+
+	job.ChunkCount = len(splitFileInfos)
+	job.State = model.UploadJobSplit
+	if err = s.Model.SaveUploadJob(job); err != nil {
+		return err
+	}
+	s.uploadEvents.publish(UploadEvent{PreviewId: job.PreviewId, Type: UploadEventSplit, Chunks: job.ChunkCount})
+	return nil
+}
+
+// encryptUploadFile encrypts the chunks not encrypted by a previous attempt,
+// spread over the proc nodes, and combines them.
+func (s *Server) encryptUploadFile(ctx context.Context, job *model.UploadJob, filePreview *model.FilePreview) error {
+	fileStat, err := os.Stat(filePreview.TmpPath)
+	if err != nil {
+		return err
+	}
+	splitFileInfos, err := fileprocess.SplitFileInfos(fileStat.Size(), s.splitFileBasePath(filePreview), job.ChunkCount)
+	if err != nil {
+		return err
+	}
+
+	encryptedFileChunkPaths := make([]string, len(splitFileInfos))
+	chunkMetadatas := make([]model.FileChunkMetadata, len(splitFileInfos))
+	encrypted := make(map[int64]model.FileChunkMetadata)
+	for _, chunkMetadata := range s.Model.GetFileChunkMetadatasByFileId(filePreview.Id) {
+		encrypted[chunkMetadata.Offset] = chunkMetadata
+	}
+	var pending []int
+	for i, splitFileInfo := range splitFileInfos {
+		encryptedFileChunkPaths[i] = s.StoreService.EncryptedChunkPath(splitFileInfo)
+		chunkMetadata, ok := encrypted[splitFileInfo.Offset]
+		if _, err = os.Stat(encryptedFileChunkPaths[i]); ok && err == nil {
+			chunkMetadatas[i] = chunkMetadata
+			continue
+		}
+		if _, err = os.Stat(splitFileInfo.FilePath); errors.Is(err, os.ErrNotExist) {
+			// the chunks were lost, split again
+			job.State = model.UploadJobCreated
+			return s.Model.SaveUploadJob(job)
+		}
+		pending = append(pending, i)
+	}
+
+	peerIds, err := s.StoreService.ProcPeers()
+	if err != nil {
+		return err
+	}
+	workers := s.FileProcess.EncryptWorkers
+	if workers <= 0 {
+		workers = len(peerIds)
+	}
+	keyShares := s.FileProcess.KeyShares
+	if keyShares <= 0 || keyShares > len(peerIds) {
+		keyShares = len(peerIds)
+	}
+	keyThreshold := s.FileProcess.KeyThreshold
+	if keyThreshold <= 0 || keyThreshold > keyShares {
+		keyThreshold = keyShares/2 + 1
+	}
+
+	log.Infof("start encrypting %d of %d chunks with %d workers...", len(pending), len(splitFileInfos), workers)
+	var lk sync.Mutex
+	job.EncryptedChunks = len(splitFileInfos) - len(pending)
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(workers)
+	for _, i := range pending {
+		i, splitFileInfo := i, splitFileInfos[i]
+		peerId := peerIds[i%len(peerIds)]
+		// the key shares go to the encrypting proc node and the ones after it
+		var sharePeers []peer.ID
+		for j := 0; j < keyShares; j++ {
+			sharePeers = append(sharePeers, peerIds[(i+j)%len(peerIds)])
+		}
+		progress := func(received int64, size int64) {
+			s.uploadEvents.publish(UploadEvent{PreviewId: job.PreviewId, Type: UploadEventChunkTransfer, Chunk: i, Chunks: job.ChunkCount, Bytes: received, Total: size})
+		}
+		g.Go(func() error {
+			encryptFilePath, resp, err := s.StoreService.EncryptFileChunk(gctx, filePreview, splitFileInfo, peerId, sharePeers, keyThreshold, progress)
+			if err != nil {
+				return err
+			}
+			log.Infof("chunk %s is encrypted into %s by %s", splitFileInfo.FilePath, encryptFilePath, peerId)
+
+			chunkMetadata := model.FileChunkMetadata{
+				FileId:        filePreview.Id,
+				Offset:        splitFileInfo.Offset,
+				Size:          splitFileInfo.Size,
+				EncryptedSize: int64(resp.Size),
+				PeerId:        peerId.String(),
+				SharePeers:    strings.Join(resp.SharePeers, ","),
+				Threshold:     resp.Threshold,
+			}
+			if err = s.Model.ReplaceFileChunkMetadata(&chunkMetadata); err != nil {
+				return err
+			}
+
+			lk.Lock()
+			defer lk.Unlock()
+			chunkMetadatas[i] = chunkMetadata
+			job.EncryptedChunks++
+			job.State = model.UploadJobEncrypting
+			if err = s.Model.SaveUploadJob(job); err != nil {
+				return err
+			}
+			s.uploadEvents.publish(UploadEvent{PreviewId: job.PreviewId, Type: UploadEventChunkEncrypted, Chunk: i, Chunks: job.ChunkCount})
+			return nil
+		})
+	}
+	if err = g.Wait(); err != nil {
+		return err
+	}
+	log.Infof("complete encrypting chunks")
+
+	var encryptedOffset int64 = 0
+	for i := range chunkMetadatas {
+		chunkMetadatas[i].EncryptedOffset = encryptedOffset
+		encryptedOffset += chunkMetadatas[i].EncryptedSize
+	}
+	if err = s.Model.UpdateFileChunkEncryptedOffsets(chunkMetadatas); err != nil {
+		return err
+	}
+
+	// combine encrypted file chunks
+	combinedEncryptedPath := filePreview.TmpPath + proc.ENCRYPT_SUFFIX
+	log.Infof("start combining ecnrypted chunks into %s", combinedEncryptedPath)
+	if err = fileprocess.CombineFile(encryptedFileChunkPaths, combinedEncryptedPath); err != nil {
+		return err
+	}
+	log.Infow("complete combining.")
+	for _, splitFileInfo := range splitFileInfos {
+		if err = os.Remove(splitFileInfo.FilePath); err != nil && !errors.Is(err, os.ErrNotExist) {
+			log.Error(err)
+		}
+	}
+
+	job.State = model.UploadJobCombined
+	return s.Model.SaveUploadJob(job)
+}
+
+// storeUploadFile stores the file at path on ipfs/filecoin, unless a previous
+// attempt already did, and prepares its filecoin deal.
+func (s *Server) storeUploadFile(ctx context.Context, job *model.UploadJob, filePreview *model.FilePreview, path string) error {
+	dsFile, err := s.Model.GetFileByFilenameAndStatus(filePreview.TmpPath, 0)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	if dsFile == nil {
+		storeFile, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer storeFile.Close()
+
+		fileStat, err := storeFile.Stat()
+		if err != nil {
+			return err
+		}
+
+		log.Infof("uploading to ipfs/filecoin...")
+		duration := int64(-1)
+		if filePreview.Duration > 0 {
+			duration = filePreview.Duration
+		}
+		reader := &progressReader{
+			Reader:    storeFile,
+			events:    s.uploadEvents,
+			previewId: job.PreviewId,
+			total:     fileStat.Size(),
+			lastPct:   -1,
+		}
+		dsFile, err = s.StoreService.StoreFile(ctx, reader, filePreview.ContentType, fileStat.Size(), filePreview.TmpPath, duration, filePreview.EthAddr, filePreview.Filename, filePreview.Replication)
+		if err != nil {
+			return err
+		}
+	}
+	if dsFile.PieceCid == "" {
+		log.Infof("packing %s into a car", path)
+		if err = s.StoreService.PrepareDeal(ctx, dsFile, path); err != nil {
+			return err
+		}
+	}
+
+	job.FileId = dsFile.Id
+	job.State = model.UploadJobUploaded
+	return s.Model.SaveUploadJob(job)
+}
+
+// linkUploadFile links the preview with the stored file and removes the
+// staged files.
+func (s *Server) linkUploadFile(job *model.UploadJob, filePreview *model.FilePreview) error {
+	if err := s.Model.LinkUploadJob(job); err != nil {
+		return err
+	}
+
+	for _, path := range []string{filePreview.TmpPath, filePreview.TmpPath + proc.ENCRYPT_SUFFIX} {
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			log.Error(err)
+		}
+	}
+	return nil
+}
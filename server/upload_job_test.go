@@ -0,0 +1,123 @@
+package server
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sao-datastore-storage/cmd"
+	"sao-datastore-storage/common"
+	"sao-datastore-storage/model"
+	"testing"
+	"time"
+)
+
+func TestRunUploadJob(t *testing.T) {
+	storage := common.StorageInfo{
+		Default: "a",
+		Stores:  []common.StoreInfo{{Name: "a", Type: "local", Params: map[string]string{"path": "a"}}},
+	}
+
+	cases := []struct {
+		name    string
+		encrypt bool
+		state   model.UploadJobState
+		// the file stored by a previous attempt
+		stored   bool
+		attempts int
+		// the staged file is lost
+		missing bool
+
+		wantState    model.UploadJobState
+		wantAttempts int
+		wantFailed   bool
+		wantEvent    UploadEventType
+		wantStatus   model.FilePreviewStatus
+	}{
+		{"stored", false, model.UploadJobCreated, false, 0, false, model.UploadJobLinked, 0, false, UploadEventDone, model.PlacedToIpfs},
+		{"resumed after the upload", false, model.UploadJobUploaded, true, 1, true, model.UploadJobLinked, 1, false, UploadEventDone, model.PlacedToIpfs},
+		{"staged file lost", false, model.UploadJobCreated, false, 0, true, model.UploadJobCreated, 1, false, UploadEventRetrying, model.Uploading},
+		{"last attempt", false, model.UploadJobCreated, false, defaultMaxUploadAttempts - 1, true, model.UploadJobCreated, defaultMaxUploadAttempts, true, UploadEventFailed, model.UploadFailed},
+		{"split without proc node", true, model.UploadJobCreated, false, 0, false, model.UploadJobSplit, 1, false, UploadEventRetrying, model.Uploading},
+	}
+	for _, c := range cases {
+		s := newTestServer(t, storage, common.QuotaInfo{})
+		s.FileProcess.ChunkCount = 2
+		if err := os.MkdirAll(filepath.Join(s.Repodir, cmd.FsStaging, "proc"), 0755); err != nil {
+			t.Fatal(err)
+		}
+
+		filePreview := model.FilePreview{
+			EthAddr: "0x01",
+			Size:    100,
+			TmpPath: filepath.Join(t.TempDir(), "file"),
+			Status:  model.Uploading,
+		}
+		if !c.missing {
+			if err := os.WriteFile(filePreview.TmpPath, make([]byte, filePreview.Size), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := s.Model.CreateFilePreview(&filePreview); err != nil {
+			t.Fatal(err)
+		}
+		job := model.UploadJob{
+			PreviewId: filePreview.Id,
+			Encrypt:   c.encrypt,
+			State:     c.state,
+			Attempts:  c.attempts,
+		}
+		if c.stored {
+			file := model.FileInfo{Size: filePreview.Size, Status: model.FileStored}
+			if err := s.Model.DB.Create(&file).Error; err != nil {
+				t.Fatal(err)
+			}
+			job.FileId = file.Id
+		}
+		if err := s.Model.SaveUploadJob(&job); err != nil {
+			t.Fatal(err)
+		}
+
+		events := s.uploadEvents.subscribe(filePreview.Id)
+		s.runUploadJob(context.Background(), &job)
+
+		stored, err := s.Model.GetUploadJobByPreviewId(filePreview.Id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stored.State != c.wantState || stored.Attempts != c.wantAttempts || stored.Failed != c.wantFailed {
+			t.Errorf("%s: job %s after %d attempts, failed %v, want %s after %d attempts, failed %v",
+				c.name, stored.State, stored.Attempts, stored.Failed, c.wantState, c.wantAttempts, c.wantFailed)
+		}
+		if c.wantEvent == UploadEventRetrying && !stored.NextAttemptAt.After(time.Now()) {
+			t.Errorf("%s: retried at %v", c.name, stored.NextAttemptAt)
+		}
+		if c.wantState == model.UploadJobLinked {
+			var files int64
+			s.Model.DB.Model(&model.FileInfo{}).Count(&files)
+			if stored.FileId == 0 || files != 1 {
+				t.Errorf("%s: job of file %d, %d files stored", c.name, stored.FileId, files)
+			}
+		}
+
+		preview, err := s.Model.GetFilePreviewById(filePreview.Id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if preview.Status != c.wantStatus {
+			t.Errorf("%s: preview status %d, want %d", c.name, preview.Status, c.wantStatus)
+		}
+
+		var last UploadEvent
+	drain:
+		for {
+			select {
+			case last = <-events:
+			default:
+				break drain
+			}
+		}
+		if last.Type != c.wantEvent {
+			t.Errorf("%s: last event %q, want %q", c.name, last.Type, c.wantEvent)
+		}
+	}
+}
@@ -0,0 +1,187 @@
+package server
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"sao-datastore-storage/model"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	errUploadNotFound = errors.New("upload not found")
+	errUploadOffset   = errors.New("upload offset mismatch")
+	errUploadBusy     = errors.New("upload is being patched by another request")
+)
+
+type UploadSessionReq struct {
+	Filename       string
+	AdditionalInfo string
+	Size           int64
+}
+
+// createUploadSession starts an upload, whose size is counted in the storage
+// quota of the address until it is complete.
+func (s *Server) createUploadSession(req UploadSessionReq, ethAddress string) (*model.UploadSession, error) {
+	if err := s.checkQuota(ethAddress, req.Size); err != nil {
+		return nil, err
+	}
+
+	tmpPath, err := s.stagingDir(ethAddress)
+	if err != nil {
+		return nil, err
+	}
+	tempFile, err := ioutil.TempFile(tmpPath, uuid.New().String())
+	if err != nil {
+		return nil, err
+	}
+	tempFile.Close()
+
+	session := model.UploadSession{
+		UploadId:       uuid.New().String(),
+		EthAddr:        ethAddress,
+		Filename:       req.Filename,
+		AdditionalInfo: req.AdditionalInfo,
+		Size:           req.Size,
+		TmpPath:        tempFile.Name(),
+	}
+	if err = s.Model.CreateUploadSession(&session); err != nil {
+		os.Remove(tempFile.Name())
+		return nil, err
+	}
+	return &session, nil
+}
+
+// getUploadSession returns the upload with the offset it is resumed from.
+func (s *Server) getUploadSession(uploadId string, ethAddress string) (*model.UploadSession, error) {
+	session, err := s.Model.GetUploadSession(uploadId, ethAddress)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errUploadNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	fileStat, err := os.Stat(session.TmpPath)
+	if err != nil {
+		return nil, err
+	}
+	session.Offset = fileStat.Size()
+	return session, nil
+}
+
+// appendUpload appends body to the upload at offset. The bytes received
+// before an error are kept, the upload is resumed after them. Once the upload
+// is complete, its preview is created.
+func (s *Server) appendUpload(uploadId string, ethAddress string, offset int64, body io.Reader) (*model.UploadSession, *model.FileInfoInMarket, error) {
+	if !s.lockUploadSession(uploadId) {
+		return nil, nil, errUploadBusy
+	}
+	defer s.unlockUploadSession(uploadId)
+
+	session, err := s.getUploadSession(uploadId, ethAddress)
+	if err != nil {
+		return nil, nil, err
+	}
+	if offset != session.Offset {
+		return session, nil, errUploadOffset
+	}
+
+	file, err := os.OpenFile(session.TmpPath, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		return session, nil, err
+	}
+	n, err := io.Copy(file, io.LimitReader(body, session.Size-session.Offset+1))
+	session.Offset += n
+	if err == nil && session.Offset > session.Size {
+		// reject the whole request
+		err = file.Truncate(offset)
+		if err == nil {
+			err = errUploadTooLarge
+		}
+		session.Offset = offset
+	}
+	if cerr := file.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil || session.Offset < session.Size {
+		return session, nil, err
+	}
+
+	staged, err := hashStagedUpload(session.TmpPath)
+	if err != nil {
+		return session, nil, err
+	}
+	// the session is deleted first so that a retried request cannot create
+	// the preview twice
+	if err = s.Model.DeleteUploadSession(session); err != nil {
+		return session, nil, err
+	}
+	fi, err := s.createFilePreview(staged, session.Filename, ethAddress, session.AdditionalInfo)
+	return session, fi, err
+}
+
+func hashStagedUpload(path string) (*stagedUpload, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	digest := newUploadDigest()
+	if _, err = io.Copy(digest, file); err != nil {
+		return nil, err
+	}
+	return &stagedUpload{
+		Path:        path,
+		Size:        digest.size,
+		Sha256:      digest.sum(),
+		ContentType: digest.contentType(),
+	}, nil
+}
+
+func (s *Server) deleteUploadSession(uploadId string, ethAddress string) error {
+	if !s.lockUploadSession(uploadId) {
+		return errUploadBusy
+	}
+	defer s.unlockUploadSession(uploadId)
+
+	session, err := s.Model.GetUploadSession(uploadId, ethAddress)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return errUploadNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if err = s.Model.DeleteUploadSession(session); err != nil {
+		return err
+	}
+	if err = os.Remove(session.TmpPath); err != nil {
+		log.Error(err)
+	}
+	return nil
+}
+
+func (s *Server) lockUploadSession(uploadId string) bool {
+	s.uploadSessionsLk.Lock()
+	defer s.uploadSessionsLk.Unlock()
+
+	if s.uploadSessions == nil {
+		s.uploadSessions = make(map[string]struct{})
+	}
+	if _, ok := s.uploadSessions[uploadId]; ok {
+		return false
+	}
+	s.uploadSessions[uploadId] = struct{}{}
+	return true
+}
+
+func (s *Server) unlockUploadSession(uploadId string) {
+	s.uploadSessionsLk.Lock()
+	defer s.uploadSessionsLk.Unlock()
+
+	delete(s.uploadSessions, uploadId)
+}
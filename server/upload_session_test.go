@@ -0,0 +1,108 @@
+package server
+
+import (
+	"errors"
+	"io"
+	"os"
+	"sao-datastore-storage/common"
+	"sao-datastore-storage/model"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestAppendUpload(t *testing.T) {
+	errBroken := errors.New("connection reset")
+	type patch struct {
+		offset int64
+		body   io.Reader
+		err    error
+		// offset the upload is resumed from after the request, -1 once
+		// the upload is gone
+		resumed int64
+	}
+
+	cases := []struct {
+		name    string
+		patches []patch
+		// content of the preview created once the upload is complete
+		content string
+	}{
+		{"one request", []patch{
+			{0, strings.NewReader("0123456789"), nil, 10},
+		}, "0123456789"},
+		{"resumed", []patch{
+			{0, strings.NewReader("0123"), nil, 4},
+			{4, strings.NewReader("456789"), nil, 10},
+		}, "0123456789"},
+		{"stale offset", []patch{
+			{0, strings.NewReader("0123"), nil, 4},
+			{0, strings.NewReader("0123"), errUploadOffset, 4},
+			{6, strings.NewReader("6789"), errUploadOffset, 4},
+			{4, strings.NewReader("456789"), nil, 10},
+		}, "0123456789"},
+		{"interrupted", []patch{
+			{0, io.MultiReader(strings.NewReader("012"), iotest.ErrReader(errBroken)), errBroken, 3},
+			{3, strings.NewReader("3456789"), nil, 10},
+		}, "0123456789"},
+		{"too large", []patch{
+			{0, strings.NewReader("0123"), nil, 4},
+			{4, strings.NewReader("4567890"), errUploadTooLarge, 4},
+			{4, strings.NewReader("456789"), nil, 10},
+		}, "0123456789"},
+		{"retried once complete", []patch{
+			{0, strings.NewReader("0123456789"), nil, 10},
+			{0, strings.NewReader("0123456789"), errUploadNotFound, -1},
+		}, "0123456789"},
+		{"incomplete", []patch{
+			{0, strings.NewReader("01234"), nil, 5},
+		}, ""},
+	}
+	for _, c := range cases {
+		s := newTestServer(t, common.StorageInfo{}, common.QuotaInfo{})
+		session, err := s.createUploadSession(UploadSessionReq{Filename: "file.txt", Size: 10}, "0x01")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var preview *model.FileInfoInMarket
+		for i, p := range c.patches {
+			resumed, fi, err := s.appendUpload(session.UploadId, "0x01", p.offset, p.body)
+			if !errors.Is(err, p.err) {
+				t.Errorf("%s: patch %d: got error %v, want %v", c.name, i, err, p.err)
+			}
+			offset := int64(-1)
+			if resumed != nil {
+				offset = resumed.Offset
+			}
+			if offset != p.resumed {
+				t.Errorf("%s: patch %d: resumed from %d, want %d", c.name, i, offset, p.resumed)
+			}
+			if fi != nil {
+				preview = fi
+			}
+		}
+
+		if c.content == "" {
+			if preview != nil {
+				t.Errorf("%s: preview created", c.name)
+			}
+			continue
+		}
+		if preview == nil {
+			t.Errorf("%s: no preview created", c.name)
+			continue
+		}
+		filePreview, err := s.Model.GetFilePreviewById(preview.Id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, err := os.ReadFile(filePreview.TmpPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(content) != c.content || filePreview.Size != int64(len(c.content)) {
+			t.Errorf("%s: preview of %d bytes %q, want %q", c.name, filePreview.Size, content, c.content)
+		}
+	}
+}
@@ -324,5 +324,45 @@ func (s *Server) GetUserSummary(ctx *gin.Context) {
 		api.ServerError(ctx, "error.get.usersummary", "database error")
 		return
 	}
+	tier := s.Quotas.Tier(ethAddress.(string))
+	summary.SpaceQuota = tier.Space
+	summary.Applications = tier.Applications
 	api.Success(ctx, summary)
 }
+
+// GetUserNotifications returns the notifications of the user, the latest
+// first.
+func (s *Server) GetUserNotifications(ctx *gin.Context) {
+	ethAddress, _ := ctx.Get("User")
+	if ethAddress.(string) == "" {
+		api.Unauthorized(ctx, "invalid.signature", "invalid signature")
+		return
+	}
+
+	offset, got := ctx.GetQuery("offset")
+	if !got {
+		offset = "0"
+	}
+	o, err := strconv.Atoi(offset)
+	if err != nil {
+		log.Info(err)
+		o = 0
+	}
+	limit, got := ctx.GetQuery("limit")
+	if !got {
+		limit = "10"
+	}
+	l, err := strconv.Atoi(limit)
+	if err != nil {
+		log.Info(err)
+		l = 10
+	}
+
+	notifications, err := s.Model.GetNotifications(ethAddress.(string), o, l)
+	if err != nil {
+		log.Error(err)
+		api.ServerError(ctx, "error.get.notifications", "database error")
+		return
+	}
+	api.Success(ctx, notifications)
+}
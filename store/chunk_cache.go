@@ -0,0 +1,203 @@
+package store
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const defaultDecryptCacheSize int64 = 1 << 30
+const defaultDecryptCacheTTL = 10 * time.Minute
+
+type chunkKey struct {
+	fileId uint
+	offset int64
+}
+
+type authKey struct {
+	fileId  uint
+	ethAddr string
+}
+
+type cachedChunk struct {
+	key      chunkKey
+	path     string
+	size     int64
+	expireAt time.Time
+}
+
+// chunkCache keeps decrypted chunks on disk for a while after they are read,
+// so that the ranges read again, e.g. by a player seeking, are not decrypted
+// again. It is bounded by size, the least recently read chunks are evicted
+// first. A chunk is only served to the addresses a proc node agreed to
+// decrypt the file for, until the auth message they signed expires.
+type chunkCache struct {
+	dir     string
+	maxSize int64
+	ttl     time.Duration
+
+	lk     sync.Mutex
+	size   int64
+	lru    *list.List
+	chunks map[chunkKey]*list.Element
+	// expiry of the auth messages the proc nodes accepted
+	authorized map[authKey]time.Time
+}
+
+func newChunkCache(dir string, maxSize int64, ttl time.Duration) *chunkCache {
+	if maxSize <= 0 {
+		maxSize = defaultDecryptCacheSize
+	}
+	if ttl <= 0 {
+		ttl = defaultDecryptCacheTTL
+	}
+	return &chunkCache{
+		dir:        dir,
+		maxSize:    maxSize,
+		ttl:        ttl,
+		lru:        list.New(),
+		chunks:     make(map[chunkKey]*list.Element),
+		authorized: make(map[authKey]time.Time),
+	}
+}
+
+// start clears the chunks left by the previous run and expires the chunks
+// until ctx is done.
+func (c *chunkCache) start(ctx context.Context) error {
+	if err := os.RemoveAll(c.dir); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(c.dir, 0755); err != nil {
+		return err
+	}
+
+	go func() {
+		ticker := time.NewTicker(c.ttl / 2)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				c.expire(now)
+			}
+		}
+	}()
+	return nil
+}
+
+// get opens the decrypted chunk at offset of fileId, if ethAddr is allowed to
+// read it. The file stays readable once open even if the chunk is evicted.
+func (c *chunkCache) get(fileId uint, offset int64, ethAddr string) (*os.File, bool) {
+	c.lk.Lock()
+	defer c.lk.Unlock()
+
+	now := time.Now()
+	if expireAt, ok := c.authorized[authKey{fileId, ethAddr}]; !ok || now.After(expireAt) {
+		return nil, false
+	}
+	elem, ok := c.chunks[chunkKey{fileId, offset}]
+	if !ok {
+		return nil, false
+	}
+	chunk := elem.Value.(*cachedChunk)
+	f, err := os.Open(chunk.path)
+	if err != nil {
+		log.Error(err)
+		c.remove(elem)
+		return nil, false
+	}
+
+	chunk.expireAt = now.Add(c.ttl)
+	c.lru.MoveToFront(elem)
+	return f, true
+}
+
+// put moves the chunk decrypted for ethAddr at path into the cache, and
+// returns its new path. ethAddr reads the chunks of fileId from the cache
+// until authExpiry, the expiry of the auth message the proc nodes accepted.
+// Chunks larger than the cache are left in place.
+func (c *chunkCache) put(fileId uint, offset int64, ethAddr string, authExpiry time.Time, path string) (string, bool) {
+	fileStat, err := os.Stat(path)
+	if err != nil || fileStat.Size() > c.maxSize {
+		return path, false
+	}
+
+	key := chunkKey{fileId, offset}
+	cachePath := filepath.Join(c.dir, fmt.Sprintf("%d_%d", fileId, offset))
+
+	c.lk.Lock()
+	defer c.lk.Unlock()
+
+	if elem, ok := c.chunks[key]; ok {
+		c.remove(elem)
+	}
+	if err = os.Rename(path, cachePath); err != nil {
+		log.Error(err)
+		return path, false
+	}
+
+	c.chunks[key] = c.lru.PushFront(&cachedChunk{
+		key:      key,
+		path:     cachePath,
+		size:     fileStat.Size(),
+		expireAt: time.Now().Add(c.ttl),
+	})
+	c.size += fileStat.Size()
+	if authExpiry.After(c.authorized[authKey{fileId, ethAddr}]) {
+		c.authorized[authKey{fileId, ethAddr}] = authExpiry
+	}
+
+	for c.size > c.maxSize {
+		c.remove(c.lru.Back())
+	}
+	return cachePath, true
+}
+
+func (c *chunkCache) expire(now time.Time) {
+	c.lk.Lock()
+	defer c.lk.Unlock()
+
+	for elem := c.lru.Back(); elem != nil; {
+		prev := elem.Prev()
+		if now.After(elem.Value.(*cachedChunk).expireAt) {
+			c.remove(elem)
+		}
+		elem = prev
+	}
+	for key, expireAt := range c.authorized {
+		if now.After(expireAt) {
+			delete(c.authorized, key)
+		}
+	}
+}
+
+func (c *chunkCache) remove(elem *list.Element) {
+	chunk := c.lru.Remove(elem).(*cachedChunk)
+	delete(c.chunks, chunk.key)
+	c.size -= chunk.size
+	if err := os.Remove(chunk.path); err != nil {
+		log.Error(err)
+	}
+}
+
+// removeOriginalFiles removes the decrypted files combined by the previous
+// versions in dir, which were never cleaned up.
+func removeOriginalFiles(dir string) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasSuffix(entry.Name(), ".original") {
+			if err = os.Remove(filepath.Join(dir, entry.Name())); err != nil {
+				log.Error(err)
+			}
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package store
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func newTestChunk(t *testing.T, dir string, size int) string {
+	f, err := ioutil.TempFile(dir, "chunk")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err = f.Write(make([]byte, size)); err != nil {
+		t.Fatal(err)
+	}
+	return f.Name()
+}
+
+func cachedOffsets(c *chunkCache, fileId uint, ethAddr string, offsets ...int64) []int64 {
+	var cached []int64
+	for _, offset := range offsets {
+		if f, ok := c.get(fileId, offset, ethAddr); ok {
+			f.Close()
+			cached = append(cached, offset)
+		}
+	}
+	return cached
+}
+
+func TestChunkCacheEviction(t *testing.T) {
+	dir := t.TempDir()
+	authExpiry := time.Now().Add(time.Hour)
+
+	type chunk struct {
+		offset int64
+		size   int
+	}
+	cases := []struct {
+		name string
+		put  []chunk
+		// offsets read before the last chunk is put
+		read   []int64
+		cached []int64
+	}{
+		{"fits", []chunk{{0, 10}, {10, 10}, {20, 10}}, nil, []int64{0, 10, 20}},
+		{"least recently put evicted", []chunk{{0, 10}, {10, 10}, {20, 10}, {30, 10}}, nil, []int64{10, 20, 30}},
+		{"least recently read evicted", []chunk{{0, 10}, {10, 10}, {20, 10}, {30, 10}}, []int64{0}, []int64{0, 20, 30}},
+		{"larger than the cache", []chunk{{0, 10}, {10, 40}}, nil, []int64{0}},
+	}
+	for i, tc := range cases {
+		c := newChunkCache(filepath.Join(dir, strconv.Itoa(i)), 30, time.Minute)
+		if err := os.MkdirAll(c.dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		for j, ch := range tc.put {
+			if j == len(tc.put)-1 {
+				cachedOffsets(c, 1, "0x01", tc.read...)
+			}
+			path, cached := c.put(1, ch.offset, "0x01", authExpiry, newTestChunk(t, dir, ch.size))
+			if cached != (int64(ch.size) <= c.maxSize) {
+				t.Errorf("%s: chunk of %d bytes cached %v", tc.name, ch.size, cached)
+			}
+			if _, err := os.Stat(path); err != nil {
+				t.Errorf("%s: chunk put at %d missing: %v", tc.name, ch.offset, err)
+			}
+		}
+		if cached := cachedOffsets(c, 1, "0x01", 0, 10, 20, 30); !reflect.DeepEqual(cached, tc.cached) {
+			t.Errorf("%s: cached %v, want %v", tc.name, cached, tc.cached)
+		}
+
+		// the chunks not read within the TTL expire
+		c.expire(time.Now().Add(2 * time.Minute))
+		if c.size != 0 || c.lru.Len() != 0 {
+			t.Errorf("%s: %d bytes of %d chunks left after the TTL", tc.name, c.size, c.lru.Len())
+		}
+	}
+}
+
+func TestChunkCacheAuthorization(t *testing.T) {
+	dir := t.TempDir()
+	c := newChunkCache(filepath.Join(dir, "cache"), 1<<20, time.Hour)
+	if err := os.MkdirAll(c.dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	c.put(1, 0, "0x01", now.Add(time.Minute), newTestChunk(t, dir, 10))
+	c.put(1, 10, "0x02", now.Add(-time.Minute), newTestChunk(t, dir, 10))
+
+	cases := []struct {
+		name    string
+		ethAddr string
+		offset  int64
+		cached  bool
+	}{
+		{"decrypted for the address", "0x01", 0, true},
+		{"other chunk of an authorized file", "0x01", 10, true},
+		{"other address", "0x03", 0, false},
+		{"expired auth message", "0x02", 10, false},
+		{"missing chunk", "0x01", 20, false},
+	}
+	for _, tc := range cases {
+		if cached := len(cachedOffsets(c, 1, tc.ethAddr, tc.offset)) == 1; cached != tc.cached {
+			t.Errorf("%s: cached %v, want %v", tc.name, cached, tc.cached)
+		}
+	}
+
+	// the authorization ends with the auth message, even if the chunks are
+	// read within their TTL
+	c.expire(now.Add(2 * time.Minute))
+	if _, ok := c.get(1, 0, "0x01"); ok {
+		t.Error("chunk served after the auth message expired")
+	}
+}
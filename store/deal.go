@@ -0,0 +1,63 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sao-datastore-storage/model"
+	"sao-datastore-storage/util/car"
+
+	"github.com/filecoin-project/go-commp-utils/writer"
+)
+
+// PrepareDeal packs the local copy at path of the stored file into the CAR of
+// its UnixFS DAG, and records the payload CID and the piece commitment of the
+// CAR, so that a deal for the file can be made with any storage provider.
+// The CAR is kept at DealCarPath until the file is deleted.
+func (a StoreService) PrepareDeal(ctx context.Context, file *model.FileInfo, path string) error {
+	if err := os.MkdirAll(a.dealsPath(), 0755); err != nil {
+		return err
+	}
+	tmpPath := path + ".car"
+	defer os.Remove(tmpPath)
+
+	commpWriter := &writer.Writer{}
+	root, _, err := car.PackFile(ctx, path, tmpPath, commpWriter)
+	if err != nil {
+		return err
+	}
+	sum, err := commpWriter.Sum()
+	if err != nil {
+		return err
+	}
+
+	file.Cid = root.String()
+	file.PieceCid = sum.PieceCID.String()
+	file.PieceSize = int64(sum.PieceSize)
+	file.CarSize = sum.PayloadSize
+	if err = os.Rename(tmpPath, a.DealCarPath(file)); err != nil {
+		return err
+	}
+	return a.m.UpdateFileDeal(file)
+}
+
+// DealCarPath returns where the CAR of the file prepared for its deals is
+// kept, named after its payload CID.
+func (a StoreService) DealCarPath(file *model.FileInfo) string {
+	return filepath.Join(a.dealsPath(), file.Cid+".car")
+}
+
+func (a StoreService) dealsPath() string {
+	return filepath.Join(a.repodir, "deals")
+}
+
+// removeDealCar removes the CAR prepared for the deals of file, if any.
+func (a StoreService) removeDealCar(file *model.FileInfo) {
+	if file.Cid == "" {
+		return
+	}
+	if err := os.Remove(a.DealCarPath(file)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		log.Error(err)
+	}
+}
@@ -65,5 +65,16 @@ func (s IpfsStore) DeleteFile(ctx context.Context, info map[string]string) error
 }
 
 func (s IpfsStore) GetFile(ctx context.Context, info map[string]string) (io.ReadCloser, error) {
-	return s.shell.Cat(info["hash"])
+	if info["offset"] == "" {
+		return s.shell.Cat(info["hash"])
+	}
+
+	resp, err := s.shell.Request("cat", info["hash"]).Option("offset", info["offset"]).Send(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Error != nil {
+		return nil, resp.Error
+	}
+	return resp.Output, nil
 }
@@ -0,0 +1,101 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sao-datastore-storage/util/car"
+	"strconv"
+
+	"github.com/ipfs/go-cid"
+)
+
+// LocalStore stores files under a local dir, each one as the CAR file of its
+// UnixFS DAG named after the CIDv1 of the DAG. It needs no IPFS node nor MCS
+// account, so that the server runs in development and tests.
+type LocalStore struct {
+	dir string
+}
+
+func NewLocalStore(dir string) (LocalStore, error) {
+	if err := os.MkdirAll(filepath.Join(dir, "tmp"), 0755); err != nil {
+		return LocalStore{}, err
+	}
+	return LocalStore{
+		dir: dir,
+	}, nil
+}
+
+func (s LocalStore) StoreFile(ctx context.Context, reader io.Reader, info map[string]string) (StoreRet, error) {
+	tmpFile, err := os.CreateTemp(filepath.Join(s.dir, "tmp"), "*")
+	if err != nil {
+		return StoreRet{}, err
+	}
+	defer os.Remove(tmpFile.Name())
+
+	_, err = io.Copy(tmpFile, reader)
+	if closeErr := tmpFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return StoreRet{}, err
+	}
+
+	carPath := tmpFile.Name() + ".car"
+	defer os.Remove(carPath)
+	root, err := car.StageFile(ctx, tmpFile.Name(), carPath)
+	if err != nil {
+		return StoreRet{}, err
+	}
+	// the same content is stored once
+	if err = os.Rename(carPath, s.carPath(root)); err != nil {
+		return StoreRet{}, err
+	}
+
+	return StoreRet{
+		IpfsHash: root.String(),
+	}, nil
+}
+
+// GetFile reads the file of info["hash"] from info["offset"].
+func (s LocalStore) GetFile(ctx context.Context, info map[string]string) (io.ReadCloser, error) {
+	root, err := cid.Decode(info["hash"])
+	if err != nil {
+		return nil, err
+	}
+	r, err := car.OpenFile(ctx, s.carPath(root))
+	if err != nil {
+		return nil, err
+	}
+
+	if info["offset"] != "" {
+		offset, err := strconv.ParseInt(info["offset"], 10, 64)
+		if err != nil {
+			r.Close()
+			return nil, fmt.Errorf("invalid offset %q", info["offset"])
+		}
+		if _, err = r.Seek(offset, io.SeekStart); err != nil {
+			r.Close()
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+func (s LocalStore) DeleteFile(ctx context.Context, info map[string]string) error {
+	root, err := cid.Decode(info["hash"])
+	if err != nil {
+		return err
+	}
+	err = os.Remove(s.carPath(root))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+func (s LocalStore) carPath(root cid.Cid) string {
+	return filepath.Join(s.dir, root.String()+".car")
+}
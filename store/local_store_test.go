@@ -0,0 +1,58 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"io"
+	"strconv"
+	"testing"
+
+	"github.com/ipfs/go-cid"
+)
+
+func TestLocalStore(t *testing.T) {
+	store, err := NewLocalStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	data := make([]byte, 3<<20+17)
+	rand.Read(data)
+	ret, err := store.StoreFile(ctx, bytes.NewReader(data), map[string]string{})
+	if err != nil {
+		t.Fatal("failed to store file", err)
+	}
+	root, err := cid.Decode(ret.IpfsHash)
+	if err != nil || root.Version() != 1 {
+		t.Fatalf("stored file hash %q is not a CIDv1", ret.IpfsHash)
+	}
+
+	again, err := store.StoreFile(ctx, bytes.NewReader(data), map[string]string{})
+	if err != nil {
+		t.Fatal("failed to store file again", err)
+	}
+	if again.IpfsHash != ret.IpfsHash {
+		t.Fatalf("same content stored as %s and %s", ret.IpfsHash, again.IpfsHash)
+	}
+
+	for _, offset := range []int{0, 1, 1<<20 + 1, len(data)} {
+		reader, err := store.GetFile(ctx, map[string]string{"hash": ret.IpfsHash, "offset": strconv.Itoa(offset)})
+		if err != nil {
+			t.Fatal("failed to get file", err)
+		}
+		read, _ := io.ReadAll(reader)
+		reader.Close()
+		if !bytes.Equal(read, data[offset:]) {
+			t.Fatalf("read %d bytes from offset %d", len(read), offset)
+		}
+	}
+
+	if err = store.DeleteFile(ctx, map[string]string{"hash": ret.IpfsHash}); err != nil {
+		t.Fatal("failed to delete file", err)
+	}
+	if _, err = store.GetFile(ctx, map[string]string{"hash": ret.IpfsHash}); err == nil {
+		t.Fatal("deleted file is still readable")
+	}
+}
@@ -0,0 +1,158 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	go_mcs_sdk "sao-datastore-storage/go-mcs-sdk"
+	"sao-datastore-storage/model"
+	"strings"
+	"time"
+)
+
+// how often the deal of a file is checked until it is active, then once
+// active
+const mcsDealPendingInterval = time.Hour
+const mcsDealActiveInterval = 24 * time.Hour
+
+// the storage of a file is paid again this long before MCS_DURATION ends
+const mcsRenewBefore = 30 * 24 * time.Hour
+
+// a failed deal is paid again at most this often, MCS reports the new deal
+// only once it is made
+const mcsFailedRenewDelay = 7 * 24 * time.Hour
+
+// MCS uploads checked at most at once
+const mcsDealBatchSize = 100
+
+// mcsDealStore follows and pays again for the deals of MCS uploads, see
+// McsStore.
+type mcsDealStore interface {
+	GetDeal(ctx context.Context, mcsInfo *model.McsInfo) (*go_mcs_sdk.SourceFileUploadDeal, error)
+	Renew(ctx context.Context, mcsInfo *model.McsInfo) (string, error)
+}
+
+// runMcsDeals follows the filecoin deals of the files stored through MCS,
+// until ctx is done.
+func (a StoreService) runMcsDeals(ctx context.Context) {
+	ticker := time.NewTicker(10 * time.Minute)
+	defer ticker.Stop()
+	for {
+		infos, err := a.m.GetDueMcsInfos(time.Now(), mcsDealBatchSize)
+		if err != nil {
+			log.Error(err)
+		}
+		for i := range infos {
+			if ctx.Err() != nil {
+				return
+			}
+			if err = a.checkMcsDeal(ctx, &infos[i]); err != nil {
+				log.Warnf("mcs upload %d: %v", infos[i].SourceFileUploadId, err)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// checkMcsDeal records the deal status of the MCS upload, and the miner of
+// the deal as storage provider of the file. The storage is paid again when
+// the deal failed or its duration is about to end.
+func (a StoreService) checkMcsDeal(ctx context.Context, info *model.McsInfo) error {
+	now := time.Now()
+	info.NextDealCheckAt = now.Add(mcsDealPendingInterval)
+	defer func() {
+		if err := a.m.SaveMcsInfo(info); err != nil {
+			log.Error(err)
+		}
+	}()
+
+	store, err := a.mcsStore(info.Backend)
+	if err != nil {
+		return err
+	}
+	deal, err := store.GetDeal(ctx, info)
+	if err != nil {
+		return err
+	}
+
+	info.DealStatus = deal.Status
+	if deal.DealId > 0 {
+		info.DealId = deal.DealId
+		info.DealCid = deal.DealCid
+		info.MessageCid = deal.MessageCid
+	}
+	if deal.MinerFid != "" && deal.MinerFid != info.MinerFid {
+		info.MinerFid = deal.MinerFid
+		if err = a.m.UpdateStorageProvider(info.Id, deal.MinerFid); err != nil {
+			return err
+		}
+	}
+	if mcsDealActive(deal.Status) {
+		info.NextDealCheckAt = now.Add(mcsDealActiveInterval)
+	}
+
+	paidAt := info.PaidAt
+	if paidAt.IsZero() {
+		paidAt = info.CreatedAt
+	}
+	paidUntil := paidAt.Add(MCS_DURATION * 24 * time.Hour)
+	expiring := now.After(paidUntil.Add(-mcsRenewBefore)) && a.keptAfter(info, paidUntil)
+	failed := mcsDealFailed(deal.Status) && now.After(paidAt.Add(mcsFailedRenewDelay))
+	if !failed && !expiring {
+		return nil
+	}
+
+	log.Infof("mcs upload %d: renewing, deal %d is %s", info.SourceFileUploadId, info.DealId, deal.Status)
+	tx, err := store.Renew(ctx, info)
+	if err != nil {
+		return fmt.Errorf("renewing: %w", err)
+	}
+	info.PaymentTxHash = tx
+	info.PaidAt = now
+	// follow the new deal
+	info.DealId = 0
+	info.DealStatus = ""
+	info.NextDealCheckAt = now.Add(mcsDealPendingInterval)
+	return nil
+}
+
+// keptAfter tells whether the file of the MCS upload expires after t.
+func (a StoreService) keptAfter(info *model.McsInfo, t time.Time) bool {
+	file, err := a.m.GetFileByMcsInfoId(info.Id)
+	if err != nil {
+		log.Error(err)
+		return false
+	}
+	return file.ExpireAt < 0 || file.ExpireAt > t.UnixMilli()
+}
+
+// mcsStore returns the McsStore named backend, "mcs" if empty.
+func (a StoreService) mcsStore(backend string) (mcsDealStore, error) {
+	if backend == "" {
+		backend = "mcs"
+	}
+	store, ok := a.storeMap[backend].(mcsDealStore)
+	if !ok {
+		return nil, fmt.Errorf("no mcs store %q", backend)
+	}
+	return store, nil
+}
+
+func mcsDealActive(status string) bool {
+	status = strings.ToLower(status)
+	return strings.Contains(status, "active") || strings.Contains(status, "success")
+}
+
+func mcsDealFailed(status string) bool {
+	status = strings.ToLower(status)
+	for _, failed := range []string{"fail", "error", "slashed", "expired", "refund"} {
+		if strings.Contains(status, failed) {
+			return true
+		}
+	}
+	return false
+}
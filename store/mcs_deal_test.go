@@ -0,0 +1,117 @@
+package store
+
+import (
+	"context"
+	"errors"
+	go_mcs_sdk "sao-datastore-storage/go-mcs-sdk"
+	"sao-datastore-storage/model"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeMcsStore reports deal and counts the renewals.
+type fakeMcsStore struct {
+	Store
+	deal     go_mcs_sdk.SourceFileUploadDeal
+	dealErr  error
+	renewErr error
+	renewed  int
+}
+
+func (s *fakeMcsStore) GetDeal(ctx context.Context, mcsInfo *model.McsInfo) (*go_mcs_sdk.SourceFileUploadDeal, error) {
+	if s.dealErr != nil {
+		return nil, s.dealErr
+	}
+	deal := s.deal
+	return &deal, nil
+}
+
+func (s *fakeMcsStore) Renew(ctx context.Context, mcsInfo *model.McsInfo) (string, error) {
+	if s.renewErr != nil {
+		return "", s.renewErr
+	}
+	s.renewed++
+	return "0xrenewal", nil
+}
+
+func TestCheckMcsDeal(t *testing.T) {
+	day := 24 * time.Hour
+	now := time.Now()
+	duration := MCS_DURATION * day
+	// the storage paid for ends in 10 days
+	expiring := now.Add(10*day - duration)
+
+	cases := []struct {
+		name string
+		mcs  fakeMcsStore
+		// when the storage was paid for, and the upload created
+		paidAt    time.Time
+		createdAt time.Time
+		// unix milliseconds the file expires at, -1 if never
+		expireAt int64
+
+		err     string
+		renewed bool
+		// the deal is checked again after
+		next time.Duration
+	}{
+		{"waiting", fakeMcsStore{deal: go_mcs_sdk.SourceFileUploadDeal{Status: "Waiting"}}, now.Add(-day), now.Add(-day), -1, "", false, mcsDealPendingInterval},
+		{"active", fakeMcsStore{deal: go_mcs_sdk.SourceFileUploadDeal{DealId: 5, MinerFid: "f01000", Status: "Active"}}, now.Add(-day), now.Add(-day), -1, "", false, mcsDealActiveInterval},
+		{"failed just after the payment", fakeMcsStore{deal: go_mcs_sdk.SourceFileUploadDeal{Status: "Failed"}}, now.Add(-day), now.Add(-day), -1, "", false, mcsDealPendingInterval},
+		{"failed", fakeMcsStore{deal: go_mcs_sdk.SourceFileUploadDeal{Status: "Failed"}}, now.Add(-8 * day), now.Add(-8 * day), -1, "", true, mcsDealPendingInterval},
+		{"expiring", fakeMcsStore{deal: go_mcs_sdk.SourceFileUploadDeal{DealId: 5, Status: "Active"}}, expiring, expiring, -1, "", true, mcsDealPendingInterval},
+		{"expiring legacy upload", fakeMcsStore{deal: go_mcs_sdk.SourceFileUploadDeal{DealId: 5, Status: "Active"}}, time.Time{}, expiring, -1, "", true, mcsDealPendingInterval},
+		{"expiring with the file", fakeMcsStore{deal: go_mcs_sdk.SourceFileUploadDeal{DealId: 5, Status: "Active"}}, expiring, expiring, now.Add(5 * day).UnixMilli(), "", false, mcsDealActiveInterval},
+		{"kept after the storage", fakeMcsStore{deal: go_mcs_sdk.SourceFileUploadDeal{DealId: 5, Status: "Active"}}, expiring, expiring, now.Add(20 * day).UnixMilli(), "", true, mcsDealPendingInterval},
+		{"deal unknown", fakeMcsStore{dealErr: errors.New("mcs down")}, now.Add(-8 * day), now.Add(-8 * day), -1, "mcs down", false, mcsDealPendingInterval},
+		{"renewal failed", fakeMcsStore{deal: go_mcs_sdk.SourceFileUploadDeal{Status: "Failed"}, renewErr: errors.New("no funds")}, now.Add(-8 * day), now.Add(-8 * day), -1, "renewing", false, mcsDealPendingInterval},
+	}
+	for _, c := range cases {
+		a := newTestStoreService(t)
+		mcs := c.mcs
+		a.storeMap["mcs"] = &mcs
+
+		info := model.McsInfo{SaoModel: model.SaoModel{CreatedAt: c.createdAt}, SourceFileUploadId: 1, PaymentTxHash: "0xpayment", PaidAt: c.paidAt}
+		if err := a.m.DB.Create(&info).Error; err != nil {
+			t.Fatal(err)
+		}
+		file := model.FileInfo{McsInfoId: info.Id, ExpireAt: c.expireAt}
+		if err := a.m.DB.Create(&file).Error; err != nil {
+			t.Fatal(err)
+		}
+
+		start := time.Now()
+		err := a.checkMcsDeal(context.Background(), &info)
+		if c.err == "" && err != nil || c.err != "" && (err == nil || !strings.Contains(err.Error(), c.err)) {
+			t.Errorf("%s: got error %v, want %q", c.name, err, c.err)
+		}
+
+		stored, err := a.m.GetMcsInfoById(info.Id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if renewed := mcs.renewed > 0; renewed != c.renewed {
+			t.Errorf("%s: renewed %v, want %v", c.name, renewed, c.renewed)
+		}
+		if c.renewed && (stored.PaymentTxHash != "0xrenewal" || stored.PaidAt.Before(start) || stored.DealId != 0 || stored.DealStatus != "") {
+			t.Errorf("%s: renewal by %s at %v, following deal %d %q", c.name, stored.PaymentTxHash, stored.PaidAt, stored.DealId, stored.DealStatus)
+		}
+		if !c.renewed && (stored.PaymentTxHash != "0xpayment" || !stored.PaidAt.Equal(c.paidAt)) {
+			t.Errorf("%s: paid by %s at %v", c.name, stored.PaymentTxHash, stored.PaidAt)
+		}
+		if next := stored.NextDealCheckAt.Sub(start); next < c.next || next > c.next+time.Minute {
+			t.Errorf("%s: deal checked again in %v, want %v", c.name, next, c.next)
+		}
+
+		if c.mcs.deal.MinerFid != "" {
+			stored, err := a.m.GetFileInfoById(file.Id)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if stored.StorageProvider != c.mcs.deal.MinerFid {
+				t.Errorf("%s: stored by %q, want %q", c.name, stored.StorageProvider, c.mcs.deal.MinerFid)
+			}
+		}
+	}
+}
@@ -8,6 +8,8 @@ import (
 	"sao-datastore-storage/common"
 	go_mcs_sdk "sao-datastore-storage/go-mcs-sdk"
 	"sao-datastore-storage/model"
+	"strconv"
+	"time"
 )
 
 const MCS_DURATION = 525
@@ -49,18 +51,50 @@ func (s McsStore) StoreFile(ctx context.Context, reader io.Reader, info map[stri
 			return StoreRet{}, err
 		}
 		mcsInfo.PaymentTxHash = tx
+		mcsInfo.PaidAt = time.Now()
+		mcsInfo.NextDealCheckAt = mcsInfo.PaidAt
 	}
 	return StoreRet{
 		IpfsHash: mcsInfo.PayloadCid,
 		McsInfo: &mcsInfo,
+		Locator:  mcsInfo.IpfsUrl,
 	}, nil
 }
+// GetDeal returns the filecoin deal MCS made for the upload of mcsInfo.
+func (s McsStore) GetDeal(ctx context.Context, mcsInfo *model.McsInfo) (*go_mcs_sdk.SourceFileUploadDeal, error) {
+	return s.mcsClient.GetDealDetail(mcsInfo.SourceFileUploadId, mcsInfo.DealId)
+}
+
+// Renew pays again for the storage of the upload of mcsInfo, and returns the
+// payment transaction.
+func (s McsStore) Renew(ctx context.Context, mcsInfo *model.McsInfo) (string, error) {
+	return s.mcsClient.MakePayment(mcsInfo.WCid, mcsInfo.FileSize, MCS_DURATION)
+}
+
 func (s McsStore) GetFile(ctx context.Context, info map[string]string) (io.ReadCloser, error) {
-	resp, err := http.Get(info["hash"])
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, info["hash"], nil)
+	if err != nil {
+		return nil, err
+	}
+	if info["offset"] != "" {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%s-", info["offset"]))
+	}
+	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
 		return nil, err
 	}
 
+	if info["offset"] != "" && resp.StatusCode == http.StatusOK {
+		// the gateway ignored the range
+		offset, err := strconv.ParseInt(info["offset"], 10, 64)
+		if err == nil {
+			_, err = io.CopyN(io.Discard, resp.Body, offset)
+		}
+		if err != nil {
+			resp.Body.Close()
+			return nil, err
+		}
+	}
 	return resp.Body, nil
 }
 
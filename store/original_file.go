@@ -0,0 +1,273 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"sao-datastore-storage/model"
+	"sao-datastore-storage/node"
+	"sao-datastore-storage/proc"
+	"sao-datastore-storage/util/fileprocess"
+	"sort"
+	"time"
+)
+
+// OriginalFile is the original content of a stored file. It is opened at any
+// offset, so that it is served by ranges.
+type OriginalFile struct {
+	Info *model.FileInfo
+	Size int64
+	ETag string
+	open func(ctx context.Context, offset int64) (io.ReadCloser, error)
+}
+
+// NewReader returns a seekable reader of the file. The file is opened at the
+// read offset on the first read after a seek.
+func (f *OriginalFile) NewReader(ctx context.Context) *OriginalFileReader {
+	return &OriginalFileReader{ctx: ctx, file: f}
+}
+
+// OriginalFileReader reads an OriginalFile, see OriginalFile.NewReader.
+type OriginalFileReader struct {
+	ctx    context.Context
+	file   *OriginalFile
+	offset int64
+	rc     io.ReadCloser
+	// offset rc is at
+	rcOffset int64
+}
+
+// Open opens the file at the current offset, so that the errors of opening
+// it are returned before the response is written.
+func (r *OriginalFileReader) Open() error {
+	if r.rc != nil && r.rcOffset == r.offset {
+		return nil
+	}
+	if err := r.closeReader(); err != nil {
+		return err
+	}
+	if r.offset >= r.file.Size {
+		return nil
+	}
+
+	rc, err := r.file.open(r.ctx, r.offset)
+	if err != nil {
+		return err
+	}
+	r.rc = rc
+	r.rcOffset = r.offset
+	return nil
+}
+
+func (r *OriginalFileReader) Read(p []byte) (int, error) {
+	if r.offset >= r.file.Size {
+		return 0, io.EOF
+	}
+	if err := r.Open(); err != nil {
+		return 0, err
+	}
+
+	n, err := r.rc.Read(p)
+	r.offset += int64(n)
+	r.rcOffset = r.offset
+	return n, err
+}
+
+func (r *OriginalFileReader) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += r.offset
+	case io.SeekEnd:
+		offset += r.file.Size
+	default:
+		return 0, errors.New("invalid whence")
+	}
+	if offset < 0 {
+		return 0, errors.New("negative position")
+	}
+	r.offset = offset
+	return offset, nil
+}
+
+func (r *OriginalFileReader) Close() error {
+	return r.closeReader()
+}
+
+func (r *OriginalFileReader) closeReader() error {
+	if r.rc == nil {
+		return nil
+	}
+	err := r.rc.Close()
+	r.rc = nil
+	return err
+}
+
+// chunkReader decrypts the chunks of a paid file one at a time, as they are
+// read, and streams them straight out. The encrypted chunks missing from the
+// cache are sliced out of the stored file, opened at the first of them.
+type chunkReader struct {
+	ctx     context.Context
+	a       StoreService
+	fileId  uint
+	ethAddr string
+	// expiry of the auth message ethAddr signed
+	authExpiry time.Time
+	chunks     []model.FileChunkMetadata
+	// bytes of the current chunk before the read offset
+	skip       int64
+	openStored func(ctx context.Context, offset int64) (io.ReadCloser, error)
+	decrypt    func(ctx context.Context, splitFile fileprocess.SplitFileInfo, chunkMetadata model.FileChunkMetadata, outFilePath string) (string, error)
+
+	stored       io.ReadCloser
+	storedOffset int64
+
+	chunk     *os.File
+	chunkPath string
+	// the cached chunks are left in the cache once read
+	chunkCached bool
+}
+
+func (r *chunkReader) Read(p []byte) (int, error) {
+	for {
+		if r.chunk == nil {
+			if len(r.chunks) == 0 {
+				return 0, io.EOF
+			}
+			if err := r.nextChunk(); err != nil {
+				return 0, err
+			}
+		}
+
+		n, err := r.chunk.Read(p)
+		if err == io.EOF {
+			r.closeChunk()
+			if n == 0 {
+				continue
+			}
+			err = nil
+		}
+		return n, err
+	}
+}
+
+func (r *chunkReader) nextChunk() error {
+	chunkMetadata := r.chunks[0]
+	r.chunks = r.chunks[1:]
+
+	chunk, cached := r.a.chunkCache.get(r.fileId, chunkMetadata.Offset, r.ethAddr)
+	if !cached {
+		var err error
+		if chunk, cached, err = r.decryptChunk(chunkMetadata); err != nil {
+			return err
+		}
+	}
+
+	r.chunk = chunk
+	r.chunkPath = chunk.Name()
+	r.chunkCached = cached
+	if r.skip > 0 {
+		if _, err := r.chunk.Seek(r.skip, io.SeekStart); err != nil {
+			r.closeChunk()
+			return err
+		}
+		r.skip = 0
+	}
+	return nil
+}
+
+// decryptChunk has a proc node decrypt the chunk and caches it.
+func (r *chunkReader) decryptChunk(chunkMetadata model.FileChunkMetadata) (*os.File, bool, error) {
+	if r.stored != nil && r.storedOffset > chunkMetadata.EncryptedOffset {
+		r.stored.Close()
+		r.stored = nil
+	}
+	if r.stored == nil {
+		stored, err := r.openStored(r.ctx, chunkMetadata.EncryptedOffset)
+		if err != nil {
+			return nil, false, err
+		}
+		r.stored = stored
+		r.storedOffset = chunkMetadata.EncryptedOffset
+	}
+	// skip the cached chunks
+	if _, err := io.CopyN(io.Discard, r.stored, chunkMetadata.EncryptedOffset-r.storedOffset); err != nil {
+		return nil, false, err
+	}
+	r.storedOffset = chunkMetadata.EncryptedOffset
+
+	stagePath := node.StageProcPath(r.a.repodir)
+	encryptedChunk, err := os.CreateTemp(stagePath, "*"+proc.ENCRYPT_SUFFIX)
+	if err != nil {
+		return nil, false, err
+	}
+	encryptedChunk.Close()
+	defer os.Remove(encryptedChunk.Name())
+	if err = writeChunk(r.stored, encryptedChunk.Name(), chunkMetadata.EncryptedSize); err != nil {
+		return nil, false, err
+	}
+	r.storedOffset += chunkMetadata.EncryptedSize
+
+	splitFileInfo := fileprocess.SplitFileInfo{
+		FilePath: encryptedChunk.Name(),
+		Offset:   chunkMetadata.Offset,
+		Size:     chunkMetadata.EncryptedSize,
+	}
+	decryptFilePath, err := r.decrypt(r.ctx, splitFileInfo, chunkMetadata, encryptedChunk.Name()+DECRYPT_SUFFIX)
+	if err != nil {
+		return nil, false, err
+	}
+
+	decryptFilePath, cached := r.a.chunkCache.put(r.fileId, chunkMetadata.Offset, r.ethAddr, r.authExpiry, decryptFilePath)
+	chunk, err := os.Open(decryptFilePath)
+	if err != nil {
+		if !cached {
+			os.Remove(decryptFilePath)
+		}
+		return nil, false, err
+	}
+	return chunk, cached, nil
+}
+
+func (r *chunkReader) closeChunk() {
+	if r.chunk == nil {
+		return
+	}
+	r.chunk.Close()
+	if !r.chunkCached {
+		if err := os.Remove(r.chunkPath); err != nil {
+			log.Error(err)
+		}
+	}
+	r.chunk = nil
+}
+
+func (r *chunkReader) Close() error {
+	r.closeChunk()
+	if r.stored == nil {
+		return nil
+	}
+	return r.stored.Close()
+}
+
+// chunksFrom returns the chunks from the one holding offset, sorted by
+// offset.
+func chunksFrom(chunkMetadatas []model.FileChunkMetadata, offset int64) []model.FileChunkMetadata {
+	sort.Slice(chunkMetadatas, func(i, j int) bool {
+		return chunkMetadatas[i].Offset < chunkMetadatas[j].Offset
+	})
+	i := sort.Search(len(chunkMetadatas), func(i int) bool {
+		return chunkMetadatas[i].Offset+chunkMetadatas[i].Size > offset
+	})
+	return chunkMetadatas[i:]
+}
+
+// originalSize is the size of the decrypted file of chunkMetadatas.
+func originalSize(chunkMetadatas []model.FileChunkMetadata) int64 {
+	var size int64
+	for _, chunkMetadata := range chunkMetadatas {
+		size += chunkMetadata.Size
+	}
+	return size
+}
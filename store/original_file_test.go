@@ -0,0 +1,132 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"os"
+	"sao-datastore-storage/model"
+	"sao-datastore-storage/node"
+	"sao-datastore-storage/util/fileprocess"
+	"testing"
+	"time"
+)
+
+// newTestPaidFile returns the original file of chunks, stored "encrypted" as
+// each chunk between two '#', and counts the chunks decrypted.
+func newTestPaidFile(t *testing.T, a StoreService, chunks []string, decrypts *int) *OriginalFile {
+	var stored []byte
+	var chunkMetadatas []model.FileChunkMetadata
+	var offset int64
+	for _, chunk := range chunks {
+		chunkMetadatas = append(chunkMetadatas, model.FileChunkMetadata{
+			Offset:          offset,
+			Size:            int64(len(chunk)),
+			EncryptedOffset: int64(len(stored)),
+			EncryptedSize:   int64(len(chunk) + 2),
+		})
+		offset += int64(len(chunk))
+		stored = append(stored, "#"+chunk+"#"...)
+	}
+
+	authExpiry := time.Now().Add(time.Hour)
+	return &OriginalFile{
+		Size: originalSize(chunkMetadatas),
+		open: func(ctx context.Context, offset int64) (io.ReadCloser, error) {
+			chunks := chunksFrom(chunkMetadatas, offset)
+			if len(chunks) == 0 {
+				return nil, io.ErrUnexpectedEOF
+			}
+			return &chunkReader{
+				ctx:        ctx,
+				a:          a,
+				fileId:     1,
+				ethAddr:    "0x01",
+				authExpiry: authExpiry,
+				chunks:     chunks,
+				skip:       offset - chunks[0].Offset,
+				openStored: func(ctx context.Context, offset int64) (io.ReadCloser, error) {
+					return ioutil.NopCloser(bytes.NewReader(stored[offset:])), nil
+				},
+				decrypt: func(ctx context.Context, splitFile fileprocess.SplitFileInfo, chunkMetadata model.FileChunkMetadata, outFilePath string) (string, error) {
+					*decrypts++
+					encrypted, err := os.ReadFile(splitFile.FilePath)
+					if err != nil {
+						return "", err
+					}
+					if int64(len(encrypted)) != chunkMetadata.EncryptedSize {
+						t.Errorf("chunk at %d sliced to %q", chunkMetadata.Offset, encrypted)
+					}
+					return outFilePath, os.WriteFile(outFilePath, bytes.Trim(encrypted, "#"), 0644)
+				},
+			}, nil
+		},
+	}
+}
+
+func TestOriginalFileRanges(t *testing.T) {
+	chunks := []string{"abcdefghij", "klmnop", "qrstuvwxyz"}
+	content := "abcdefghijklmnopqrstuvwxyz"
+
+	cases := []struct {
+		name   string
+		offset int64
+		length int64
+		// bytes of decrypted chunks the cache holds
+		cacheSize int64
+		// chunks decrypted by the first and the second read of the range
+		decrypts      int
+		decryptsAgain int
+	}{
+		{"whole file", 0, 26, 0, 3, 0},
+		{"within a chunk", 2, 5, 0, 1, 0},
+		{"over a boundary", 8, 4, 0, 2, 0},
+		{"over every chunk", 5, 18, 0, 3, 0},
+		{"at a boundary", 10, 6, 0, 1, 0},
+		{"last byte", 25, 1, 0, 1, 0},
+		{"to the end", 12, 14, 0, 2, 0},
+		{"chunks larger than the cache", 8, 4, 5, 2, 2},
+		{"chunks fitting the cache", 12, 14, 16, 2, 0},
+		{"chunks evicting each other", 0, 26, 16, 3, 3},
+	}
+	for _, c := range cases {
+		repodir := t.TempDir()
+		if err := os.MkdirAll(node.StageProcPath(repodir), 0755); err != nil {
+			t.Fatal(err)
+		}
+		a := StoreService{repodir: repodir, chunkCache: newChunkCache(t.TempDir(), c.cacheSize, time.Hour)}
+		decrypts := 0
+		file := newTestPaidFile(t, a, chunks, &decrypts)
+
+		for i, want := range []int{c.decrypts, c.decryptsAgain} {
+			decrypts = 0
+			r := file.NewReader(context.Background())
+			if _, err := r.Seek(c.offset, io.SeekStart); err != nil {
+				t.Fatal(err)
+			}
+			got := make([]byte, c.length)
+			if _, err := io.ReadFull(r, got); err != nil {
+				t.Errorf("%s: read %d: %v", c.name, i, err)
+			}
+			if err := r.Close(); err != nil {
+				t.Errorf("%s: read %d: %v", c.name, i, err)
+			}
+			if string(got) != content[c.offset:c.offset+c.length] {
+				t.Errorf("%s: read %d: got %q, want %q", c.name, i, got, content[c.offset:c.offset+c.length])
+			}
+			if decrypts != want {
+				t.Errorf("%s: read %d: decrypted %d chunks, want %d", c.name, i, decrypts, want)
+			}
+
+			// the chunks left out of the cache are removed once read
+			staged, err := os.ReadDir(node.StageProcPath(repodir))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(staged) != 0 {
+				t.Errorf("%s: read %d: %d chunks left in the staging dir", c.name, i, len(staged))
+			}
+		}
+	}
+}
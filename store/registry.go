@@ -0,0 +1,78 @@
+package store
+
+import (
+	"fmt"
+	"path/filepath"
+	"sao-datastore-storage/common"
+	"strconv"
+	"sync"
+)
+
+// StoreFactory creates a Store from the params of its config, the relative
+// paths in params being under repodir.
+type StoreFactory func(params map[string]string, repodir string) (Store, error)
+
+var (
+	storeFactoriesLk sync.Mutex
+	storeFactories   = make(map[string]StoreFactory)
+)
+
+// RegisterStore makes a backend type available to the storage section of the
+// config.
+func RegisterStore(storeType string, factory StoreFactory) {
+	storeFactoriesLk.Lock()
+	defer storeFactoriesLk.Unlock()
+
+	storeFactories[storeType] = factory
+}
+
+// NewStore creates the store declared by info for the node of repodir.
+func NewStore(info common.StoreInfo, repodir string) (Store, error) {
+	storeFactoriesLk.Lock()
+	factory, ok := storeFactories[info.Type]
+	storeFactoriesLk.Unlock()
+
+	if !ok {
+		return nil, fmt.Errorf("store %s: unknown type %q", info.Name, info.Type)
+	}
+	store, err := factory(info.Params, repodir)
+	if err != nil {
+		return nil, fmt.Errorf("store %s: %w", info.Name, err)
+	}
+	return store, nil
+}
+
+func init() {
+	RegisterStore("ipfs", func(params map[string]string, repodir string) (Store, error) {
+		if params["url"] == "" {
+			return nil, fmt.Errorf("missing url")
+		}
+		if params["projectId"] != "" {
+			return NewIpfsStoreWithBasicAuth(params["url"], params["projectId"], params["projectSecret"]), nil
+		}
+		return NewIpfsStore(params["url"]), nil
+	})
+	RegisterStore("mcs", func(params map[string]string, repodir string) (Store, error) {
+		enableFilecoin, _ := strconv.ParseBool(params["enableFilecoin"])
+		return NewMcsStore(common.McsInfo{
+			McsEndpoint:     params["mcsEndpoint"],
+			StorageEndpoint: params["storageEndpoint"],
+			PrivateKey:      params["privateKey"],
+			EnableFilecoin:  enableFilecoin,
+			ProviderRpc:     params["providerRpc"],
+		}), nil
+	})
+	RegisterStore("s3", func(params map[string]string, repodir string) (Store, error) {
+		return NewS3Store(params)
+	})
+	RegisterStore("local", func(params map[string]string, repodir string) (Store, error) {
+		path := params["path"]
+		if path == "" {
+			path = "local-store"
+		}
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(repodir, path)
+		}
+		return NewLocalStore(path)
+	})
+}
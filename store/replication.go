@@ -0,0 +1,334 @@
+package store
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sao-datastore-storage/common"
+	"sao-datastore-storage/model"
+	"sort"
+	"strconv"
+	"time"
+)
+
+const defaultVerifyInterval = 24 * time.Hour
+
+// delay before a replica which could not be copied is tried again
+const replicaRetryDelay = 10 * time.Minute
+
+// replicas checked at most at once
+const replicaBatchSize = 100
+
+var errNoReplicaSource = errors.New("no stored replica to copy from")
+
+// replicationPolicies returns the stores of the policies of storage, the
+// default one by "".
+func replicationPolicies(storage common.StorageInfo, storeMap map[string]Store, defaultStore string) (map[string][]string, error) {
+	policies := map[string][]string{
+		"": {defaultStore},
+	}
+	for _, policy := range storage.Policies {
+		if _, ok := policies[policy.Name]; ok || policy.Name == "" {
+			return nil, fmt.Errorf("invalid or duplicated replication policy name %q", policy.Name)
+		}
+		if len(policy.Stores) == 0 {
+			return nil, fmt.Errorf("replication policy %s: no store", policy.Name)
+		}
+		seen := make(map[string]bool)
+		for _, storeName := range policy.Stores {
+			if storeMap[storeName] == nil || seen[storeName] {
+				return nil, fmt.Errorf("replication policy %s: unknown or duplicated store %q", policy.Name, storeName)
+			}
+			seen[storeName] = true
+		}
+		policies[policy.Name] = policy.Stores
+	}
+	if storage.DefaultPolicy != "" {
+		stores, ok := policies[storage.DefaultPolicy]
+		if !ok {
+			return nil, fmt.Errorf("no replication policy %q", storage.DefaultPolicy)
+		}
+		policies[""] = stores
+	}
+	return policies, nil
+}
+
+// HasPolicy tells whether files are stored with the replication policy, ""
+// being the default one.
+func (a StoreService) HasPolicy(policy string) bool {
+	_, ok := a.policies[policy]
+	return ok
+}
+
+// PolicyReplicas returns the number of replicas of a file stored with the
+// replication policy.
+func (a StoreService) PolicyReplicas(policy string) int {
+	if stores := a.policies[policy]; len(stores) > 0 {
+		return len(stores)
+	}
+	return 1
+}
+
+// addReplicas records the replicas of file on stores, the one it is stored
+// on and the others to copy it to.
+func (a StoreService) addReplicas(file *model.FileInfo, stores []string) error {
+	// the files stored before the backends were recorded are left as they are
+	if file.Backend == "" {
+		return nil
+	}
+
+	now := time.Now()
+	replicas := []model.FileReplica{{
+		FileId:      file.Id,
+		Backend:     file.Backend,
+		Locator:     file.Locator,
+		Status:      model.FileReplicaStored,
+		VerifiedAt:  now,
+		NextCheckAt: now.Add(a.verifyInterval()),
+	}}
+	for _, storeName := range stores {
+		if storeName == file.Backend {
+			continue
+		}
+		replicas = append(replicas, model.FileReplica{
+			FileId:      file.Id,
+			Backend:     storeName,
+			Status:      model.FileReplicaPending,
+			NextCheckAt: now,
+		})
+	}
+	return a.m.AddFileReplicas(replicas)
+}
+
+// openReplicas opens the first replica which can be read at offset, the
+// stored ones first.
+func (a StoreService) openReplicas(ctx context.Context, replicas []model.FileReplica, offset int64) (io.ReadCloser, error) {
+	sort.SliceStable(replicas, func(i, j int) bool {
+		return replicas[i].Status == model.FileReplicaStored && replicas[j].Status != model.FileReplicaStored
+	})
+
+	err := errNoReplicaSource
+	for i := range replicas {
+		if replicas[i].Locator == "" {
+			continue
+		}
+		var reader io.ReadCloser
+		reader, err = a.openReplica(ctx, &replicas[i], offset)
+		if err == nil {
+			return reader, nil
+		}
+		log.Warnf("file %d: failed to read replica on %s: %v", replicas[i].FileId, replicas[i].Backend, err)
+	}
+	return nil, err
+}
+
+func (a StoreService) openReplica(ctx context.Context, replica *model.FileReplica, offset int64) (io.ReadCloser, error) {
+	store, ok := a.storeMap[replica.Backend]
+	if !ok {
+		return nil, fmt.Errorf("unknown store %q", replica.Backend)
+	}
+	return getFile(ctx, store, replica.Locator, offset)
+}
+
+func (a StoreService) deleteReplicas(ctx context.Context, replicas []model.FileReplica) error {
+	var lastErr error
+	for i := range replicas {
+		if err := a.deleteReplica(ctx, &replicas[i]); err != nil {
+			log.Warn("delete file error: ", err)
+			lastErr = err
+			continue
+		}
+		if err := a.m.DeleteFileReplica(&replicas[i]); err != nil {
+			lastErr = err
+		}
+	}
+	return lastErr
+}
+
+func (a StoreService) deleteReplica(ctx context.Context, replica *model.FileReplica) error {
+	if replica.Locator == "" {
+		return nil
+	}
+	store, ok := a.storeMap[replica.Backend]
+	if !ok {
+		return fmt.Errorf("unknown store %q", replica.Backend)
+	}
+	return store.DeleteFile(ctx, map[string]string{
+		"hash": replica.Locator,
+	})
+}
+
+func (a StoreService) verifyInterval() time.Duration {
+	if a.config.Storage.VerifyInterval > 0 {
+		return time.Duration(a.config.Storage.VerifyInterval) * time.Second
+	}
+	return defaultVerifyInterval
+}
+
+// runReplicas copies the pending replicas and verifies the stored ones when
+// they are due, until ctx is done.
+func (a StoreService) runReplicas(ctx context.Context) {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+	for {
+		replicas, err := a.m.GetDueFileReplicas(time.Now(), replicaBatchSize)
+		if err != nil {
+			log.Error(err)
+		}
+		for i := range replicas {
+			if ctx.Err() != nil {
+				return
+			}
+			a.checkReplica(ctx, &replicas[i])
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// checkReplica verifies a stored replica, or copies a missing or failed one
+// from another replica.
+func (a StoreService) checkReplica(ctx context.Context, replica *model.FileReplica) {
+	file, err := a.m.GetFileInfoById(replica.FileId)
+	if err != nil {
+		log.Error(err)
+		return
+	}
+
+	if replica.Status == model.FileReplicaStored {
+		err = a.verifyReplica(ctx, file, replica)
+	} else {
+		err = a.copyReplica(ctx, file, replica)
+		// the replica may have failed for a while only
+		if errors.Is(err, errNoReplicaSource) && replica.Locator != "" {
+			err = a.verifyReplica(ctx, file, replica)
+		}
+	}
+	if ctx.Err() != nil {
+		return
+	}
+
+	now := time.Now()
+	if err != nil {
+		log.Warnf("file %d: replica on %s: %v", replica.FileId, replica.Backend, err)
+		replica.Error = err.Error()
+		if replica.Status == model.FileReplicaStored {
+			// copy it again right away
+			replica.Status = model.FileReplicaFailed
+			replica.NextCheckAt = now
+		} else {
+			replica.NextCheckAt = now.Add(replicaRetryDelay)
+		}
+	} else {
+		replica.Status = model.FileReplicaStored
+		replica.Error = ""
+		replica.VerifiedAt = now
+		replica.NextCheckAt = now.Add(a.verifyInterval())
+	}
+	if err = a.m.SaveFileReplica(replica); err != nil {
+		log.Error(err)
+	}
+}
+
+// verifyReplica reads the replica back and checks its hash.
+func (a StoreService) verifyReplica(ctx context.Context, file *model.FileInfo, replica *model.FileReplica) error {
+	reader, err := a.openReplica(ctx, replica, 0)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	hash := sha256.New()
+	size, err := io.Copy(hash, reader)
+	if err != nil {
+		return err
+	}
+	return checkStored(file, size, hex.EncodeToString(hash.Sum(nil)))
+}
+
+// copyReplica stores the file on the store of replica, read from one of the
+// stored replicas.
+func (a StoreService) copyReplica(ctx context.Context, file *model.FileInfo, replica *model.FileReplica) error {
+	store, ok := a.storeMap[replica.Backend]
+	if !ok {
+		return fmt.Errorf("unknown store %q", replica.Backend)
+	}
+	replicas, err := a.m.GetFileReplicas(file.Id)
+	if err != nil {
+		return err
+	}
+	var sources []model.FileReplica
+	for _, source := range replicas {
+		if source.Id != replica.Id && source.Status == model.FileReplicaStored {
+			sources = append(sources, source)
+		}
+	}
+	if len(sources) == 0 {
+		return errNoReplicaSource
+	}
+	reader, err := a.openReplicas(ctx, sources, 0)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	// replace the replica which failed
+	if err = a.deleteReplica(ctx, replica); err != nil {
+		log.Warn("delete file error: ", err)
+	}
+	replica.Locator = ""
+
+	hash := sha256.New()
+	counter := &countReader{Reader: io.TeeReader(reader, hash)}
+	ret, err := store.StoreFile(ctx, counter, map[string]string{
+		"filename": filepath.Base(file.Filename),
+		"size":     strconv.FormatInt(file.Size, 10),
+	})
+	if err != nil {
+		return err
+	}
+	replica.Locator = storedLocator(ret)
+	if ret.McsInfo != nil && file.McsInfoId == 0 {
+		ret.McsInfo.Backend = replica.Backend
+		if err = a.m.SetFileMcsInfo(file, ret.McsInfo); err != nil {
+			return err
+		}
+	}
+
+	if err = checkStored(file, counter.n, hex.EncodeToString(hash.Sum(nil))); err != nil {
+		return fmt.Errorf("copy: %w", err)
+	}
+	return nil
+}
+
+// checkStored checks the size and hash of the bytes read back from a
+// replica. The hash of the files stored before it was recorded is not
+// checked.
+func checkStored(file *model.FileInfo, size int64, sha256 string) error {
+	if file.Size > 0 && size != file.Size {
+		return fmt.Errorf("read %d bytes, expected %d", size, file.Size)
+	}
+	if file.Sha256 != "" && sha256 != file.Sha256 {
+		return fmt.Errorf("hash %s, expected %s", sha256, file.Sha256)
+	}
+	return nil
+}
+
+type countReader struct {
+	io.Reader
+	n int64
+}
+
+func (r *countReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	r.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,170 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"path/filepath"
+	"sao-datastore-storage/common"
+	"sao-datastore-storage/model"
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// newTestStoreService returns a store service on a sqlite database, storing
+// files on the local stores a, b and c.
+func newTestStoreService(t *testing.T) StoreService {
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "test.db")), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = db.AutoMigrate(&model.FileInfo{}, &model.FileReplica{}, &model.McsInfo{}); err != nil {
+		t.Fatal(err)
+	}
+
+	config := &common.Config{Storage: common.StorageInfo{
+		Default: "a",
+		Stores: []common.StoreInfo{
+			{Name: "a", Type: "local", Params: map[string]string{"path": "a"}},
+			{Name: "b", Type: "local", Params: map[string]string{"path": "b"}},
+			{Name: "c", Type: "local", Params: map[string]string{"path": "c"}},
+		},
+	}}
+	a, err := NewStoreService(config, &model.Model{DB: db}, nil, t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return a
+}
+
+func TestCheckReplica(t *testing.T) {
+	type replica struct {
+		backend string
+		status  model.FileReplicaStatus
+		// "file" if the store holds the file, "other" if it holds other
+		// bytes, "lost" if it lost it, "" if it was never stored
+		content string
+	}
+
+	cases := []struct {
+		name string
+		// the first replica is checked
+		replicas []replica
+		status   model.FileReplicaStatus
+		err      string
+		// the replica is checked again at once
+		now bool
+	}{
+		{"verified", []replica{{"a", model.FileReplicaStored, "file"}}, model.FileReplicaStored, "", false},
+		{"wrong hash", []replica{{"a", model.FileReplicaStored, "other"}}, model.FileReplicaFailed, "hash", true},
+		{"lost", []replica{{"a", model.FileReplicaStored, "lost"}}, model.FileReplicaFailed, "", true},
+		{"copied", []replica{
+			{"b", model.FileReplicaPending, ""},
+			{"a", model.FileReplicaStored, "file"},
+		}, model.FileReplicaStored, "", false},
+		{"failed replica copied again", []replica{
+			{"b", model.FileReplicaFailed, "other"},
+			{"a", model.FileReplicaStored, "file"},
+		}, model.FileReplicaStored, "", false},
+		{"copied from the replica left", []replica{
+			{"c", model.FileReplicaPending, ""},
+			{"a", model.FileReplicaStored, "lost"},
+			{"b", model.FileReplicaStored, "file"},
+		}, model.FileReplicaStored, "", false},
+		{"copied from a wrong replica", []replica{
+			{"b", model.FileReplicaPending, ""},
+			{"a", model.FileReplicaStored, "other"},
+		}, model.FileReplicaPending, "copy: hash", false},
+		{"no replica to copy from", []replica{
+			{"b", model.FileReplicaPending, ""},
+			{"a", model.FileReplicaFailed, "file"},
+		}, model.FileReplicaPending, errNoReplicaSource.Error(), false},
+		{"failed replica restored", []replica{
+			{"b", model.FileReplicaFailed, "file"},
+		}, model.FileReplicaStored, "", false},
+	}
+	for _, c := range cases {
+		a := newTestStoreService(t)
+		ctx := context.Background()
+
+		data := make([]byte, 1000)
+		rand.Read(data)
+		sum := sha256.Sum256(data)
+		file := model.FileInfo{Filename: "file", Size: int64(len(data)), Sha256: hex.EncodeToString(sum[:])}
+		if err := a.m.DB.Create(&file).Error; err != nil {
+			t.Fatal(err)
+		}
+
+		var replicas []model.FileReplica
+		for _, r := range c.replicas {
+			fileReplica := model.FileReplica{FileId: file.Id, Backend: r.backend, Status: r.status}
+			content := data
+			if r.content == "other" {
+				content = make([]byte, len(data))
+			}
+			if r.content != "" {
+				ret, err := a.storeMap[r.backend].StoreFile(ctx, bytes.NewReader(content), map[string]string{})
+				if err != nil {
+					t.Fatal(err)
+				}
+				fileReplica.Locator = storedLocator(ret)
+			}
+			if r.content == "lost" {
+				if err := a.deleteReplica(ctx, &fileReplica); err != nil {
+					t.Fatal(err)
+				}
+			}
+			replicas = append(replicas, fileReplica)
+		}
+		if err := a.m.AddFileReplicas(replicas); err != nil {
+			t.Fatal(err)
+		}
+		stored, err := a.m.GetFileReplicas(file.Id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var replica model.FileReplica
+		for _, r := range stored {
+			if r.Backend == c.replicas[0].backend {
+				replica = r
+			}
+		}
+
+		start := time.Now()
+		a.checkReplica(ctx, &replica)
+		if replica.Status != c.status {
+			t.Errorf("%s: replica %s, want %s: %s", c.name, replica.Status, c.status, replica.Error)
+		}
+		if c.err != "" && !strings.Contains(replica.Error, c.err) {
+			t.Errorf("%s: error %q, want %q", c.name, replica.Error, c.err)
+		}
+		if now := !replica.NextCheckAt.After(time.Now()); now != c.now {
+			t.Errorf("%s: checked again at %v, %v after the check", c.name, replica.NextCheckAt, replica.NextCheckAt.Sub(start))
+		}
+		if c.status != model.FileReplicaStored {
+			continue
+		}
+
+		// the stored replica is read back whole
+		if replica.Error != "" || !replica.VerifiedAt.After(start) {
+			t.Errorf("%s: replica verified at %v: %s", c.name, replica.VerifiedAt, replica.Error)
+		}
+		reader, err := a.openReplica(ctx, &replica, 0)
+		if err != nil {
+			t.Errorf("%s: %v", c.name, err)
+			continue
+		}
+		read, err := io.ReadAll(reader)
+		reader.Close()
+		if err != nil || !bytes.Equal(read, data) {
+			t.Errorf("%s: read %d bytes back: %v", c.name, len(read), err)
+		}
+	}
+}
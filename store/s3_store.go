@@ -0,0 +1,198 @@
+package store
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// an unsigned payload lets the file be streamed without hashing it first
+const s3UnsignedPayload = "UNSIGNED-PAYLOAD"
+
+// S3Store stores files as the objects of a bucket of an S3 compatible
+// service, e.g. MinIO. Objects are addressed path-style and the requests are
+// signed with AWS signature v4.
+type S3Store struct {
+	client    *http.Client
+	endpoint  *url.URL
+	bucket    string
+	region    string
+	prefix    string
+	accessKey string
+	secretKey string
+}
+
+// NewS3Store creates the store of params endpoint, bucket, accessKey,
+// secretKey, and optionally region (us-east-1 by default) and prefix, the
+// key prefix of the objects.
+func NewS3Store(params map[string]string) (S3Store, error) {
+	endpoint, err := url.Parse(params["endpoint"])
+	if err != nil {
+		return S3Store{}, err
+	}
+	if endpoint.Host == "" || params["bucket"] == "" {
+		return S3Store{}, fmt.Errorf("missing endpoint or bucket")
+	}
+	region := params["region"]
+	if region == "" {
+		region = "us-east-1"
+	}
+	return S3Store{
+		client:    http.DefaultClient,
+		endpoint:  endpoint,
+		bucket:    params["bucket"],
+		region:    region,
+		prefix:    params["prefix"],
+		accessKey: params["accessKey"],
+		secretKey: params["secretKey"],
+	}, nil
+}
+
+// StoreFile puts the file as a new object, info["size"] is its length if
+// known, otherwise the file is staged to a temp file first.
+func (s S3Store) StoreFile(ctx context.Context, reader io.Reader, info map[string]string) (StoreRet, error) {
+	size, err := strconv.ParseInt(info["size"], 10, 64)
+	if err != nil {
+		tmpFile, err := os.CreateTemp("", "s3-upload")
+		if err != nil {
+			return StoreRet{}, err
+		}
+		defer os.Remove(tmpFile.Name())
+		defer tmpFile.Close()
+
+		if size, err = io.Copy(tmpFile, reader); err != nil {
+			return StoreRet{}, err
+		}
+		if _, err = tmpFile.Seek(0, io.SeekStart); err != nil {
+			return StoreRet{}, err
+		}
+		reader = tmpFile
+	}
+
+	key := s.prefix + uuid.New().String()
+	req, err := s.newRequest(ctx, http.MethodPut, key, reader)
+	if err != nil {
+		return StoreRet{}, err
+	}
+	req.ContentLength = size
+	if size == 0 {
+		req.Body = http.NoBody
+	}
+	resp, err := s.do(req)
+	if err != nil {
+		return StoreRet{}, err
+	}
+	resp.Body.Close()
+
+	return StoreRet{
+		Locator: key,
+	}, nil
+}
+
+func (s S3Store) GetFile(ctx context.Context, info map[string]string) (io.ReadCloser, error) {
+	req, err := s.newRequest(ctx, http.MethodGet, info["hash"], nil)
+	if err != nil {
+		return nil, err
+	}
+	if info["offset"] != "" {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%s-", info["offset"]))
+	}
+	resp, err := s.do(req)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+func (s S3Store) DeleteFile(ctx context.Context, info map[string]string) error {
+	req, err := s.newRequest(ctx, http.MethodDelete, info["hash"], nil)
+	if err != nil {
+		return err
+	}
+	resp, err := s.do(req)
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+func (s S3Store) newRequest(ctx context.Context, method string, key string, body io.Reader) (*http.Request, error) {
+	u := *s.endpoint
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/" + s.bucket + "/" + key
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	signS3Request(req, s.accessKey, s.secretKey, s.region, time.Now())
+	return req, nil
+}
+
+func (s S3Store) do(req *http.Request) (*http.Response, error) {
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("s3 %s %s: %s %s", req.Method, req.URL.Path, resp.Status, msg)
+	}
+	return resp, nil
+}
+
+// signS3Request adds the AWS signature v4 authorization of req, signing the
+// host, x-amz-content-sha256 and x-amz-date headers.
+func signS3Request(req *http.Request, accessKey string, secretKey string, region string, now time.Time) {
+	amzDate := now.UTC().Format("20060102T150405Z")
+	date := amzDate[:8]
+	req.Header.Set("x-amz-date", amzDate)
+	req.Header.Set("x-amz-content-sha256", s3UnsignedPayload)
+
+	signedHeaders := "host;x-amz-content-sha256;x-amz-date"
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		"host:" + req.URL.Host,
+		"x-amz-content-sha256:" + s3UnsignedPayload,
+		"x-amz-date:" + amzDate,
+		"",
+		signedHeaders,
+		s3UnsignedPayload,
+	}, "\n")
+
+	scope := date + "/" + region + "/s3/aws4_request"
+	canonicalHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		hex.EncodeToString(canonicalHash[:]),
+	}, "\n")
+
+	key := []byte("AWS4" + secretKey)
+	for _, part := range []string{date, region, "s3", "aws4_request"} {
+		key = hmacSha256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSha256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		accessKey, scope, signedHeaders, signature))
+}
+
+func hmacSha256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
@@ -0,0 +1,83 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sao-datastore-storage/common"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestS3Store(t *testing.T) {
+	var lk sync.Mutex
+	objects := make(map[string][]byte)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=access/") {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		lk.Lock()
+		defer lk.Unlock()
+		switch r.Method {
+		case http.MethodPut:
+			data, _ := io.ReadAll(r.Body)
+			objects[r.URL.Path] = data
+		case http.MethodGet:
+			data, ok := objects[r.URL.Path]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(data))
+		case http.MethodDelete:
+			delete(objects, r.URL.Path)
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer server.Close()
+
+	store, err := NewStore(common.StoreInfo{
+		Name: "minio",
+		Type: "s3",
+		Params: map[string]string{
+			"endpoint":  server.URL,
+			"bucket":    "saods",
+			"accessKey": "access",
+			"secretKey": "secret",
+		},
+	}, t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	data := []byte("hello s3 store")
+	for _, info := range []map[string]string{{}, {"size": "14"}} {
+		ret, err := store.StoreFile(ctx, bytes.NewReader(data), info)
+		if err != nil {
+			t.Fatal("failed to store file", err)
+		}
+
+		reader, err := store.GetFile(ctx, map[string]string{"hash": ret.Locator, "offset": "6"})
+		if err != nil {
+			t.Fatal("failed to get file", err)
+		}
+		read, _ := io.ReadAll(reader)
+		reader.Close()
+		if !bytes.Equal(read, data[6:]) {
+			t.Fatalf("read %q", read)
+		}
+
+		if err = store.DeleteFile(ctx, map[string]string{"hash": ret.Locator}); err != nil {
+			t.Fatal("failed to delete file", err)
+		}
+		if _, err = store.GetFile(ctx, map[string]string{"hash": ret.Locator}); err == nil {
+			t.Fatal("deleted file is still readable")
+		}
+	}
+}
@@ -2,13 +2,14 @@ package store
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"github.com/shopspring/decimal"
 	"io"
-	"io/ioutil"
-	"math/rand"
+	"net/http"
 	"os"
 	"path/filepath"
 	"sao-datastore-storage/common"
@@ -20,6 +21,8 @@ import (
 	"sao-datastore-storage/util/transport"
 	"sao-datastore-storage/util/transport/httptransport"
 	"sao-datastore-storage/util/transport/types"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -31,11 +34,11 @@ import (
 
 var log = logging.Logger("store")
 
-const ORIGINAL_SUFFIX = ".original"
 const DECRYPT_SUFFIX = ".decrypt"
 
 // chain store interface.
-// ipfs, filecoin, arweave should implement.
+// ipfs, filecoin, arweave should implement. GetFile reads the file from the
+// byte offset in info["offset"] when it is set.
 type Store interface {
 	StoreFile(ctx context.Context, reader io.Reader, info map[string]string) (StoreRet, error)
 	GetFile(ctx context.Context, info map[string]string) (io.ReadCloser, error)
@@ -65,22 +68,42 @@ type PagedFileInfoInMarket struct {
 }
 
 type StoreService struct {
-	store     Store
-	storeMap  map[string]Store
+	storeMap map[string]Store
+	// stores of the replication policies by name, the default one by ""
+	policies  map[string][]string
 	m         *model.Model
 	host      host.Host
 	config    *common.Config
 	repodir   string
 	transport transport.Transport
+	// serve the file chunks to the proc nodes
+	stagedFiles *httptransport.StagedFileServer
+	stagedCars  *httptransport.StagedCarServer
+	// decrypted chunks of the paid files recently downloaded
+	chunkCache *chunkCache
 }
 
 type StoreRet struct {
 	IpfsHash string
 	McsInfo  *model.McsInfo
+	// where the store finds the file, IpfsHash if not set
+	Locator string
+}
+
+// WrappedChunkKey locates a chunk in the encrypted file and carries its file
+// key encrypted for the buyer.
+type WrappedChunkKey struct {
+	Offset          int64  `json:"offset"`
+	Size            int64  `json:"size"`
+	EncryptedOffset int64  `json:"encryptedOffset"`
+	EncryptedSize   int64  `json:"encryptedSize"`
+	WrappedKey      []byte `json:"wrappedKey"`
+	// format of the encrypted chunk, 0 for a chunk sealed whole
+	EncryptionVersion int `json:"encryptionVersion"`
 }
 
 func NewStoreService(config *common.Config, m *model.Model, host host.Host, repodir string) (StoreService, error) {
-	var store Store
+	storeName := ""
 	storeMap := make(map[string]Store)
 
 	// ipfs
@@ -88,29 +111,109 @@ func NewStoreService(config *common.Config, m *model.Model, host host.Host, repo
 		ipfsUrl := fmt.Sprintf("%s:%d", config.Ipfs.Ip, config.Ipfs.Port)
 		if config.Ipfs.ProjectId != "" {
 			// infura
-			store = NewIpfsStoreWithBasicAuth(ipfsUrl, config.Ipfs.ProjectId, config.Ipfs.ProjectSecret)
+			storeMap["ipfs"] = NewIpfsStoreWithBasicAuth(ipfsUrl, config.Ipfs.ProjectId, config.Ipfs.ProjectSecret)
 		} else {
 			// local
-			store = NewIpfsStore(ipfsUrl)
-			storeMap["ipfs"] = store
+			storeMap["ipfs"] = NewIpfsStore(ipfsUrl)
 		}
+		storeName = "ipfs"
 	}
 	storeMap["mcs"] = NewMcsStore(config.Mcs)
 	if config.Mcs.Enabled {
-		store = storeMap["mcs"]
+		storeName = "mcs"
+	}
+
+	for _, storeInfo := range config.Storage.Stores {
+		if _, ok := storeMap[storeInfo.Name]; ok || storeInfo.Name == "" {
+			return StoreService{}, fmt.Errorf("invalid or duplicated store name %q", storeInfo.Name)
+		}
+		store, err := NewStore(storeInfo, repodir)
+		if err != nil {
+			return StoreService{}, err
+		}
+		storeMap[storeInfo.Name] = store
+	}
+	if config.Storage.Default != "" {
+		storeName = config.Storage.Default
+	}
+	if storeMap[storeName] == nil {
+		return StoreService{}, fmt.Errorf("no store %q to store files on", storeName)
+	}
+	policies, err := replicationPolicies(config.Storage, storeMap, storeName)
+	if err != nil {
+		return StoreService{}, err
 	}
+
 	return StoreService{
-		store:     store,
-		storeMap: storeMap,
-		m:         m,
-		host:      host,
-		config:    config,
-		repodir:   repodir,
-		transport: httptransport.New(host),
+		storeMap:    storeMap,
+		policies:    policies,
+		m:           m,
+		host:        host,
+		config:      config,
+		repodir:     repodir,
+		transport:   httptransport.New(host),
+		stagedFiles: httptransport.NewStagedFileServer(node.StageProcPath(repodir), config.Transport.MaxTransferDuration*time.Second),
+		stagedCars:  httptransport.NewStagedCarServer(host, config.Transport.MaxTransferDuration*time.Second),
+		chunkCache:  newChunkCache(filepath.Join(node.StageProcPath(repodir), "cache"), config.FileProcess.DecryptCacheSize, time.Duration(config.FileProcess.DecryptCacheTTL)*time.Second),
+	}, nil
+}
+
+func (a StoreService) Start(ctx context.Context) error {
+	removeOriginalFiles(node.StageProcPath(a.repodir))
+	if err := a.chunkCache.start(ctx); err != nil {
+		return err
+	}
+	go a.runReplicas(ctx)
+	go a.runMcsDeals(ctx)
+	return a.stagedCars.Start(ctx)
+}
+
+func (a StoreService) Stop(ctx context.Context) error {
+	return a.stagedCars.Stop(ctx)
+}
+
+// StagedFileHandler serves the file chunks sent to the proc nodes, each one
+// only with the auth token created for its transfer.
+func (a StoreService) StagedFileHandler() http.Handler {
+	return a.stagedFiles
+}
+
+// chunkTransfer authorizes the proc node to fetch the file chunk, either from
+// the ds api server or as a CAR over libp2p.
+func (a StoreService) chunkTransfer(ctx context.Context, splitFile fileprocess.SplitFileInfo) (types.Transfer, error) {
+	if a.config.Transport.Type == "libp2p" {
+		return a.stagedCars.Authorize(ctx, splitFile.FilePath)
+	}
+
+	headers, err := a.stagedFiles.Authorize(ctx, splitFile.FilePath)
+	if err != nil {
+		return types.Transfer{}, err
+	}
+	transferParams := &types.HttpRequest{
+		URL:     a.config.ApiServer.ExposedPath + a.config.ApiServer.ContextPath + "/api/v1/proc/file/" + filepath.Base(splitFile.FilePath),
+		Headers: headers,
+	}
+
+	paramsBytes, err := json.Marshal(transferParams)
+	if err != nil {
+		return types.Transfer{}, xerrors.Errorf("marshalling request parameters: %v", err)
+	}
+	return types.Transfer{
+		Size:   uint64(splitFile.Size),
+		Type:   "http",
+		Params: paramsBytes,
 	}, nil
 }
 
-func (a StoreService) StoreFile(ctx context.Context, reader io.Reader, contentType string, size int64, dest string, duration int64, walletAddr string, filename string) (*model.FileInfo, error) {
+// StoreFile stores the file on the first store of the replication policy,
+// the other replicas are copied in the background. The file expires after
+// duration days, or never if duration is negative.
+func (a StoreService) StoreFile(ctx context.Context, reader io.Reader, contentType string, size int64, dest string, duration int64, walletAddr string, filename string, policy string) (*model.FileInfo, error) {
+	stores, ok := a.policies[policy]
+	if !ok {
+		return nil, fmt.Errorf("unknown replication policy %q", policy)
+	}
+
 	count, err := a.m.CountFileByFilenameAndStatus(dest, 0)
 	if err != nil {
 		return nil, err
@@ -122,17 +225,19 @@ func (a StoreService) StoreFile(ctx context.Context, reader io.Reader, contentTy
 	storeInfo := map[string]string{
 		"address":  walletAddr,
 		"filename": filename,
+		"size":     strconv.FormatInt(size, 10),
 	}
-	ret, err := a.store.StoreFile(ctx, reader, storeInfo)
+	hash := sha256.New()
+	ret, err := a.storeMap[stores[0]].StoreFile(ctx, io.TeeReader(reader, hash), storeInfo)
 	if err != nil {
 		return nil, err
 	}
 
 	// TODO: allow user to upload two same files?
-	expireAt := time.Now().UnixMilli()
-	if duration < 0 {
-		// -1 - forever
-		expireAt = -1
+	// -1 - forever
+	expireAt := int64(-1)
+	if duration >= 0 {
+		expireAt = time.Now().Add(time.Duration(duration) * 24 * time.Hour).UnixMilli()
 	}
 
 	file := model.FileInfo{
@@ -141,209 +246,337 @@ func (a StoreService) StoreFile(ctx context.Context, reader io.Reader, contentTy
 		Size:        size,
 		ExpireAt:    expireAt,
 		Status:      0,
+		Backend:     stores[0],
+		Locator:     storedLocator(ret),
+		Sha256:      hex.EncodeToString(hash.Sum(nil)),
 	}
 	if ret.IpfsHash != "" {
 		file.IpfsHash = ret.IpfsHash
 	}
+	if ret.McsInfo != nil {
+		ret.McsInfo.Backend = stores[0]
+	}
 	returnFile, err := a.m.StoreFile(file, ret.McsInfo)
 	if err != nil {
 		return nil, err
 	}
+	if err = a.addReplicas(returnFile, stores); err != nil {
+		return nil, err
+	}
 
 	return returnFile, nil
 }
 
-func (a StoreService) EncryptFileChunk(ctx context.Context, preview *model.FilePreview, splitFile fileprocess.SplitFileInfo, offset int64) (string, uint64, error) {
-	peerIds := a.host.Peerstore().Peers()
-	tryTime := 50
-	var peerId peer.ID
-	for findPeer := false; findPeer == false && tryTime > 0; tryTime-- {
-		randomIndex := rand.Intn(len(peerIds))
-		peerId = peerIds[randomIndex]
-		for _, configPeer := range a.config.Libp2p.DirectPeers {
-			if strings.Contains(configPeer, peerId.String()) {
-				findPeer = true
-				break
-			}
+// ProcPeers returns the proc nodes configured in Libp2p.DirectPeers.
+func (a StoreService) ProcPeers() ([]peer.ID, error) {
+	var peerIds []peer.ID
+	for _, configPeer := range a.config.Libp2p.DirectPeers {
+		addrInfo, err := peer.AddrInfoFromString(configPeer)
+		if err != nil {
+			return nil, xerrors.Errorf("invalid direct peer %s: %w", configPeer, err)
 		}
+		peerIds = append(peerIds, addrInfo.ID)
 	}
-	addrInfo := a.host.Peerstore().PeerInfo(peerId)
+	if len(peerIds) == 0 {
+		return nil, errors.New("no proc node configured")
+	}
+	return peerIds, nil
+}
 
-	transferParams := &types.HttpRequest{URL: a.config.ApiServer.ExposedPath + a.config.ApiServer.ContextPath + "/api/v1/proc/file/" + filepath.Base(splitFile.FilePath)}
+// downloadAuthValidity is how long a buyer has to download a paid file with
+// the auth message it signed.
+const downloadAuthValidity = 30 * time.Minute
 
-	paramsBytes, err := json.Marshal(transferParams)
+// DownloadAuthMessage returns the message the buyer signs to download the
+// paid file fileId, which the proc nodes check before decrypting it.
+func (a StoreService) DownloadAuthMessage(fileId uint) (string, error) {
+	peerIds, err := a.ProcPeers()
 	if err != nil {
-		return "", 0, xerrors.Errorf("marshalling request parameters: %v", err)
+		return "", err
 	}
-	transfer := types.Transfer{
-		Size:   uint64(splitFile.Size),
-		Type:   "http",
-		Params: paramsBytes,
+	var procPeers []string
+	for _, peerId := range peerIds {
+		procPeers = append(procPeers, peerId.String())
 	}
+	return proc.AuthMessage(fmt.Sprintf("%d", fileId), procPeers, time.Now().Add(downloadAuthValidity)), nil
+}
 
-	s, err := a.host.NewStream(ctx, addrInfo.ID, proc.FileProcessEncryptionProtocolDraft)
+// EncryptFileChunk asks peerId to encrypt the chunk and split its key between
+// sharePeers, threshold of which are required to decrypt it again. progress,
+// if not nil, follows the transfer of the encrypted chunk back.
+func (a StoreService) EncryptFileChunk(ctx context.Context, preview *model.FilePreview, splitFile fileprocess.SplitFileInfo, peerId peer.ID, sharePeers []peer.ID, threshold int, progress fileprocess.ProgressFunc) (string, *proc.FileEncryptResp, error) {
+	addrInfo := a.host.Peerstore().PeerInfo(peerId)
+
+	transfer, err := a.chunkTransfer(ctx, splitFile)
 	if err != nil {
-		return "", transfer.Size, xerrors.Errorf("failed to open stream to peer %s: %w", addrInfo.ID, err)
+		return "", nil, xerrors.Errorf("authorizing chunk transfer: %w", err)
+	}
+
+	s, format, err := proc.NewStream(ctx, a.host, addrInfo.ID, proc.FileProcessEncryptionProtocols)
+	if err != nil {
+		return "", nil, xerrors.Errorf("failed to open stream to peer %s: %w", addrInfo.ID, err)
 	}
 	defer s.Close()
 
+	var sharePeerIds []string
+	for _, sharePeer := range sharePeers {
+		sharePeerIds = append(sharePeerIds, sharePeer.String())
+	}
+
 	req := proc.FileEncryptReq{
-		FileId:   fmt.Sprintf("%d", preview.Id),
-		ClientId: preview.EthAddr,
-		Offset:   uint64(offset),
-		Size:     uint64(splitFile.Size),
-		Transfer: transfer,
+		FileId:     fmt.Sprintf("%d", preview.Id),
+		ClientId:   preview.EthAddr,
+		Offset:     uint64(splitFile.Offset),
+		Size:       uint64(splitFile.Size),
+		Transfer:   transfer,
+		SharePeers: sharePeerIds,
+		Threshold:  threshold,
 	}
 
 	var resp proc.FileEncryptResp
-	if err = util.DoRpc(ctx, s, &req, &resp, "json"); err != nil {
-		return "", transfer.Size, xerrors.Errorf("send proposal rpc: %w", err)
+	if err = util.DoRpc(ctx, s, &req, &resp, format); err != nil {
+		return "", nil, xerrors.Errorf("send proposal rpc: %w", err)
 	}
 
 	if resp.Accepted {
-		outFilePath := filepath.Join(node.StageProcPath(a.repodir), fmt.Sprintf("%s.encrypt", filepath.Base(splitFile.FilePath)))
-		err = fileprocess.TransferFile(ctx, a.transport, resp.Transfer, req.FileId, outFilePath)
+		outFilePath := a.EncryptedChunkPath(splitFile)
+		err = fileprocess.TransferFileWithProgress(ctx, a.transport, resp.Transfer, req.FileId, outFilePath, progress)
 		if err != nil {
-			return "", transfer.Size, xerrors.Errorf("transfer file error: %w", err)
+			return "", nil, xerrors.Errorf("transfer file error: %w", err)
 		}
-		return outFilePath, resp.Transfer.Size, nil
+		return outFilePath, &resp, nil
 	} else {
-		return "", transfer.Size, xerrors.Errorf("failed to get encrypted file chunk from peer: %w", err)
+		return "", nil, xerrors.Errorf("failed to get encrypted file chunk from peer: %w", err)
 	}
 }
 
-func(a StoreService) DeleteFile(ctx context.Context, ipfsHash string) error {
-	err := a.store.DeleteFile(ctx, map[string]string{
-		"hash": ipfsHash,
+// EncryptedChunkPath returns where the encrypted splitFile is received.
+func (a StoreService) EncryptedChunkPath(splitFile fileprocess.SplitFileInfo) string {
+	return filepath.Join(node.StageProcPath(a.repodir), fmt.Sprintf("%s.encrypt", filepath.Base(splitFile.FilePath)))
+}
+
+// DeleteFile deletes the replicas of the file from their stores, and the CAR
+// prepared for its deals.
+func (a StoreService) DeleteFile(ctx context.Context, file *model.FileInfo) error {
+	replicas, err := a.m.GetFileReplicas(file.Id)
+	if err != nil {
+		return err
+	}
+	if len(replicas) > 0 {
+		if err = a.deleteReplicas(ctx, replicas); err != nil {
+			return err
+		}
+		a.removeDealCar(file)
+		return nil
+	}
+
+	store, locator, err := a.fileStore(file)
+	if err != nil {
+		return err
+	}
+	err = store.DeleteFile(ctx, map[string]string{
+		"hash": locator,
 	})
 	if err != nil {
 		log.Warn("delete file error: ", err)
 		return err
 	}
+	a.removeDealCar(file)
 	return nil
 }
 
-func (a StoreService) GetFile(ctx context.Context, previewId uint, ethAddr string) (*model.FileInfo, io.ReadCloser, error) {
+// GetFile returns the original file, paid files are decrypted by the proc
+// nodes which check the signature proves ethAddr owns or bought the file.
+// Only the chunks covering the bytes read are fetched and decrypted.
+func (a StoreService) GetFile(ctx context.Context, previewId uint, ethAddr string, signature string, signatureMessage string) (*OriginalFile, error) {
 	filePreview, err := a.m.GetFilePreviewById(previewId)
 	if err != nil {
-		return nil, nil, err
+		return nil, err
 	}
 
 	file := a.m.GetFileInfoByPreviewId(previewId)
-	ipfsHash := file.IpfsHash
+	originalFile := &OriginalFile{
+		Info: file,
+		Size: file.Size,
+		ETag: fmt.Sprintf("\"%s\"", file.IpfsHash),
+	}
+	if filePreview.Sha256 != "" {
+		originalFile.ETag = fmt.Sprintf("\"%s\"", filePreview.Sha256)
+	}
 
-	var storeService Store
-	if file.McsInfoId > 0 {
-		mcsInfo, err := a.m.GetMcsInfoById(file.McsInfoId)
-		if err != nil {
-			return file, nil, errors.New("missing ipfs hash")
+	willDecrypt := filePreview.Price.Cmp(decimal.NewFromInt(0)) > 0
+	if !willDecrypt {
+		originalFile.open = func(ctx context.Context, offset int64) (io.ReadCloser, error) {
+			return a.getStoredFile(ctx, file, offset)
 		}
-		ipfsHash = mcsInfo.IpfsUrl
-		storeService = a.storeMap["mcs"]
-	} else {
-		storeService = a.storeMap["ipfs"]
+		return originalFile, nil
 	}
 
-	filePath := filepath.Join(node.StageProcPath(a.repodir), filepath.Base(file.Filename))
-	var originalFile *os.File
-	if _, err = os.Stat(filePath + ORIGINAL_SUFFIX); errors.Is(err, os.ErrNotExist) {
-		read, err := storeService.GetFile(ctx, map[string]string{
-			"hash": ipfsHash,
-		})
-		if err != nil {
-			return nil, nil, err
+	fileChunkMetadatas := a.m.GetFileChunkMetadatasByFileId(previewId)
+	auth := proc.ClientAuth{
+		TokenId:          filePreview.NftTokenId,
+		Signature:        signature,
+		SignatureMessage: signatureMessage,
+	}
+	// the proc nodes refuse an invalid auth message, the zero expiry keeps
+	// the cache closed to it
+	authExpiry, _ := proc.AuthMessageExpiry(signatureMessage)
+	originalFile.Size = originalSize(fileChunkMetadatas)
+	originalFile.open = func(ctx context.Context, offset int64) (io.ReadCloser, error) {
+		chunks := chunksFrom(fileChunkMetadatas, offset)
+		if len(chunks) == 0 {
+			return nil, io.ErrUnexpectedEOF
 		}
-
-		willDecrypt := filePreview.Price.Cmp(decimal.NewFromInt(0)) > 0
-		if willDecrypt {
-			defer read.Close()
-			fileChunkMetadatas := a.m.GetFileChunkMetadatasByFileId(previewId)
-
-			var splitFileInfos []fileprocess.SplitFileInfo
-
-			for i, fileChunkMetadata := range fileChunkMetadatas {
-				partBuffer := make([]byte, fileChunkMetadata.EncryptedSize)
-
-				read.Read(partBuffer)
-
-				// write to disk
-				splitFilePath := fmt.Sprintf("%s_%d.encrypt", filePath, i)
-				f, err := os.Create(splitFilePath)
-				if err != nil {
-					return nil, nil, err
-				}
-
-				// write/save buffer to disk
-				err = ioutil.WriteFile(splitFilePath, partBuffer, os.ModeAppend)
-				if err != nil {
-					return nil, nil, err
-				}
-
-				func() {
-					defer f.Close()
-				}()
-
-				log.Info("Split to:", splitFilePath)
-
-				splitFileInfos = append(splitFileInfos, fileprocess.SplitFileInfo{
-					FilePath: splitFilePath,
-					Offset:   fileChunkMetadata.EncryptedOffset,
-					Size:     fileChunkMetadata.EncryptedSize,
-				})
-			}
-
-			var decryptFilePaths []string
-			for i, splitFileInfo := range splitFileInfos {
-				outFilePath := filepath.Join(node.StageProcPath(a.repodir), fmt.Sprintf("%s_%d%s", filepath.Base(filePath), i, DECRYPT_SUFFIX))
-				decryptFilePath, err := a.decryptFileChunk(ctx, splitFileInfo, ethAddr, previewId, outFilePath)
-				if err != nil {
-					return nil, nil, err
-				}
-				decryptFilePaths = append(decryptFilePaths, decryptFilePath)
-			}
-
-			log.Infof("start combining decrypted chunks into %s", filePath+ORIGINAL_SUFFIX)
-			if err = fileprocess.CombineFile(decryptFilePaths, filePath+ORIGINAL_SUFFIX); err != nil {
-				return nil, nil, err
-			}
-
-			log.Infof("finished combining decrypted chunks into %s", filePath+ORIGINAL_SUFFIX)
-
-			originalFile, _ = os.Open(filePath + ORIGINAL_SUFFIX)
-			if err != nil {
-				return nil, nil, err
-			}
-
-			return file, originalFile, err
-		} else {
-			return file, read, nil
+		r := &chunkReader{
+			ctx:        ctx,
+			a:          a,
+			fileId:     previewId,
+			ethAddr:    ethAddr,
+			authExpiry: authExpiry,
+			chunks:     chunks,
+			skip:       offset - chunks[0].Offset,
+			openStored: func(ctx context.Context, offset int64) (io.ReadCloser, error) {
+				return a.getStoredFile(ctx, file, offset)
+			},
+			decrypt: func(ctx context.Context, splitFile fileprocess.SplitFileInfo, chunkMetadata model.FileChunkMetadata, outFilePath string) (string, error) {
+				return a.decryptFileChunk(ctx, splitFile, chunkMetadata, ethAddr, auth, previewId, outFilePath)
+			},
 		}
-	} else {
-		originalFile, _ = os.Open(filePath + ORIGINAL_SUFFIX)
+		// decrypt the first chunk now, a failure is returned before the
+		// response is written
+		if err := r.nextChunk(); err != nil {
+			r.Close()
+			return nil, err
+		}
+		return r, nil
+	}
+	return originalFile, nil
+}
+
+// GetEncryptedFile returns the file as stored, without decrypting it, along
+// with the chunk keys wrapped for the buyer's public key which is recovered
+// from the signature.
+func (a StoreService) GetEncryptedFile(ctx context.Context, previewId uint, ethAddr string, signature string, signatureMessage string) (*model.FileInfo, []WrappedChunkKey, io.ReadCloser, error) {
+	filePreview, err := a.m.GetFilePreviewById(previewId)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if filePreview.Price.Cmp(decimal.NewFromInt(0)) <= 0 {
+		return nil, nil, nil, errors.New("file is not encrypted")
+	}
+
+	file := a.m.GetFileInfoByPreviewId(previewId)
+	auth := proc.ClientAuth{
+		TokenId:          filePreview.NftTokenId,
+		Signature:        signature,
+		SignatureMessage: signatureMessage,
+	}
+
+	var chunkKeys []WrappedChunkKey
+	for _, fileChunkMetadata := range a.m.GetFileChunkMetadatasByFileId(previewId) {
+		wrapped, err := a.wrapFileChunkKey(ctx, fileChunkMetadata, ethAddr, auth)
 		if err != nil {
-			return nil, nil, err
+			return nil, nil, nil, err
 		}
-		return file, originalFile, err
+		chunkKeys = append(chunkKeys, WrappedChunkKey{
+			Offset:            fileChunkMetadata.Offset,
+			Size:              fileChunkMetadata.Size,
+			EncryptedOffset:   fileChunkMetadata.EncryptedOffset,
+			EncryptedSize:     fileChunkMetadata.EncryptedSize,
+			WrappedKey:        wrapped.WrappedKey,
+			EncryptionVersion: wrapped.EncryptionVersion,
+		})
 	}
+
+	read, err := a.getStoredFile(ctx, file, 0)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return file, chunkKeys, read, nil
 }
 
-func (a StoreService) decryptFileChunk(ctx context.Context, splitFile fileprocess.SplitFileInfo, ethAddr string, fileId uint, outFilePath string) (string, error) {
-	peerIds := a.host.Peerstore().Peers()
-	for _, peerId := range peerIds {
-		findPeer := false
-		for _, configPeer := range a.config.Libp2p.DirectPeers {
-			if strings.Contains(configPeer, peerId.String()) {
-				findPeer = true
-				break
-			}
+// fileStore returns the store of file, and where the store finds it.
+func (a StoreService) fileStore(file *model.FileInfo) (Store, string, error) {
+	if file.Backend != "" {
+		store, ok := a.storeMap[file.Backend]
+		if !ok {
+			return nil, "", fmt.Errorf("unknown store %q", file.Backend)
 		}
+		return store, file.Locator, nil
+	}
 
-		if !findPeer {
-			log.Debugf("peer id %s is not in config file", peerId)
-			continue
+	if file.McsInfoId > 0 {
+		mcsInfo, err := a.m.GetMcsInfoById(file.McsInfoId)
+		if err != nil {
+			return nil, "", errors.New("missing ipfs hash")
 		}
+		return a.storeMap["mcs"], mcsInfo.IpfsUrl, nil
+	}
+	store, ok := a.storeMap["ipfs"]
+	if !ok {
+		return nil, "", errors.New("ipfs store is not configured")
+	}
+	return store, file.IpfsHash, nil
+}
 
-		decryptedChunkPath, err, done := a.tryDecryptFromPeer(ctx, splitFile, ethAddr, fileId, outFilePath, peerId)
+// getStoredFile reads the file as stored, from offset. The replicas are
+// tried in turn, the ones last verified first.
+func (a StoreService) getStoredFile(ctx context.Context, file *model.FileInfo, offset int64) (io.ReadCloser, error) {
+	replicas, err := a.m.GetFileReplicas(file.Id)
+	if err != nil {
+		return nil, err
+	}
+	if len(replicas) > 0 {
+		return a.openReplicas(ctx, replicas, offset)
+	}
+
+	store, locator, err := a.fileStore(file)
+	if err != nil {
+		return nil, err
+	}
+	return getFile(ctx, store, locator, offset)
+}
+
+func getFile(ctx context.Context, store Store, locator string, offset int64) (io.ReadCloser, error) {
+	info := map[string]string{
+		"hash": locator,
+	}
+	if offset > 0 {
+		info["offset"] = strconv.FormatInt(offset, 10)
+	}
+	return store.GetFile(ctx, info)
+}
+
+// storedLocator is where the store finds the file it returned ret for.
+func storedLocator(ret StoreRet) string {
+	if ret.Locator != "" {
+		return ret.Locator
+	}
+	return ret.IpfsHash
+}
+
+func writeChunk(reader io.Reader, chunkPath string, size int64) error {
+	f, err := os.Create(chunkPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = io.CopyN(f, reader, size)
+	return err
+}
+
+func (a StoreService) decryptFileChunk(ctx context.Context, splitFile fileprocess.SplitFileInfo, chunkMetadata model.FileChunkMetadata, ethAddr string, auth proc.ClientAuth, fileId uint, outFilePath string) (string, error) {
+	peerIds, err := a.ProcPeers()
+	if err != nil {
+		return "", err
+	}
+
+	sharePeers := orderKeyPeers(peerIds, chunkMetadata)
+	// the proc nodes look the chunk key up by the offset of the split file
+	splitFile.Offset = keyOffset(chunkMetadata)
+	for _, peerId := range peerIds {
+		decryptedChunkPath, err, done := a.tryDecryptFromPeer(ctx, splitFile, ethAddr, auth, fileId, outFilePath, peerId, sharePeers, chunkMetadata.Threshold)
 		if done {
 			return decryptedChunkPath, err
 		}
@@ -355,36 +588,103 @@ func (a StoreService) decryptFileChunk(ctx context.Context, splitFile fileproces
 	return "", errors.New("missing decrypt file part")
 }
 
-func (a StoreService) tryDecryptFromPeer(ctx context.Context, splitFile fileprocess.SplitFileInfo, ethAddr string, fileId uint, outFilePath string, peerId peer.ID) (string, error, bool) {
-	addrInfo := a.host.Peerstore().PeerInfo(peerId)
-	transferParams := &types.HttpRequest{URL: a.config.ApiServer.ExposedPath + a.config.ApiServer.ContextPath + "/api/v1/proc/file/" + filepath.Base(splitFile.FilePath)}
+// orderKeyPeers moves the proc nodes holding the chunk key or its shares to
+// the front, any of them is able to gather the other shares. It returns the
+// share holders of the chunk.
+func orderKeyPeers(peerIds []peer.ID, chunkMetadata model.FileChunkMetadata) []string {
+	var sharePeers []string
+	if chunkMetadata.SharePeers != "" {
+		sharePeers = strings.Split(chunkMetadata.SharePeers, ",")
+	}
+	keyPeers := append([]string{chunkMetadata.PeerId}, sharePeers...)
+	sort.SliceStable(peerIds, func(i, j int) bool {
+		return isKeyPeer(peerIds[i], keyPeers) && !isKeyPeer(peerIds[j], keyPeers)
+	})
+	return sharePeers
+}
 
-	paramsBytes, err := json.Marshal(transferParams)
+// keyOffset returns the offset the proc nodes store the chunk key under.
+// Chunks encrypted before the key shares, which have no PeerId, were keyed by
+// their offset in the encrypted file.
+func keyOffset(chunkMetadata model.FileChunkMetadata) int64 {
+	if chunkMetadata.PeerId == "" {
+		return chunkMetadata.EncryptedOffset
+	}
+	return chunkMetadata.Offset
+}
+
+func (a StoreService) wrapFileChunkKey(ctx context.Context, chunkMetadata model.FileChunkMetadata, ethAddr string, auth proc.ClientAuth) (*proc.FileKeyWrapResp, error) {
+	peerIds, err := a.ProcPeers()
 	if err != nil {
-		return "", xerrors.Errorf("marshalling request parameters: %v", err), true
+		return nil, err
 	}
-	transfer := types.Transfer{
-		Size:   uint64(splitFile.Size),
-		Type:   "http",
-		Params: paramsBytes,
+
+	req := proc.FileKeyWrapReq{
+		FileId:     fmt.Sprintf("%d", chunkMetadata.FileId),
+		ClientId:   ethAddr,
+		Offset:     uint64(keyOffset(chunkMetadata)),
+		Size:       uint64(chunkMetadata.EncryptedSize),
+		SharePeers: orderKeyPeers(peerIds, chunkMetadata),
+		Threshold:  chunkMetadata.Threshold,
+		Auth:       auth,
+	}
+	for _, peerId := range peerIds {
+		s, format, err := proc.NewStream(ctx, a.host, peerId, proc.FileKeyWrapProtocols)
+		if err != nil {
+			log.Errorf("failed to open stream to peer %s: %v", peerId, err)
+			continue
+		}
+
+		var resp proc.FileKeyWrapResp
+		err = util.DoRpc(ctx, s, &req, &resp, format)
+		s.Close()
+		if err != nil {
+			log.Errorf("send key wrap rpc: %v", err)
+			continue
+		}
+		if resp.Accepted {
+			return &resp, nil
+		}
+	}
+
+	return nil, errors.New("missing file chunk key")
+}
+
+func isKeyPeer(peerId peer.ID, keyPeers []string) bool {
+	for _, keyPeer := range keyPeers {
+		if peerId.String() == keyPeer {
+			return true
+		}
+	}
+	return false
+}
+
+func (a StoreService) tryDecryptFromPeer(ctx context.Context, splitFile fileprocess.SplitFileInfo, ethAddr string, auth proc.ClientAuth, fileId uint, outFilePath string, peerId peer.ID, sharePeers []string, threshold int) (string, error, bool) {
+	addrInfo := a.host.Peerstore().PeerInfo(peerId)
+	transfer, err := a.chunkTransfer(ctx, splitFile)
+	if err != nil {
+		return "", xerrors.Errorf("authorizing chunk transfer: %w", err), true
 	}
 
-	s, err := a.host.NewStream(ctx, addrInfo.ID, proc.FileProcessDecryptionProtocolDraft)
+	s, format, err := proc.NewStream(ctx, a.host, addrInfo.ID, proc.FileProcessDecryptionProtocols)
 	if err != nil {
 		return "", xerrors.Errorf("failed to open stream to peer %s: %w", addrInfo.ID, err), false
 	}
 	defer s.Close()
 
 	req := proc.FileDecryptReq{
-		FileId:   fmt.Sprintf("%d", fileId),
-		ClientId: ethAddr,
-		Offset:   uint64(splitFile.Offset),
-		Size:     uint64(splitFile.Size),
-		Transfer: transfer,
+		FileId:     fmt.Sprintf("%d", fileId),
+		ClientId:   ethAddr,
+		Offset:     uint64(splitFile.Offset),
+		Size:       uint64(splitFile.Size),
+		Transfer:   transfer,
+		SharePeers: sharePeers,
+		Threshold:  threshold,
+		Auth:       auth,
 	}
 
 	var resp proc.FileDecryptResp
-	if err = util.DoRpc(ctx, s, &req, &resp, "json"); err != nil {
+	if err = util.DoRpc(ctx, s, &req, &resp, format); err != nil {
 		return "", xerrors.Errorf("send proposal rpc: %w", err), false
 	}
 
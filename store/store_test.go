@@ -0,0 +1,25 @@
+package store
+
+import (
+	"testing"
+
+	"sao-datastore-storage/model"
+)
+
+func TestKeyOffset(t *testing.T) {
+	cases := []struct {
+		name          string
+		chunkMetadata model.FileChunkMetadata
+		offset        int64
+	}{
+		{"first legacy chunk", model.FileChunkMetadata{Offset: 0, EncryptedOffset: 0}, 0},
+		{"legacy chunk", model.FileChunkMetadata{Offset: 100, EncryptedOffset: 128}, 128},
+		{"whole key on a proc node", model.FileChunkMetadata{Offset: 100, EncryptedOffset: 128, PeerId: "QmProc1"}, 100},
+		{"key shares", model.FileChunkMetadata{Offset: 100, EncryptedOffset: 128, PeerId: "QmProc1", SharePeers: "QmProc1,QmProc2", Threshold: 2}, 100},
+	}
+	for _, c := range cases {
+		if offset := keyOffset(c.chunkMetadata); offset != c.offset {
+			t.Errorf("%s: key offset %d, want %d", c.name, offset, c.offset)
+		}
+	}
+}
@@ -36,6 +36,14 @@ func Success(ctx *gin.Context, data interface{}) {
 	ctx.JSON(http.StatusOK, successResponse(data))
 }
 
+func Conflict(ctx *gin.Context, code string, message string) {
+	ctx.JSON(http.StatusConflict, failResponse(code, message))
+}
+
+func TooLarge(ctx *gin.Context, code string, message string) {
+	ctx.JSON(http.StatusRequestEntityTooLarge, failResponse(code, message))
+}
+
 func Unauthorized(ctx *gin.Context, code string, message string) {
 	ctx.JSON(http.StatusUnauthorized, failResponse(code, message))
 }
@@ -0,0 +1,190 @@
+package car
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/ipfs/go-blockservice"
+	"github.com/ipfs/go-cid"
+	blockstore "github.com/ipfs/go-ipfs-blockstore"
+	chunker "github.com/ipfs/go-ipfs-chunker"
+	offline "github.com/ipfs/go-ipfs-exchange-offline"
+	"github.com/ipfs/go-merkledag"
+	"github.com/ipfs/go-unixfs/importer/balanced"
+	"github.com/ipfs/go-unixfs/importer/helpers"
+	uio "github.com/ipfs/go-unixfs/io"
+	carv2 "github.com/ipld/go-car/v2"
+	carbs "github.com/ipld/go-car/v2/blockstore"
+	"github.com/multiformats/go-multihash"
+)
+
+const unixfsChunkSize = 1 << 20
+const unixfsLinksPerBlock = 1024
+
+// placeholderRoot has the same size as the CIDv1 root of the imported DAG, so
+// it can be replaced in place once the DAG is written
+var placeholderRoot = func() cid.Cid {
+	mh, _ := multihash.Sum([]byte{}, multihash.SHA2_256, -1)
+	return cid.NewCidV1(cid.DagProtobuf, mh)
+}()
+
+// StageFile imports the file at path as a UnixFS DAG into a CARv2 file at
+// carPath and returns the root CID of the DAG.
+func StageFile(ctx context.Context, path string, carPath string) (cid.Cid, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return cid.Undef, err
+	}
+	defer f.Close()
+
+	// a car file left at carPath would be resumed instead of overwritten
+	if err = os.Remove(carPath); err != nil && !os.IsNotExist(err) {
+		return cid.Undef, err
+	}
+	bs, err := carbs.OpenReadWrite(carPath, []cid.Cid{placeholderRoot}, carbs.AllowDuplicatePuts(false))
+	if err != nil {
+		return cid.Undef, fmt.Errorf("creating car file: %w", err)
+	}
+
+	root, err := importFile(ctx, bs, f)
+	if err != nil {
+		bs.Discard()
+		return cid.Undef, err
+	}
+	if err = bs.Finalize(); err != nil {
+		return cid.Undef, fmt.Errorf("finalizing car file: %w", err)
+	}
+	if err = carv2.ReplaceRootsInFile(carPath, []cid.Cid{root}); err != nil {
+		return cid.Undef, fmt.Errorf("setting car root: %w", err)
+	}
+	return root, nil
+}
+
+func importFile(ctx context.Context, bs blockstore.Blockstore, r io.Reader) (cid.Cid, error) {
+	prefix, err := merkledag.PrefixForCidVersion(1)
+	if err != nil {
+		return cid.Undef, err
+	}
+	prefix.MhType = multihash.SHA2_256
+
+	params := helpers.DagBuilderParams{
+		Maxlinks:   unixfsLinksPerBlock,
+		RawLeaves:  true,
+		CidBuilder: prefix,
+		Dagserv:    merkledag.NewDAGService(blockservice.New(bs, offline.Exchange(bs))),
+	}
+	db, err := params.New(chunker.NewSizeSplitter(r, unixfsChunkSize))
+	if err != nil {
+		return cid.Undef, err
+	}
+	nd, err := balanced.Layout(db)
+	if err != nil {
+		return cid.Undef, fmt.Errorf("importing file: %w", err)
+	}
+	return nd.Cid(), nil
+}
+
+// PackFile imports the file at path as a UnixFS DAG into a CARv2 file at
+// carPath, then writes the CARv1 stream of the DAG to w, the CAR a storage
+// provider is sent. It returns the root CID of the DAG and the size of the
+// stream.
+func PackFile(ctx context.Context, path string, carPath string, w io.Writer) (cid.Cid, uint64, error) {
+	root, err := StageFile(ctx, path, carPath)
+	if err != nil {
+		return cid.Undef, 0, err
+	}
+
+	bs := NewStagedBlockstore()
+	if err = bs.Add(carPath, carPath); err != nil {
+		return cid.Undef, 0, err
+	}
+	defer bs.Remove(carPath)
+
+	var cw countWriter
+	if err = NewCarOffsetWriter(root, bs, NewBlockInfoCache()).Write(ctx, io.MultiWriter(w, &cw), 0); err != nil {
+		return cid.Undef, 0, fmt.Errorf("writing car: %w", err)
+	}
+	return root, cw.n, nil
+}
+
+// CarSize returns the size of the CARv1 stream of the DAG rooted at payloadCid,
+// as served by a CarOffsetWriter.
+func CarSize(ctx context.Context, payloadCid cid.Cid, bstore blockstore.Blockstore) (uint64, error) {
+	var cw countWriter
+	if err := NewCarOffsetWriter(payloadCid, bstore, NewBlockInfoCache()).Write(ctx, &cw, 0); err != nil {
+		return 0, err
+	}
+	return cw.n, nil
+}
+
+// ExtractFile writes the UnixFS file stored in the CAR file at carPath to
+// outPath.
+func ExtractFile(ctx context.Context, carPath string, outPath string) error {
+	r, err := OpenFile(ctx, carPath)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	out, err := os.Create(outPath)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	_, err = io.Copy(out, r)
+	return err
+}
+
+// OpenFile returns a seekable reader of the UnixFS file stored in the CAR
+// file at carPath, closing the CAR file when it is closed.
+func OpenFile(ctx context.Context, carPath string) (io.ReadSeekCloser, error) {
+	bs, err := carbs.OpenReadOnly(carPath)
+	if err != nil {
+		return nil, fmt.Errorf("opening car file: %w", err)
+	}
+
+	roots, err := bs.Roots()
+	if err != nil {
+		bs.Close()
+		return nil, err
+	}
+	if len(roots) != 1 {
+		bs.Close()
+		return nil, fmt.Errorf("expected one root in car file, got %d", len(roots))
+	}
+
+	dserv := merkledag.NewDAGService(blockservice.New(bs, offline.Exchange(bs)))
+	nd, err := dserv.Get(ctx, roots[0])
+	if err != nil {
+		bs.Close()
+		return nil, err
+	}
+	dr, err := uio.NewDagReader(ctx, nd, dserv)
+	if err != nil {
+		bs.Close()
+		return nil, err
+	}
+	return &carFileReader{DagReader: dr, bs: bs}, nil
+}
+
+type carFileReader struct {
+	uio.DagReader
+	bs *carbs.ReadOnly
+}
+
+func (r *carFileReader) Close() error {
+	r.DagReader.Close()
+	return r.bs.Close()
+}
+
+type countWriter struct {
+	n uint64
+}
+
+func (w *countWriter) Write(p []byte) (int, error) {
+	w.n += uint64(len(p))
+	return len(p), nil
+}
@@ -0,0 +1,108 @@
+package car
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestStageAndExtractFile(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	for _, size := range []int{0, 1, unixfsChunkSize, 3*unixfsChunkSize + 17} {
+		plain := make([]byte, size)
+		rand.Read(plain)
+
+		path := filepath.Join(dir, "chunk")
+		if err := os.WriteFile(path, plain, 0644); err != nil {
+			t.Fatal(err)
+		}
+		root, err := StageFile(ctx, path, path+".staged.car")
+		if err != nil {
+			t.Fatal("failed to stage file", err)
+		}
+
+		bs := NewStagedBlockstore()
+		if err = bs.Add("chunk", path+".staged.car"); err != nil {
+			t.Fatal(err)
+		}
+		carSize, err := CarSize(ctx, root, bs)
+		if err != nil {
+			t.Fatal("failed to size car", err)
+		}
+
+		// the receiver gets the CARv1 stream of the staged DAG
+		var carData bytes.Buffer
+		if err = NewCarOffsetWriter(root, bs, NewBlockInfoCache()).Write(ctx, &carData, 0); err != nil {
+			t.Fatal("failed to write car", err)
+		}
+		if uint64(carData.Len()) != carSize {
+			t.Fatalf("size %d: car size %d, written %d", size, carSize, carData.Len())
+		}
+		if err = bs.Remove("chunk"); err != nil {
+			t.Fatal(err)
+		}
+
+		if err = os.WriteFile(path+".car", carData.Bytes(), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err = ExtractFile(ctx, path+".car", path+".out"); err != nil {
+			t.Fatal("failed to extract file", err)
+		}
+		extracted, err := os.ReadFile(path + ".out")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(plain, extracted) {
+			t.Fatalf("size %d: extracted data mismatch", size)
+		}
+	}
+}
+
+func TestPackFile(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	plain := make([]byte, 2*unixfsChunkSize+5)
+	rand.Read(plain)
+	path := filepath.Join(dir, "file")
+	if err := os.WriteFile(path, plain, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var carData bytes.Buffer
+	root, carSize, err := PackFile(ctx, path, path+".car", &carData)
+	if err != nil {
+		t.Fatal("failed to pack file", err)
+	}
+	if root.Version() != 1 || uint64(carData.Len()) != carSize {
+		t.Fatalf("root %s, car size %d, written %d", root, carSize, carData.Len())
+	}
+
+	// the same file is packed into the same CAR
+	staged, err := StageFile(ctx, path, path+".staged.car")
+	if err != nil {
+		t.Fatal("failed to stage file", err)
+	}
+	if staged != root {
+		t.Fatalf("packed root %s, staged root %s", root, staged)
+	}
+
+	if err = os.WriteFile(path+".v1.car", carData.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err = ExtractFile(ctx, path+".v1.car", path+".out"); err != nil {
+		t.Fatal("failed to extract file", err)
+	}
+	extracted, err := os.ReadFile(path + ".out")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(plain, extracted) {
+		t.Fatal("extracted data mismatch")
+	}
+}
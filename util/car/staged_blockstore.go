@@ -0,0 +1,122 @@
+package car
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	blocks "github.com/ipfs/go-block-format"
+	"github.com/ipfs/go-cid"
+	blockstore "github.com/ipfs/go-ipfs-blockstore"
+	carbs "github.com/ipld/go-car/v2/blockstore"
+)
+
+var errReadOnly = errors.New("staged blockstore is read-only")
+
+// StagedBlockstore is a read-only blockstore over a set of CAR files, each one
+// added under the id of the transfer it is staged for
+type StagedBlockstore struct {
+	lk   sync.RWMutex
+	cars map[string]*carbs.ReadOnly
+}
+
+var _ blockstore.Blockstore = (*StagedBlockstore)(nil)
+
+func NewStagedBlockstore() *StagedBlockstore {
+	return &StagedBlockstore{
+		cars: make(map[string]*carbs.ReadOnly),
+	}
+}
+
+// Add opens the CAR file at carPath and adds its blocks under id
+func (b *StagedBlockstore) Add(id string, carPath string) error {
+	bs, err := carbs.OpenReadOnly(carPath)
+	if err != nil {
+		return fmt.Errorf("opening car file: %w", err)
+	}
+
+	b.lk.Lock()
+	defer b.lk.Unlock()
+
+	if existing, ok := b.cars[id]; ok {
+		existing.Close()
+	}
+	b.cars[id] = bs
+	return nil
+}
+
+// Remove closes the CAR file added under id
+func (b *StagedBlockstore) Remove(id string) error {
+	b.lk.Lock()
+	defer b.lk.Unlock()
+
+	bs, ok := b.cars[id]
+	if !ok {
+		return nil
+	}
+	delete(b.cars, id)
+	return bs.Close()
+}
+
+func (b *StagedBlockstore) Has(ctx context.Context, c cid.Cid) (bool, error) {
+	b.lk.RLock()
+	defer b.lk.RUnlock()
+
+	for _, bs := range b.cars {
+		has, err := bs.Has(ctx, c)
+		if err != nil {
+			return false, err
+		}
+		if has {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (b *StagedBlockstore) Get(ctx context.Context, c cid.Cid) (blocks.Block, error) {
+	b.lk.RLock()
+	defer b.lk.RUnlock()
+
+	for _, bs := range b.cars {
+		blk, err := bs.Get(ctx, c)
+		if errors.Is(err, blockstore.ErrNotFound) {
+			continue
+		}
+		return blk, err
+	}
+	return nil, blockstore.ErrNotFound
+}
+
+func (b *StagedBlockstore) GetSize(ctx context.Context, c cid.Cid) (int, error) {
+	b.lk.RLock()
+	defer b.lk.RUnlock()
+
+	for _, bs := range b.cars {
+		size, err := bs.GetSize(ctx, c)
+		if errors.Is(err, blockstore.ErrNotFound) {
+			continue
+		}
+		return size, err
+	}
+	return 0, blockstore.ErrNotFound
+}
+
+func (b *StagedBlockstore) DeleteBlock(context.Context, cid.Cid) error {
+	return errReadOnly
+}
+
+func (b *StagedBlockstore) Put(context.Context, blocks.Block) error {
+	return errReadOnly
+}
+
+func (b *StagedBlockstore) PutMany(context.Context, []blocks.Block) error {
+	return errReadOnly
+}
+
+func (b *StagedBlockstore) AllKeysChan(context.Context) (<-chan cid.Cid, error) {
+	return nil, errors.New("listing the keys of a staged blockstore is not supported")
+}
+
+func (b *StagedBlockstore) HashOnRead(bool) {}
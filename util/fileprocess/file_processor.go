@@ -1,25 +1,20 @@
 package fileprocess
 
 import (
-	"bufio"
 	"context"
-	"crypto/aes"
-	"crypto/cipher"
-	"crypto/rand"
 	"fmt"
 	logging "github.com/ipfs/go-log/v2"
 	"io"
-	"io/ioutil"
 	"math"
 	"os"
 	"sao-datastore-storage/util"
+	"sao-datastore-storage/util/car"
 	"sao-datastore-storage/util/transport"
 	"sao-datastore-storage/util/transport/types"
 	"time"
 )
 
 var log = logging.Logger("file")
-const FileChunkThreshold = 512 * (1 << 20) // 500 MB, change this to your requirement
 
 type SplitFileInfo struct {
 	FilePath string
@@ -27,93 +22,86 @@ type SplitFileInfo struct {
 	Size     int64
 }
 
-func SplitFile(file *os.File, fileSize int64, fileBasePath string) ([]SplitFileInfo, error) {
-	totalPartsNum := 2
-	fileChunkFloat := float64(fileSize) / float64(totalPartsNum)
-	fileChunk := int64(math.Ceil(fileChunkFloat))
+const DefaultPartsNum = 2
 
-	log.Infof("Splitting to %d chunks.", totalPartsNum)
+// PartsNum returns the number of chunks a file of fileSize bytes is split
+// into. chunkSize takes precedence over chunkCount, the result is never
+// larger than the file size.
+func PartsNum(fileSize int64, chunkSize int64, chunkCount int) int {
+	partsNum := DefaultPartsNum
+	if chunkSize > 0 {
+		partsNum = int(math.Ceil(float64(fileSize) / float64(chunkSize)))
+	} else if chunkCount > 0 {
+		partsNum = chunkCount
+	}
+	if int64(partsNum) > fileSize {
+		partsNum = int(fileSize)
+	}
+	if partsNum < 1 {
+		partsNum = 1
+	}
+	return partsNum
+}
+
+// SplitFileInfos returns the chunks SplitFile writes for a file of fileSize
+// bytes, without writing them.
+func SplitFileInfos(fileSize int64, fileBasePath string, totalPartsNum int) ([]SplitFileInfo, error) {
+	if totalPartsNum < 1 {
+		return nil, fmt.Errorf("invalid number of chunks %d", totalPartsNum)
+	}
+	// spread the remainder over the first chunks so that no chunk is empty
+	fileChunk := fileSize / int64(totalPartsNum)
+	remainder := fileSize % int64(totalPartsNum)
 
 	var splitFileInfos []SplitFileInfo
 	var offset int64
 	for i := 0; i < totalPartsNum; i++ {
-		partSize := int(math.Min(float64(fileChunk), float64(fileSize-int64(i)*fileChunk)))
-		filePath := fmt.Sprintf("%s_%d", fileBasePath, i)
-		err := writeThisPartToFile(file, partSize, filePath)
-		if err != nil {
-			return splitFileInfos, err
+		partSize := fileChunk
+		if int64(i) < remainder {
+			partSize++
 		}
-
-		log.Info("Split to:", filePath)
 		splitFileInfos = append(splitFileInfos, SplitFileInfo{
-			FilePath: filePath,
+			FilePath: fmt.Sprintf("%s_%d", fileBasePath, i),
 			Offset:   offset,
-			Size:     int64(partSize),
+			Size:     partSize,
 		})
-		offset += int64(partSize)
+		offset += partSize
 	}
 	return splitFileInfos, nil
 }
 
-func writeThisPartToFile(file *os.File, originalPartSize int, filePath string) error {
-	if originalPartSize > FileChunkThreshold {
-		// calculate total number of parts the file will be chunked into
-		totalPartsNum := uint64(math.Ceil(float64(originalPartSize) / float64(FileChunkThreshold)))
+func SplitFile(file *os.File, fileSize int64, fileBasePath string, totalPartsNum int) ([]SplitFileInfo, error) {
+	splitFileInfos, err := SplitFileInfos(fileSize, fileBasePath, totalPartsNum)
+	if err != nil {
+		return nil, err
+	}
 
-		log.Infof("Splitting to %d pieces.\n", totalPartsNum)
-		partFile, err := os.Create(filePath)
-		if err != nil {
-			return err
-		}
-		defer partFile.Close()
+	log.Infof("Splitting to %d chunks.", totalPartsNum)
 
-		f, err := os.OpenFile(filePath, os.O_APPEND|os.O_WRONLY, os.ModeAppend)
+	for i, splitFileInfo := range splitFileInfos {
+		err := writeThisPartToFile(file, int(splitFileInfo.Size), splitFileInfo.FilePath)
 		if err != nil {
-			return err
+			return splitFileInfos[:i], err
 		}
-		defer f.Close()
-		for i := uint64(0); i < totalPartsNum; i++ {
-			partSize := int(math.Min(FileChunkThreshold, float64(int64(originalPartSize)-int64(i*FileChunkThreshold))))
-			partBuffer := make([]byte, partSize)
 
-			_, err = file.Read(partBuffer)
-			if err != nil {
-				return err
-			}
-
-			// write/save buffer to disk
-			n, err := f.Write(partBuffer)
-			if err != nil {
-				return err
-			}
-			f.Sync()
-			partBuffer = nil
-			log.Debug("Written ", n, " bytes")
-		}
-	} else {
-		partBuffer := make([]byte, originalPartSize)
-
-		_, err := file.Read(partBuffer)
-		if err != nil {
-			return err
-		}
+		log.Info("Split to:", splitFileInfo.FilePath)
+	}
+	return splitFileInfos, nil
+}
 
-		// write to disk
-		f, err := os.Create(filePath)
-		if err != nil {
-			return err
-		}
-		func() {
-			defer f.Close()
-		}()
+func writeThisPartToFile(file *os.File, originalPartSize int, filePath string) error {
+	f, err := os.Create(filePath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
 
-		// write/save buffer to disk
-		err = ioutil.WriteFile(filePath, partBuffer, os.ModeAppend)
-		if err != nil {
-			return err
-		}
+	n, err := io.CopyN(f, file, int64(originalPartSize))
+	if err != nil {
+		return err
 	}
-	return nil
+	log.Debug("Written ", n, " bytes")
+	return f.Sync()
 }
 
 func CombineFile(chunkPaths []string, outFilePath string) error {
@@ -162,51 +150,12 @@ func combineChunk(outFilePath string, currentChunkPath string, outFile *os.File,
 }
 
 func writeChunkToCombinedFile(chunkSize int64, currentChunkFile *os.File, outFile *os.File) error {
-	if chunkSize > FileChunkThreshold {
-		// calculate total number of parts the file will be chunked into
-		totalPartsNum := uint64(math.Ceil(float64(chunkSize) / float64(FileChunkThreshold)))
-
-		log.Infof("Splitting to %d pieces.\n", totalPartsNum)
-		for i := uint64(0); i < totalPartsNum; i++ {
-			partSize := int(math.Min(FileChunkThreshold, float64(chunkSize-int64(i*FileChunkThreshold))))
-			chunkBufferBytes := make([]byte, partSize)
-
-			_, err := currentChunkFile.Read(chunkBufferBytes)
-			if err != nil {
-				return err
-			}
-
-			n, err := outFile.Write(chunkBufferBytes)
-			if err != nil {
-				return err
-			}
-
-			outFile.Sync()
-			chunkBufferBytes = nil
-
-			log.Debug("Written ", n, " bytes")
-		}
-	} else {
-		chunkBufferBytes := make([]byte, chunkSize)
-
-		reader := bufio.NewReader(currentChunkFile)
-		_, err := reader.Read(chunkBufferBytes)
-		if err != nil {
-			return err
-		}
-
-		n, err := outFile.Write(chunkBufferBytes)
-		if err != nil {
-			return err
-		}
-
-		outFile.Sync()
-		chunkBufferBytes = nil
-
-		log.Debug("Written ", n, " bytes")
+	n, err := io.CopyN(outFile, currentChunkFile, chunkSize)
+	if err != nil {
+		return err
 	}
-	return nil
-
+	log.Debug("Written ", n, " bytes")
+	return outFile.Sync()
 }
 
 func deleteChunkFile(currentChunkPath string) {
@@ -216,7 +165,33 @@ func deleteChunkFile(currentChunkPath string) {
 	}
 }
 
+// ProgressFunc is called with the number of bytes received as a transfer
+// progresses.
+type ProgressFunc func(received int64, size int64)
+
 func TransferFile(ctx context.Context, transport transport.Transport, transfer types.Transfer, fileId string, outFilePath string) error {
+	return TransferFileWithProgress(ctx, transport, transfer, fileId, outFilePath, nil)
+}
+
+// TransferFileWithProgress is TransferFile reporting the transport events to
+// progress, which may be nil.
+func TransferFileWithProgress(ctx context.Context, transport transport.Transport, transfer types.Transfer, fileId string, outFilePath string, progress ProgressFunc) error {
+	if transfer.Type != "libp2p" {
+		return transferFile(ctx, transport, transfer, fileId, outFilePath, progress)
+	}
+
+	// libp2p transfers send the file as a CAR, which is unpacked once received
+	carPath := outFilePath + ".car"
+	if err := transferFile(ctx, transport, transfer, fileId, carPath, progress); err != nil {
+		return err
+	}
+	if err := car.ExtractFile(ctx, carPath, outFilePath); err != nil {
+		return fmt.Errorf("extracting car file: %w", err)
+	}
+	return os.Remove(carPath)
+}
+
+func transferFile(ctx context.Context, transport transport.Transport, transfer types.Transfer, fileId string, outFilePath string, progress ProgressFunc) error {
 	err := util.CreateFileIfNotExists(outFilePath)
 	if err != nil {
 		return err
@@ -234,7 +209,7 @@ func TransferFile(ctx context.Context, transport transport.Transport, transfer t
 	}
 
 	// wait for data-transfer to finish
-	if err = waitForTransferFinish(ctx, handler, int64(transfer.Size), fileId); err != nil {
+	if err = waitForTransferFinish(ctx, handler, int64(transfer.Size), fileId, progress); err != nil {
 		// Note that the data transfer has automatic retries built in, so if
 		// it fails, it means it's already retried several times and we should
 		// surface the problem to the user so they can decide manually whether
@@ -245,7 +220,7 @@ func TransferFile(ctx context.Context, transport transport.Transport, transfer t
 	return nil
 }
 
-func waitForTransferFinish(ctx context.Context, handler transport.Handler, size int64, fileId string) error {
+func waitForTransferFinish(ctx context.Context, handler transport.Handler, size int64, fileId string, progress ProgressFunc) error {
 	defer handler.Close()
 	var lastOutputPct int64
 
@@ -268,70 +243,12 @@ func waitForTransferFinish(ctx context.Context, handler transport.Handler, size
 			if evt.Error != nil {
 				return evt.Error
 			}
-			//deal.NBytesReceived = evt.NBytesReceived
 			logTransferProgress(evt.NBytesReceived)
+			if progress != nil {
+				progress(evt.NBytesReceived, size)
+			}
 		case <-ctx.Done():
 			return ctx.Err()
 		}
 	}
 }
-
-func DecryptFile(inputPath string, outPath string, key []byte) (int, error) {
-	body, err := ioutil.ReadFile(inputPath)
-
-	block, err := aes.NewCipher(key)
-	if err != nil {
-		return 0, err
-	}
-	gcm, err := cipher.NewGCM(block)
-	if err != nil {
-		return 0, err
-	}
-
-	nonce := body[:gcm.NonceSize()]
-	body = body[gcm.NonceSize():]
-	originalData, err := gcm.Open(nil, nonce, body, nil)
-	if err != nil {
-		return 0, err
-	}
-
-	err = ioutil.WriteFile(outPath, originalData, 0777)
-	if err != nil {
-		return 0, err
-	}
-
-	return len(originalData), nil
-}
-
-func EncryptFile(inputPath string, outPath string) ([]byte, []byte, int, error) {
-	key := make([]byte, 32)
-
-	if _, err := rand.Read(key); err != nil {
-		return nil, nil, 0, err
-	}
-
-	block, err := aes.NewCipher(key)
-	if err != nil {
-		return nil, nil, 0, err
-	}
-	gcm, err := cipher.NewGCM(block)
-	if err != nil {
-		return nil, nil, 0, err
-	}
-
-	nonce := make([]byte, gcm.NonceSize())
-	if _, err = io.ReadFull(rand.Reader, nonce); err != nil {
-		return nil, nil, 0, err
-	}
-
-	body, err := ioutil.ReadFile(inputPath)
-
-	encryptedData := gcm.Seal(nonce, nonce, body, nil)
-
-	err = ioutil.WriteFile(outPath, encryptedData, 0777)
-	if err != nil {
-		return nil, nil, 0, err
-	}
-
-	return key, nonce, len(encryptedData), nil
-}
@@ -0,0 +1,335 @@
+package fileprocess
+
+import (
+	"bufio"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Encrypted chunks are written in a segmented AEAD format so that neither
+// side has to hold a whole chunk in memory:
+//
+//	header: version (1 byte) | frame size (4 bytes, big endian) | base nonce (12 bytes)
+//	frames: AES-256-GCM sealed frames of at most frame size plaintext bytes
+//
+// The nonce of frame i is the base nonce with the frame counter xor-ed into
+// bytes 7..10 and the last byte flipped for the final frame, so frames can be
+// neither reordered nor dropped from the end. The header is authenticated as
+// additional data of every frame.
+//
+// Chunks encrypted before this format, of LegacyEncryptionVersion, are
+// sealed whole with the nonce prepended and no additional data.
+const (
+	LegacyEncryptionVersion = 0
+	EncryptionVersion       = 1
+	DefaultFrameSize        = 64 * (1 << 10) // 64 KB of plaintext per frame
+	encryptionKeySize       = 32
+	encryptionNonceLen      = 12
+	encryptionTagLen        = 16
+	encryptionHdrLen        = 1 + 4 + encryptionNonceLen
+	maxFrameSize            = 16 * (1 << 20)
+)
+
+var ErrEncryptedStreamTruncated = errors.New("encrypted stream is truncated")
+
+type EncryptionHeader struct {
+	Version   uint8
+	FrameSize uint32
+	Nonce     []byte
+}
+
+func (h EncryptionHeader) bytes() []byte {
+	buf := make([]byte, encryptionHdrLen)
+	buf[0] = h.Version
+	binary.BigEndian.PutUint32(buf[1:5], h.FrameSize)
+	copy(buf[5:], h.Nonce)
+	return buf
+}
+
+func ReadEncryptionHeader(r io.Reader) (EncryptionHeader, error) {
+	buf := make([]byte, encryptionHdrLen)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return EncryptionHeader{}, ErrEncryptedStreamTruncated
+		}
+		return EncryptionHeader{}, err
+	}
+	h := EncryptionHeader{
+		Version:   buf[0],
+		FrameSize: binary.BigEndian.Uint32(buf[1:5]),
+		Nonce:     buf[5:],
+	}
+	if h.Version != EncryptionVersion {
+		return h, fmt.Errorf("unsupported encryption version %d", h.Version)
+	}
+	if h.FrameSize == 0 || h.FrameSize > maxFrameSize {
+		return h, fmt.Errorf("invalid encryption frame size %d", h.FrameSize)
+	}
+	return h, nil
+}
+
+// EncryptedSize returns the size of the encrypted stream for plainSize bytes
+// of plaintext sealed with the given frame size.
+func EncryptedSize(plainSize int64, frameSize int) int64 {
+	frames := plainSize / int64(frameSize)
+	if plainSize%int64(frameSize) != 0 || plainSize == 0 {
+		frames++
+	}
+	return encryptionHdrLen + plainSize + frames*encryptionTagLen
+}
+
+func frameNonce(base []byte, counter uint32, last bool) []byte {
+	nonce := make([]byte, encryptionNonceLen)
+	copy(nonce, base)
+	var c [4]byte
+	binary.BigEndian.PutUint32(c[:], counter)
+	for i := 0; i < 4; i++ {
+		nonce[7+i] ^= c[i]
+	}
+	if last {
+		nonce[encryptionNonceLen-1] ^= 1
+	}
+	return nonce
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+type encryptWriter struct {
+	w       io.Writer
+	gcm     cipher.AEAD
+	header  []byte
+	nonce   []byte
+	buf     []byte
+	out     []byte
+	counter uint32
+	written int64
+	closed  bool
+}
+
+// NewEncryptWriter returns a writer sealing everything written to it into w.
+// Close must be called to seal the final frame; it does not close w.
+func NewEncryptWriter(w io.Writer, key []byte, nonce []byte, frameSize int) (io.WriteCloser, error) {
+	if frameSize <= 0 || frameSize > maxFrameSize {
+		return nil, fmt.Errorf("invalid encryption frame size %d", frameSize)
+	}
+	if len(nonce) != encryptionNonceLen {
+		return nil, fmt.Errorf("invalid nonce length %d", len(nonce))
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	header := EncryptionHeader{Version: EncryptionVersion, FrameSize: uint32(frameSize), Nonce: nonce}.bytes()
+	n, err := w.Write(header)
+	if err != nil {
+		return nil, err
+	}
+	return &encryptWriter{
+		w:       w,
+		gcm:     gcm,
+		header:  header,
+		nonce:   nonce,
+		buf:     make([]byte, 0, frameSize),
+		out:     make([]byte, 0, frameSize+encryptionTagLen),
+		written: int64(n),
+	}, nil
+}
+
+func (e *encryptWriter) Write(p []byte) (int, error) {
+	if e.closed {
+		return 0, errors.New("write to closed encrypt writer")
+	}
+	total := len(p)
+	for len(p) > 0 {
+		// a full frame is only sealed once more data arrives, because the
+		// final frame has to be marked as such.
+		if len(e.buf) == cap(e.buf) {
+			if err := e.seal(false); err != nil {
+				return total - len(p), err
+			}
+		}
+		n := copy(e.buf[len(e.buf):cap(e.buf)], p)
+		e.buf = e.buf[:len(e.buf)+n]
+		p = p[n:]
+	}
+	return total, nil
+}
+
+func (e *encryptWriter) seal(last bool) error {
+	e.out = e.gcm.Seal(e.out[:0], frameNonce(e.nonce, e.counter, last), e.buf, e.header)
+	n, err := e.w.Write(e.out)
+	e.written += int64(n)
+	if err != nil {
+		return err
+	}
+	if e.counter == ^uint32(0) {
+		return errors.New("too many frames in encrypted stream")
+	}
+	e.counter++
+	e.buf = e.buf[:0]
+	return nil
+}
+
+func (e *encryptWriter) Close() error {
+	if e.closed {
+		return nil
+	}
+	e.closed = true
+	return e.seal(true)
+}
+
+type decryptReader struct {
+	r       *bufio.Reader
+	gcm     cipher.AEAD
+	header  []byte
+	nonce   []byte
+	in      []byte
+	plain   []byte
+	pending []byte
+	counter uint32
+	done    bool
+}
+
+// NewDecryptReader returns a reader yielding the plaintext of the encrypted
+// stream r. Every frame is authenticated before any of its bytes are returned.
+func NewDecryptReader(r io.Reader, key []byte) (io.Reader, error) {
+	h, err := ReadEncryptionHeader(r)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	frameLen := int(h.FrameSize) + encryptionTagLen
+	return &decryptReader{
+		r:      bufio.NewReaderSize(r, frameLen+1),
+		gcm:    gcm,
+		header: h.bytes(),
+		nonce:  h.Nonce,
+		in:     make([]byte, frameLen),
+		plain:  make([]byte, 0, h.FrameSize),
+	}, nil
+}
+
+func (d *decryptReader) Read(p []byte) (int, error) {
+	for len(d.pending) == 0 {
+		if d.done {
+			return 0, io.EOF
+		}
+		if err := d.open(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, d.pending)
+	d.pending = d.pending[n:]
+	return n, nil
+}
+
+func (d *decryptReader) open() error {
+	var last bool
+	n, err := io.ReadFull(d.r, d.in)
+	switch {
+	case err == io.EOF:
+		return ErrEncryptedStreamTruncated
+	case err == io.ErrUnexpectedEOF:
+		last = true
+	case err != nil:
+		return err
+	default:
+		if _, perr := d.r.Peek(1); perr == io.EOF {
+			last = true
+		} else if perr != nil {
+			return perr
+		}
+	}
+	if n < encryptionTagLen {
+		return ErrEncryptedStreamTruncated
+	}
+
+	d.plain, err = d.gcm.Open(d.plain[:0], frameNonce(d.nonce, d.counter, last), d.in[:n], d.header)
+	if err != nil {
+		return fmt.Errorf("decrypting frame %d: %w", d.counter, err)
+	}
+	d.counter++
+	d.pending = d.plain
+	d.done = last
+	return nil
+}
+
+// EncryptStream seals everything read from r into w with a fresh key and
+// base nonce, and returns them together with the number of bytes written.
+func EncryptStream(r io.Reader, w io.Writer) ([]byte, []byte, int64, error) {
+	key := make([]byte, encryptionKeySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, nil, 0, err
+	}
+	nonce := make([]byte, encryptionNonceLen)
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, nil, 0, err
+	}
+
+	ew, err := NewEncryptWriter(w, key, nonce, DefaultFrameSize)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	if _, err = io.Copy(ew, r); err != nil {
+		return nil, nil, 0, err
+	}
+	if err = ew.Close(); err != nil {
+		return nil, nil, 0, err
+	}
+	return key, nonce, ew.(*encryptWriter).written, nil
+}
+
+// DecryptLegacyStream writes the plaintext of the chunk r encrypted in the
+// legacy format into w. The chunk is sealed whole, so it is read in memory.
+func DecryptLegacyStream(r io.Reader, w io.Writer, key []byte) (int64, error) {
+	body, err := io.ReadAll(r)
+	if err != nil {
+		return 0, err
+	}
+	if len(body) < encryptionNonceLen+encryptionTagLen {
+		return 0, ErrEncryptedStreamTruncated
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return 0, err
+	}
+	plain, err := gcm.Open(nil, body[:encryptionNonceLen], body[encryptionNonceLen:], nil)
+	if err != nil {
+		return 0, err
+	}
+	n, err := w.Write(plain)
+	return int64(n), err
+}
+
+// DecryptStreamVersion writes the plaintext of the chunk r, encrypted in the
+// format version, into w and returns the number of plaintext bytes written.
+func DecryptStreamVersion(r io.Reader, w io.Writer, key []byte, version int) (int64, error) {
+	if version == LegacyEncryptionVersion {
+		return DecryptLegacyStream(r, w, key)
+	}
+	return DecryptStream(r, w, key)
+}
+
+// DecryptStream writes the plaintext of the encrypted stream r into w and
+// returns the number of plaintext bytes written.
+func DecryptStream(r io.Reader, w io.Writer, key []byte) (int64, error) {
+	dr, err := NewDecryptReader(r, key)
+	if err != nil {
+		return 0, err
+	}
+	return io.Copy(w, dr)
+}
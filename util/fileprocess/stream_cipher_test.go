@@ -0,0 +1,96 @@
+package fileprocess
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"io"
+	"testing"
+)
+
+func TestEncryptDecryptStream(t *testing.T) {
+	for _, size := range []int{0, 1, DefaultFrameSize - 1, DefaultFrameSize, DefaultFrameSize + 1, 3*DefaultFrameSize + 17} {
+		plain := make([]byte, size)
+		rand.Read(plain)
+
+		var encrypted bytes.Buffer
+		key, _, n, err := EncryptStream(bytes.NewReader(plain), &encrypted)
+		if err != nil {
+			t.Fatal("failed to encrypt", err)
+		}
+		if n != int64(encrypted.Len()) || n != EncryptedSize(int64(size), DefaultFrameSize) {
+			t.Fatalf("size %d: encrypted size %d, written %d, expected %d", size, encrypted.Len(), n, EncryptedSize(int64(size), DefaultFrameSize))
+		}
+
+		var decrypted bytes.Buffer
+		if _, err = DecryptStream(bytes.NewReader(encrypted.Bytes()), &decrypted, key); err != nil {
+			t.Fatal("failed to decrypt", err)
+		}
+		if !bytes.Equal(plain, decrypted.Bytes()) {
+			t.Fatalf("size %d: decrypted data mismatch", size)
+		}
+	}
+}
+
+func TestDecryptStreamRejectsTampering(t *testing.T) {
+	plain := make([]byte, 2*DefaultFrameSize+5)
+	rand.Read(plain)
+
+	var encrypted bytes.Buffer
+	key, _, _, err := EncryptStream(bytes.NewReader(plain), &encrypted)
+	if err != nil {
+		t.Fatal("failed to encrypt", err)
+	}
+	data := encrypted.Bytes()
+
+	flipped := append([]byte{}, data...)
+	flipped[len(flipped)-1] ^= 1
+	if _, err = DecryptStream(bytes.NewReader(flipped), io.Discard, key); err == nil {
+		t.Fatal("expected error on modified frame")
+	}
+
+	// drop the final frame: the last remaining frame is not marked as final
+	truncated := data[:encryptionHdrLen+2*(DefaultFrameSize+encryptionTagLen)]
+	if _, err = DecryptStream(bytes.NewReader(truncated), io.Discard, key); err == nil {
+		t.Fatal("expected error on truncated stream")
+	}
+
+	header := append([]byte{}, data...)
+	header[1] ^= 1
+	if _, err = DecryptStream(bytes.NewReader(header), io.Discard, key); err == nil {
+		t.Fatal("expected error on modified header")
+	}
+}
+
+// TestDecryptLegacyStream decrypts a chunk encrypted as before the segmented
+// format, whole with the nonce prepended.
+func TestDecryptLegacyStream(t *testing.T) {
+	plain := make([]byte, DefaultFrameSize+5)
+	rand.Read(plain)
+	key := make([]byte, encryptionKeySize)
+	rand.Read(key)
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	rand.Read(nonce)
+	encrypted := gcm.Seal(nonce, nonce, plain, nil)
+
+	if _, err = DecryptStreamVersion(bytes.NewReader(encrypted), io.Discard, key, EncryptionVersion); err == nil {
+		t.Fatal("decrypted a legacy chunk in the segmented format")
+	}
+	var decrypted bytes.Buffer
+	size, err := DecryptStreamVersion(bytes.NewReader(encrypted), &decrypted, key, LegacyEncryptionVersion)
+	if err != nil {
+		t.Fatal("failed to decrypt legacy chunk", err)
+	}
+	if size != int64(len(plain)) || !bytes.Equal(plain, decrypted.Bytes()) {
+		t.Fatalf("decrypted %d bytes of %d, mismatch", size, len(plain))
+	}
+}
@@ -0,0 +1,136 @@
+package shamir
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+)
+
+// Shamir secret sharing over GF(2^8), byte by byte. Every share carries its
+// x coordinate as the last byte, so shares can be combined in any order.
+
+const maxShares = 255
+
+// Split splits secret into n shares, any k of which can recover it.
+func Split(secret []byte, n int, k int) ([][]byte, error) {
+	if len(secret) == 0 {
+		return nil, errors.New("cannot split an empty secret")
+	}
+	if k < 1 || n < k || n > maxShares {
+		return nil, fmt.Errorf("invalid shares %d with threshold %d", n, k)
+	}
+
+	shares := make([][]byte, n)
+	for i := range shares {
+		shares[i] = make([]byte, len(secret)+1)
+		// x coordinates 1..n, 0 would reveal the secret
+		shares[i][len(secret)] = byte(i + 1)
+	}
+
+	coefficients := make([]byte, k)
+	for idx, b := range secret {
+		coefficients[0] = b
+		if _, err := rand.Read(coefficients[1:]); err != nil {
+			return nil, err
+		}
+		for i := range shares {
+			shares[i][idx] = evaluate(coefficients, byte(i+1))
+		}
+	}
+	return shares, nil
+}
+
+// Combine recovers the secret from at least threshold shares. Combining
+// fewer shares than the threshold yields garbage, not an error.
+func Combine(shares [][]byte) ([]byte, error) {
+	if len(shares) == 0 {
+		return nil, errors.New("no shares to combine")
+	}
+	size := len(shares[0])
+	if size < 2 {
+		return nil, errors.New("share is too short")
+	}
+
+	xs := make([]byte, len(shares))
+	seen := make(map[byte]bool)
+	for i, share := range shares {
+		if len(share) != size {
+			return nil, errors.New("shares have different lengths")
+		}
+		x := share[size-1]
+		if x == 0 || seen[x] {
+			return nil, errors.New("invalid or duplicated share")
+		}
+		seen[x] = true
+		xs[i] = x
+	}
+
+	secret := make([]byte, size-1)
+	ys := make([]byte, len(shares))
+	for idx := range secret {
+		for i, share := range shares {
+			ys[i] = share[idx]
+		}
+		secret[idx] = interpolateAtZero(xs, ys)
+	}
+	return secret, nil
+}
+
+func evaluate(coefficients []byte, x byte) byte {
+	// Horner's method
+	var y byte
+	for i := len(coefficients) - 1; i >= 0; i-- {
+		y = add(mul(y, x), coefficients[i])
+	}
+	return y
+}
+
+func interpolateAtZero(xs []byte, ys []byte) byte {
+	var y byte
+	for i := range xs {
+		basis := byte(1)
+		for j := range xs {
+			if i == j {
+				continue
+			}
+			// x_j / (x_j - x_i), subtraction is xor in GF(2^8)
+			basis = mul(basis, div(xs[j], add(xs[j], xs[i])))
+		}
+		y = add(y, mul(ys[i], basis))
+	}
+	return y
+}
+
+func add(a, b byte) byte {
+	return a ^ b
+}
+
+// mul multiplies in GF(2^8) with the AES polynomial x^8 + x^4 + x^3 + x + 1.
+func mul(a, b byte) byte {
+	var p byte
+	for b > 0 {
+		if b&1 == 1 {
+			p ^= a
+		}
+		carry := a & 0x80
+		a <<= 1
+		if carry != 0 {
+			a ^= 0x1b
+		}
+		b >>= 1
+	}
+	return p
+}
+
+func inverse(a byte) byte {
+	// a^254 == a^-1 in GF(2^8)
+	result := byte(1)
+	for i := 0; i < 254; i++ {
+		result = mul(result, a)
+	}
+	return result
+}
+
+func div(a, b byte) byte {
+	return mul(a, inverse(b))
+}
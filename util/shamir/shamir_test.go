@@ -0,0 +1,48 @@
+package shamir
+
+import (
+	"bytes"
+	"crypto/rand"
+	"testing"
+)
+
+func TestSplitCombine(t *testing.T) {
+	secret := make([]byte, 32)
+	rand.Read(secret)
+
+	shares, err := Split(secret, 5, 3)
+	if err != nil {
+		t.Fatal("failed to split", err)
+	}
+
+	for _, subset := range [][]int{{0, 1, 2}, {4, 2, 0}, {1, 3, 4}, {0, 1, 2, 3, 4}} {
+		var picked [][]byte
+		for _, i := range subset {
+			picked = append(picked, shares[i])
+		}
+		recovered, err := Combine(picked)
+		if err != nil {
+			t.Fatal("failed to combine", err)
+		}
+		if !bytes.Equal(secret, recovered) {
+			t.Fatalf("shares %v: recovered secret mismatch", subset)
+		}
+	}
+
+	recovered, err := Combine(shares[:2])
+	if err != nil {
+		t.Fatal("failed to combine", err)
+	}
+	if bytes.Equal(secret, recovered) {
+		t.Fatal("recovered secret below threshold")
+	}
+}
+
+func TestSplitInvalid(t *testing.T) {
+	if _, err := Split([]byte("key"), 2, 3); err == nil {
+		t.Fatal("expected error when threshold exceeds shares")
+	}
+	if _, err := Combine([][]byte{{1, 1}, {2, 1}}); err == nil {
+		t.Fatal("expected error on duplicated shares")
+	}
+}
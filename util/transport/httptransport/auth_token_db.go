@@ -80,6 +80,28 @@ func (db *AuthTokenDB) Get(ctx context.Context, authToken string) (*AuthValue, e
 	return &val.AuthValue, nil
 }
 
+// GetUnexpired gets data by auth token, tokens created before the given time
+// are treated as not found
+func (db *AuthTokenDB) GetUnexpired(ctx context.Context, authToken string, before time.Time) (*AuthValue, error) {
+	data, err := db.ds.Get(ctx, datastore.NewKey(authToken))
+	if err != nil {
+		if errors.Is(err, datastore.ErrNotFound) {
+			return nil, ErrTokenNotFound
+		}
+		return nil, fmt.Errorf("getting auth token from datastore: %w", err)
+	}
+
+	var val authValueTS
+	err = json.Unmarshal(data, &val)
+	if err != nil {
+		return nil, fmt.Errorf("unmarshaling json from datastore: %w", err)
+	}
+	if val.CreatedAt.Before(before) {
+		return nil, ErrTokenNotFound
+	}
+	return &val.AuthValue, nil
+}
+
 // Delete auth token from the datastore
 func (db *AuthTokenDB) Delete(ctx context.Context, authToken string) error {
 	return db.ds.Delete(ctx, datastore.NewKey(authToken))
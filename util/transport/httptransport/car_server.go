@@ -0,0 +1,199 @@
+package httptransport
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-datastore"
+	dssync "github.com/ipfs/go-datastore/sync"
+	"github.com/libp2p/go-libp2p-core/host"
+	manet "github.com/multiformats/go-multiaddr/net"
+	"sao-datastore-storage/util/car"
+	"sao-datastore-storage/util/transport/types"
+)
+
+// StagedCarServer serves staged files as CARs over libp2p, so that peers
+// without a public HTTP endpoint can exchange them. Like StagedFileServer each
+// file is only served with the auth token created for it, and the CAR is
+// deleted once it has been sent or the TTL expires.
+type StagedCarServer struct {
+	h      host.Host
+	auth   *AuthTokenDB
+	bstore *car.StagedBlockstore
+	server *Libp2pCarServer
+	ttl    time.Duration
+
+	lk     sync.Mutex
+	staged map[string]stagedCar
+	unsub  UnsubFn
+}
+
+type stagedCar struct {
+	authToken string
+	carPath   string
+}
+
+func NewStagedCarServer(h host.Host, ttl time.Duration) *StagedCarServer {
+	if ttl <= 0 {
+		ttl = DefaultAuthTokenTTL
+	}
+	auth := NewAuthTokenDB(dssync.MutexWrap(datastore.NewMapDatastore()))
+	bstore := car.NewStagedBlockstore()
+	return &StagedCarServer{
+		h:      h,
+		auth:   auth,
+		bstore: bstore,
+		server: NewLibp2pCarServer(h, auth, bstore, ServerConfig{}),
+		ttl:    ttl,
+		staged: make(map[string]stagedCar),
+	}
+}
+
+func (s *StagedCarServer) Start(ctx context.Context) error {
+	s.unsub = s.server.Subscribe(func(id string, st types.TransferState) {
+		if st.Status == types.TransferStatusCompleted {
+			s.release(ctx, id)
+		}
+	})
+	return s.server.Start(ctx)
+}
+
+func (s *StagedCarServer) Stop(ctx context.Context) error {
+	if s.unsub != nil {
+		s.unsub()
+	}
+	err := s.server.Stop(ctx)
+
+	s.lk.Lock()
+	ids := make([]string, 0, len(s.staged))
+	for id := range s.staged {
+		ids = append(ids, id)
+	}
+	s.lk.Unlock()
+	for _, id := range ids {
+		s.release(ctx, id)
+	}
+	return err
+}
+
+// Authorize stages the file at path as a CAR, creates a single-use auth token
+// for it and returns the transfer the peer fetches it with.
+func (s *StagedCarServer) Authorize(ctx context.Context, path string) (types.Transfer, error) {
+	expired, err := s.auth.DeleteExpired(ctx, time.Now().Add(-s.ttl))
+	if err != nil {
+		log.Warnw("deleting expired auth tokens", "err", err)
+	}
+	for _, val := range expired {
+		s.release(ctx, val.ID)
+	}
+
+	addr, err := s.dialAddr()
+	if err != nil {
+		return types.Transfer{}, err
+	}
+
+	id := uuid.New().String()
+	carPath := fmt.Sprintf("%s.%s.car", path, id)
+	root, err := car.StageFile(ctx, path, carPath)
+	if err != nil {
+		os.Remove(carPath)
+		return types.Transfer{}, fmt.Errorf("staging car file: %w", err)
+	}
+	if err = s.bstore.Add(id, carPath); err != nil {
+		os.Remove(carPath)
+		return types.Transfer{}, err
+	}
+
+	s.lk.Lock()
+	s.staged[id] = stagedCar{carPath: carPath}
+	s.lk.Unlock()
+
+	transfer, err := s.authorize(ctx, id, root, addr)
+	if err != nil {
+		s.release(ctx, id)
+		return types.Transfer{}, err
+	}
+	return transfer, nil
+}
+
+func (s *StagedCarServer) authorize(ctx context.Context, id string, root cid.Cid, addr string) (types.Transfer, error) {
+	size, err := car.CarSize(ctx, root, s.bstore)
+	if err != nil {
+		return types.Transfer{}, fmt.Errorf("getting car size: %w", err)
+	}
+
+	authToken, err := GenerateAuthToken()
+	if err != nil {
+		return types.Transfer{}, err
+	}
+	s.lk.Lock()
+	s.staged[id] = stagedCar{authToken: authToken, carPath: s.staged[id].carPath}
+	s.lk.Unlock()
+	err = s.auth.Put(ctx, authToken, AuthValue{
+		ID:         id,
+		PayloadCid: root,
+		Size:       size,
+	})
+	if err != nil {
+		return types.Transfer{}, err
+	}
+
+	paramsBytes, err := json.Marshal(types.HttpRequest{
+		URL:     fmt.Sprintf("%s://%s/p2p/%s", libp2pScheme, addr, s.h.ID()),
+		Headers: map[string]string{"Authorization": BasicAuthHeader("", authToken)},
+	})
+	if err != nil {
+		return types.Transfer{}, fmt.Errorf("marshalling request parameters: %w", err)
+	}
+
+	return types.Transfer{
+		Type:   libp2pScheme,
+		Size:   size,
+		Params: paramsBytes,
+	}, nil
+}
+
+// dialAddr returns the address put in the transfer URL. The peer fetching the
+// CAR is already connected to this host, so it only needs to be a valid one.
+func (s *StagedCarServer) dialAddr() (string, error) {
+	addrs := s.h.Addrs()
+	if len(addrs) == 0 {
+		return "", errors.New("libp2p host has no listen address")
+	}
+	for _, addr := range addrs {
+		if !manet.IsIPLoopback(addr) {
+			return addr.String(), nil
+		}
+	}
+	return addrs[0].String(), nil
+}
+
+// release deletes the auth token and the CAR staged for the transfer id
+func (s *StagedCarServer) release(ctx context.Context, id string) {
+	s.lk.Lock()
+	staged, ok := s.staged[id]
+	delete(s.staged, id)
+	s.lk.Unlock()
+	if !ok {
+		return
+	}
+
+	if staged.authToken != "" {
+		if err := s.auth.Delete(ctx, staged.authToken); err != nil {
+			log.Warnw("deleting used auth token", "id", id, "err", err)
+		}
+	}
+	if err := s.bstore.Remove(id); err != nil {
+		log.Warnw("closing staged car", "id", id, "err", err)
+	}
+	if err := os.Remove(staged.carPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+		log.Warnw("removing staged car", "id", id, "err", err)
+	}
+}
@@ -0,0 +1,146 @@
+package httptransport
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/ipfs/go-datastore"
+	dssync "github.com/ipfs/go-datastore/sync"
+)
+
+// DefaultAuthTokenTTL is how long a staged file can be downloaded with its
+// auth token when no TTL is configured
+const DefaultAuthTokenTTL = 10 * time.Minute
+
+// StagedFileServer serves the files of a staging directory over HTTP. Each
+// file is only served to requests carrying the auth token created for it. The
+// token is deleted once a GET request has sent the last byte of the file, or
+// once the TTL expires, so an interrupted transfer resumes with a range
+// request and the same token.
+type StagedFileServer struct {
+	auth *AuthTokenDB
+	dir  string
+	ttl  time.Duration
+}
+
+func NewStagedFileServer(dir string, ttl time.Duration) *StagedFileServer {
+	if ttl <= 0 {
+		ttl = DefaultAuthTokenTTL
+	}
+	return &StagedFileServer{
+		auth: NewAuthTokenDB(dssync.MutexWrap(datastore.NewMapDatastore())),
+		dir:  dir,
+		ttl:  ttl,
+	}
+}
+
+// Authorize creates an auth token for the staged file at path, valid until
+// the file is sent, and returns the headers to send it with.
+func (s *StagedFileServer) Authorize(ctx context.Context, path string) (map[string]string, error) {
+	if _, err := s.auth.DeleteExpired(ctx, time.Now().Add(-s.ttl)); err != nil {
+		log.Warnw("deleting expired auth tokens", "err", err)
+	}
+
+	st, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	authToken, err := GenerateAuthToken()
+	if err != nil {
+		return nil, err
+	}
+	err = s.auth.Put(ctx, authToken, AuthValue{
+		ID:   filepath.Base(path),
+		Size: uint64(st.Size()),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]string{
+		"Authorization": BasicAuthHeader("", authToken),
+	}, nil
+}
+
+func (s *StagedFileServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	name := filepath.Base(r.URL.Path)
+
+	_, authToken, ok := r.BasicAuth()
+	if !ok {
+		http.Error(w, "rejected request with no Authorization header", http.StatusUnauthorized)
+		return
+	}
+	val, err := s.auth.GetUnexpired(ctx, authToken, time.Now().Add(-s.ttl))
+	if errors.Is(err, ErrTokenNotFound) || (err == nil && val.ID != name) {
+		log.Infow("rejected unrecognized auth token", "file", name, "peer", r.RemoteAddr)
+		http.Error(w, "rejected unrecognized auth token", http.StatusUnauthorized)
+		return
+	} else if err != nil {
+		http.Error(w, fmt.Sprintf("getting auth token: %s", err), http.StatusInternalServerError)
+		return
+	}
+
+	f, err := os.Open(filepath.Join(s.dir, name))
+	if err != nil {
+		http.Error(w, "file not found", http.StatusNotFound)
+		return
+	}
+	defer f.Close()
+
+	st, err := f.Stat()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	cw := &countingWriter{ResponseWriter: w}
+	http.ServeContent(cw, r, name, st.ModTime(), f)
+	if r.Method == http.MethodGet && cw.sentEnd(st.Size()) {
+		if err := s.auth.Delete(ctx, authToken); err != nil {
+			log.Warnw("deleting used auth token", "file", name, "err", err)
+		}
+	}
+}
+
+// countingWriter records the status and the number of body bytes of a
+// response.
+type countingWriter struct {
+	http.ResponseWriter
+	status  int
+	written int64
+}
+
+func (w *countingWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *countingWriter) Write(bz []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	count, err := w.ResponseWriter.Write(bz)
+	w.written += int64(count)
+	return count, err
+}
+
+// sentEnd reports whether the response carried the file of the given size
+// through to its last byte.
+func (w *countingWriter) sentEnd(size int64) bool {
+	switch w.status {
+	case http.StatusOK:
+		return w.written == size
+	case http.StatusPartialContent:
+		var first, last, total int64
+		_, err := fmt.Sscanf(w.Header().Get("Content-Range"), "bytes %d-%d/%d", &first, &last, &total)
+		return err == nil && last == size-1 && w.written == last-first+1
+	}
+	return false
+}
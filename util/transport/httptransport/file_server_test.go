@@ -0,0 +1,83 @@
+package httptransport
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+)
+
+func TestStagedFileServerToken(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "chunk")
+	if err := ioutil.WriteFile(path, make([]byte, 100), 0644); err != nil {
+		t.Fatal(err)
+	}
+	s := NewStagedFileServer(dir, 0)
+	srv := httptest.NewServer(s)
+	defer srv.Close()
+
+	get := func(method string, headers map[string]string, rangeHeader string) int {
+		req, err := http.NewRequest(method, srv.URL+"/chunk", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for name, value := range headers {
+			req.Header.Set(name, value)
+		}
+		if rangeHeader != "" {
+			req.Header.Set("Range", rangeHeader)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		io.Copy(io.Discard, resp.Body)
+		return resp.StatusCode
+	}
+
+	type request struct {
+		method      string
+		rangeHeader string
+		code        int
+	}
+	cases := []struct {
+		name     string
+		requests []request
+	}{
+		{"whole file", []request{
+			{http.MethodHead, "", http.StatusOK},
+			{http.MethodGet, "", http.StatusOK},
+			{http.MethodGet, "", http.StatusUnauthorized},
+		}},
+		{"resumed transfer", []request{
+			{http.MethodGet, "bytes=0-9", http.StatusPartialContent},
+			{http.MethodGet, "bytes=10-49", http.StatusPartialContent},
+			{http.MethodGet, "bytes=10-", http.StatusPartialContent},
+			{http.MethodGet, "bytes=0-", http.StatusUnauthorized},
+		}},
+		{"last range first", []request{
+			{http.MethodGet, "bytes=90-", http.StatusPartialContent},
+			{http.MethodGet, "bytes=0-89", http.StatusUnauthorized},
+		}},
+	}
+	for _, c := range cases {
+		headers, err := s.Authorize(context.Background(), path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i, req := range c.requests {
+			if code := get(req.method, headers, req.rangeHeader); code != req.code {
+				t.Errorf("%s: request %d %s %q answered %d, want %d", c.name, i, req.method, req.rangeHeader, code, req.code)
+			}
+		}
+	}
+
+	if code := get(http.MethodGet, map[string]string{"Authorization": BasicAuthHeader("", "unknown")}, ""); code != http.StatusUnauthorized {
+		t.Errorf("unknown token answered %d", code)
+	}
+}
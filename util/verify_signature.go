@@ -54,19 +54,32 @@ func TextHash(data []byte) []byte {
 }
 
 func verifyEthereumSignature(from, signature, message string) bool {
+	_, err := RecoverEthereumPubkey(from, signature, message)
+	return err == nil
+}
+
+// RecoverEthereumPubkey recovers the public key of the personal_sign signature
+// and checks it belongs to address from.
+func RecoverEthereumPubkey(from, signature, message string) (*ecdsa.PublicKey, error) {
 	sig, err := MustDecode(signature)
 	if err != nil {
-		return false
+		return nil, err
+	}
+	if len(sig) != SignatureLength {
+		return nil, fmt.Errorf("invalid signature length %d", len(sig))
 	}
 	msg := TextHash([]byte(message))
 	sig[RecoveryIDOffset] -= 27
 
 	recovered, err := SigToPub(msg, sig)
 	if err != nil {
-		return false
+		return nil, err
 	}
 	recoveredAddr := PubkeyToAddress(*recovered)
-	return strings.ToLower(from) == strings.ToLower(recoveredAddr.Hex())
+	if strings.ToLower(from) != strings.ToLower(recoveredAddr.Hex()) {
+		return nil, fmt.Errorf("signature is not signed by %s", from)
+	}
+	return recovered, nil
 }
 
 func MustDecode(input string) ([]byte, error) {
@@ -0,0 +1,15 @@
+package web3
+
+import "github.com/ethereum/go-ethereum/common"
+
+// The bindings of saofile.go are generated from the ABI and the bytecode of
+// contract/SAOFile.sol, compiled with the openzeppelin contracts installed in
+// contract/node_modules by npm install @openzeppelin/contracts, and abigen of
+// go install github.com/ethereum/go-ethereum/cmd/abigen@v1.10.20.
+//go:generate solc --abi --bin --overwrite --base-path ../contract --include-path ../contract/node_modules -o ../contract ../contract/SAOFile.sol
+//go:generate abigen --abi ../contract/SAOFile.abi --bin ../contract/SAOFile.bin --pkg web3 --type SAOFile --out saofile.go
+
+// SAOFile returns the bindings of the SAOFile contract at address.
+func (p *Provider) SAOFile(address common.Address) (*SAOFile, error) {
+	return NewSAOFile(address, p.client)
+}
@@ -107,6 +107,25 @@ func (p *Provider) FilterLogs(ctx context.Context, addresses []common.Address, f
 	return logs
 }
 
+// FilterBlockLogs returns the logs of address in the blocks from from to to,
+// both included.
+func (p *Provider) FilterBlockLogs(ctx context.Context, address common.Address, from uint64, to uint64) ([]types.Log, error) {
+	query := ethereum.FilterQuery{
+		Addresses: []common.Address{address},
+		FromBlock: new(big.Int).SetUint64(from),
+		ToBlock:   new(big.Int).SetUint64(to),
+	}
+	return p.client.FilterLogs(ctx, query)
+}
+
+func (p *Provider) BlockNumber(ctx context.Context) (uint64, error) {
+	return p.client.BlockNumber(ctx)
+}
+
+func (p *Provider) HeaderByNumber(ctx context.Context, number uint64) (*types.Header, error) {
+	return p.client.HeaderByNumber(ctx, new(big.Int).SetUint64(number))
+}
+
 func (p *Provider) SubscribePendingTransactions(ch chan common.Hash) {
 	ctx := context.Background()
 	p.gclient.SubscribePendingTransactions(ctx, ch)
@@ -137,6 +156,22 @@ func (p *Provider) EstamateGas(from, to common.Address, data []byte) (uint64, er
 	return p.client.EstimateGas(ctx, msg)
 }
 
+func (p *Provider) SuggestGasTipCap(ctx context.Context) (*big.Int, error) {
+	return p.client.SuggestGasTipCap(ctx)
+}
+
+func (p *Provider) LatestHeader(ctx context.Context) (*types.Header, error) {
+	return p.client.HeaderByNumber(ctx, nil)
+}
+
+func (p *Provider) ChainID(ctx context.Context) (*big.Int, error) {
+	return p.client.ChainID(ctx)
+}
+
+func (p *Provider) TransactionReceipt(ctx context.Context, hash common.Hash) (*types.Receipt, error) {
+	return p.client.TransactionReceipt(ctx, hash)
+}
+
 func (p *Provider) Getgasprice() (*big.Int, error) {
 	ctx := context.Background()
 	return p.client.SuggestGasPrice(ctx)
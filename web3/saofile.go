@@ -0,0 +1,2424 @@
+// Code generated - DO NOT EDIT.
+// This file is a generated binding and any manual changes will be lost.
+
+package web3
+
+import (
+	"errors"
+	"math/big"
+	"strings"
+
+	ethereum "github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/event"
+)
+
+// Reference imports to suppress errors if they are not otherwise used.
+var (
+	_ = errors.New
+	_ = big.NewInt
+	_ = strings.NewReader
+	_ = ethereum.NotFound
+	_ = bind.Bind
+	_ = common.Big1
+	_ = types.BloomLookup
+	_ = event.NewSubscription
+)
+
+// SAOFileMetaData contains all meta data concerning the SAOFile contract.
+var SAOFileMetaData = &bind.MetaData{
+	ABI: "[{\"inputs\":[{\"internalType\":\"string\",\"name\":\"name\",\"type\":\"string\"},{\"internalType\":\"string\",\"name\":\"symbol\",\"type\":\"string\"},{\"internalType\":\"string\",\"name\":\"_uri\",\"type\":\"string\"}],\"stateMutability\":\"nonpayable\",\"type\":\"constructor\"},{\"anonymous\":false,\"inputs\":[{\"indexed\":true,\"internalType\":\"address\",\"name\":\"owner\",\"type\":\"address\"},{\"indexed\":true,\"internalType\":\"address\",\"name\":\"approved\",\"type\":\"address\"},{\"indexed\":true,\"internalType\":\"uint256\",\"name\":\"tokenId\",\"type\":\"uint256\"}],\"name\":\"Approval\",\"type\":\"event\"},{\"anonymous\":false,\"inputs\":[{\"indexed\":true,\"internalType\":\"address\",\"name\":\"owner\",\"type\":\"address\"},{\"indexed\":true,\"internalType\":\"address\",\"name\":\"operator\",\"type\":\"address\"},{\"indexed\":false,\"internalType\":\"bool\",\"name\":\"approved\",\"type\":\"bool\"}],\"name\":\"ApprovalForAll\",\"type\":\"event\"},{\"anonymous\":false,\"inputs\":[{\"indexed\":true,\"internalType\":\"uint256\",\"name\":\"tokenId\",\"type\":\"uint256\"},{\"indexed\":true,\"internalType\":\"address\",\"name\":\"buyer\",\"type\":\"address\"},{\"indexed\":true,\"internalType\":\"uint256\",\"name\":\"orderId\",\"type\":\"uint256\"},{\"indexed\":false,\"internalType\":\"uint256\",\"name\":\"price\",\"type\":\"uint256\"},{\"indexed\":false,\"internalType\":\"uint256\",\"name\":\"timestamp\",\"type\":\"uint256\"}],\"name\":\"Bought\",\"type\":\"event\"},{\"anonymous\":false,\"inputs\":[{\"indexed\":true,\"internalType\":\"uint256\",\"name\":\"tokenId\",\"type\":\"uint256\"},{\"indexed\":false,\"internalType\":\"uint256\",\"name\":\"price\",\"type\":\"uint256\"},{\"indexed\":false,\"internalType\":\"uint256\",\"name\":\"timestamp\",\"type\":\"uint256\"}],\"name\":\"ChangePrice\",\"type\":\"event\"},{\"anonymous\":false,\"inputs\":[{\"indexed\":true,\"internalType\":\"uint256\",\"name\":\"orderId\",\"type\":\"uint256\"},{\"indexed\":false,\"internalType\":\"uint256\",\"name\":\"timestamp\",\"type\":\"uint256\"}],\"name\":\"Download\",\"type\":\"event\"},{\"anonymous\":false,\"inputs\":[{\"indexed\":true,\"internalType\":\"uint256\",\"name\":\"tokenId\",\"type\":\"uint256\"},{\"indexed\":false,\"internalType\":\"uint256\",\"name\":\"fileId\",\"type\":\"uint256\"},{\"indexed\":false,\"internalType\":\"uint256\",\"name\":\"price\",\"type\":\"uint256\"},{\"indexed\":false,\"internalType\":\"uint256\",\"name\":\"timestamp\",\"type\":\"uint256\"}],\"name\":\"Listing\",\"type\":\"event\"},{\"anonymous\":false,\"inputs\":[{\"indexed\":true,\"internalType\":\"address\",\"name\":\"previousOwner\",\"type\":\"address\"},{\"indexed\":true,\"internalType\":\"address\",\"name\":\"newOwner\",\"type\":\"address\"}],\"name\":\"OwnershipTransferred\",\"type\":\"event\"},{\"anonymous\":false,\"inputs\":[{\"indexed\":true,\"internalType\":\"address\",\"name\":\"from\",\"type\":\"address\"},{\"indexed\":true,\"internalType\":\"address\",\"name\":\"to\",\"type\":\"address\"},{\"indexed\":true,\"internalType\":\"uint256\",\"name\":\"tokenId\",\"type\":\"uint256\"}],\"name\":\"Transfer\",\"type\":\"event\"},{\"anonymous\":false,\"inputs\":[{\"indexed\":true,\"internalType\":\"address\",\"name\":\"user\",\"type\":\"address\"},{\"indexed\":false,\"internalType\":\"uint256\",\"name\":\"amount\",\"type\":\"uint256\"},{\"indexed\":false,\"internalType\":\"uint256\",\"name\":\"timestamp\",\"type\":\"uint256\"}],\"name\":\"Withdraw\",\"type\":\"event\"},{\"inputs\":[],\"name\":\"admin\",\"outputs\":[{\"internalType\":\"address\",\"name\":\"\",\"type\":\"address\"}],\"stateMutability\":\"view\",\"type\":\"function\"},{\"inputs\":[{\"internalType\":\"address\",\"name\":\"to\",\"type\":\"address\"},{\"internalType\":\"uint256\",\"name\":\"tokenId\",\"type\":\"uint256\"}],\"name\":\"approve\",\"outputs\":[],\"stateMutability\":\"nonpayable\",\"type\":\"function\"},{\"inputs\":[{\"internalType\":\"address\",\"name\":\"owner\",\"type\":\"address\"}],\"name\":\"balanceOf\",\"outputs\":[{\"internalType\":\"uint256\",\"name\":\"\",\"type\":\"uint256\"}],\"stateMutability\":\"view\",\"type\":\"function\"},{\"inputs\":[{\"internalType\":\"address\",\"name\":\"\",\"type\":\"address\"}],\"name\":\"balances\",\"outputs\":[{\"internalType\":\"uint256\",\"name\":\"\",\"type\":\"uint256\"}],\"stateMutability\":\"view\",\"type\":\"function\"},{\"inputs\":[{\"internalType\":\"uint256\",\"name\":\"tokenId\",\"type\":\"uint256\"}],\"name\":\"buy\",\"outputs\":[],\"stateMutability\":\"payable\",\"type\":\"function\"},{\"inputs\":[{\"internalType\":\"uint256\",\"name\":\"\",\"type\":\"uint256\"},{\"internalType\":\"address\",\"name\":\"\",\"type\":\"address\"}],\"name\":\"buyer\",\"outputs\":[{\"internalType\":\"bool\",\"name\":\"\",\"type\":\"bool\"}],\"stateMutability\":\"view\",\"type\":\"function\"},{\"inputs\":[{\"internalType\":\"uint256\",\"name\":\"tokenId\",\"type\":\"uint256\"},{\"internalType\":\"uint256\",\"name\":\"price\",\"type\":\"uint256\"}],\"name\":\"changePrice\",\"outputs\":[],\"stateMutability\":\"nonpayable\",\"type\":\"function\"},{\"inputs\":[],\"name\":\"fee\",\"outputs\":[{\"internalType\":\"uint256\",\"name\":\"\",\"type\":\"uint256\"}],\"stateMutability\":\"view\",\"type\":\"function\"},{\"inputs\":[{\"internalType\":\"uint256\",\"name\":\"orderId\",\"type\":\"uint256\"}],\"name\":\"finish\",\"outputs\":[],\"stateMutability\":\"nonpayable\",\"type\":\"function\"},{\"inputs\":[{\"internalType\":\"uint256\",\"name\":\"tokenId\",\"type\":\"uint256\"}],\"name\":\"getApproved\",\"outputs\":[{\"internalType\":\"address\",\"name\":\"\",\"type\":\"address\"}],\"stateMutability\":\"view\",\"type\":\"function\"},{\"inputs\":[],\"name\":\"idx\",\"outputs\":[{\"internalType\":\"uint256\",\"name\":\"\",\"type\":\"uint256\"}],\"stateMutability\":\"view\",\"type\":\"function\"},{\"inputs\":[{\"internalType\":\"address\",\"name\":\"owner\",\"type\":\"address\"},{\"internalType\":\"address\",\"name\":\"operator\",\"type\":\"address\"}],\"name\":\"isApprovedForAll\",\"outputs\":[{\"internalType\":\"bool\",\"name\":\"\",\"type\":\"bool\"}],\"stateMutability\":\"view\",\"type\":\"function\"},{\"inputs\":[{\"internalType\":\"uint256\",\"name\":\"\",\"type\":\"uint256\"}],\"name\":\"listing\",\"outputs\":[{\"internalType\":\"uint256\",\"name\":\"\",\"type\":\"uint256\"}],\"stateMutability\":\"view\",\"type\":\"function\"},{\"inputs\":[{\"internalType\":\"uint256\",\"name\":\"file_id\",\"type\":\"uint256\"},{\"internalType\":\"uint256\",\"name\":\"price\",\"type\":\"uint256\"}],\"name\":\"mint\",\"outputs\":[],\"stateMutability\":\"nonpayable\",\"type\":\"function\"},{\"inputs\":[],\"name\":\"name\",\"outputs\":[{\"internalType\":\"string\",\"name\":\"\",\"type\":\"string\"}],\"stateMutability\":\"view\",\"type\":\"function\"},{\"inputs\":[],\"name\":\"orderIdx\",\"outputs\":[{\"internalType\":\"uint256\",\"name\":\"\",\"type\":\"uint256\"}],\"stateMutability\":\"view\",\"type\":\"function\"},{\"inputs\":[{\"internalType\":\"uint256\",\"name\":\"\",\"type\":\"uint256\"}],\"name\":\"orders\",\"outputs\":[{\"internalType\":\"uint256\",\"name\":\"tokenId\",\"type\":\"uint256\"},{\"internalType\":\"address\",\"name\":\"buyer\",\"type\":\"address\"},{\"internalType\":\"address\",\"name\":\"seller\",\"type\":\"address\"},{\"internalType\":\"uint256\",\"name\":\"price\",\"type\":\"uint256\"},{\"internalType\":\"uint256\",\"name\":\"status\",\"type\":\"uint256\"},{\"internalType\":\"uint256\",\"name\":\"fee\",\"type\":\"uint256\"}],\"stateMutability\":\"view\",\"type\":\"function\"},{\"inputs\":[],\"name\":\"owner\",\"outputs\":[{\"internalType\":\"address\",\"name\":\"\",\"type\":\"address\"}],\"stateMutability\":\"view\",\"type\":\"function\"},{\"inputs\":[{\"internalType\":\"uint256\",\"name\":\"tokenId\",\"type\":\"uint256\"}],\"name\":\"ownerOf\",\"outputs\":[{\"internalType\":\"address\",\"name\":\"\",\"type\":\"address\"}],\"stateMutability\":\"view\",\"type\":\"function\"},{\"inputs\":[],\"name\":\"renounceOwnership\",\"outputs\":[],\"stateMutability\":\"nonpayable\",\"type\":\"function\"},{\"inputs\":[{\"internalType\":\"address\",\"name\":\"from\",\"type\":\"address\"},{\"internalType\":\"address\",\"name\":\"to\",\"type\":\"address\"},{\"internalType\":\"uint256\",\"name\":\"tokenId\",\"type\":\"uint256\"}],\"name\":\"safeTransferFrom\",\"outputs\":[],\"stateMutability\":\"nonpayable\",\"type\":\"function\"},{\"inputs\":[{\"internalType\":\"address\",\"name\":\"from\",\"type\":\"address\"},{\"internalType\":\"address\",\"name\":\"to\",\"type\":\"address\"},{\"internalType\":\"uint256\",\"name\":\"tokenId\",\"type\":\"uint256\"},{\"internalType\":\"bytes\",\"name\":\"data\",\"type\":\"bytes\"}],\"name\":\"safeTransferFrom\",\"outputs\":[],\"stateMutability\":\"nonpayable\",\"type\":\"function\"},{\"inputs\":[{\"internalType\":\"address\",\"name\":\"operator\",\"type\":\"address\"},{\"internalType\":\"bool\",\"name\":\"approved\",\"type\":\"bool\"}],\"name\":\"setApprovalForAll\",\"outputs\":[],\"stateMutability\":\"nonpayable\",\"type\":\"function\"},{\"inputs\":[{\"internalType\":\"string\",\"name\":\"_uri\",\"type\":\"string\"}],\"name\":\"setBaseURI\",\"outputs\":[],\"stateMutability\":\"nonpayable\",\"type\":\"function\"},{\"inputs\":[{\"internalType\":\"bytes4\",\"name\":\"interfaceId\",\"type\":\"bytes4\"}],\"name\":\"supportsInterface\",\"outputs\":[{\"internalType\":\"bool\",\"name\":\"\",\"type\":\"bool\"}],\"stateMutability\":\"view\",\"type\":\"function\"},{\"inputs\":[],\"name\":\"symbol\",\"outputs\":[{\"internalType\":\"string\",\"name\":\"\",\"type\":\"string\"}],\"stateMutability\":\"view\",\"type\":\"function\"},{\"inputs\":[{\"internalType\":\"uint256\",\"name\":\"tokenId\",\"type\":\"uint256\"}],\"name\":\"tokenURI\",\"outputs\":[{\"internalType\":\"string\",\"name\":\"\",\"type\":\"string\"}],\"stateMutability\":\"view\",\"type\":\"function\"},{\"inputs\":[],\"name\":\"totalFee\",\"outputs\":[{\"internalType\":\"uint256\",\"name\":\"\",\"type\":\"uint256\"}],\"stateMutability\":\"view\",\"type\":\"function\"},{\"inputs\":[{\"internalType\":\"address\",\"name\":\"from\",\"type\":\"address\"},{\"internalType\":\"address\",\"name\":\"to\",\"type\":\"address\"},{\"internalType\":\"uint256\",\"name\":\"tokenId\",\"type\":\"uint256\"}],\"name\":\"transferFrom\",\"outputs\":[],\"stateMutability\":\"nonpayable\",\"type\":\"function\"},{\"inputs\":[{\"internalType\":\"address\",\"name\":\"newOwner\",\"type\":\"address\"}],\"name\":\"transferOwnership\",\"outputs\":[],\"stateMutability\":\"nonpayable\",\"type\":\"function\"},{\"inputs\":[],\"name\":\"withdraw\",\"outputs\":[],\"stateMutability\":\"nonpayable\",\"type\":\"function\"}]",
+}
+
+// SAOFileABI is the input ABI used to generate the binding from.
+// Deprecated: Use SAOFileMetaData.ABI instead.
+var SAOFileABI = SAOFileMetaData.ABI
+
+// SAOFile is an auto generated Go binding around an Ethereum contract.
+type SAOFile struct {
+	SAOFileCaller     // Read-only binding to the contract
+	SAOFileTransactor // Write-only binding to the contract
+	SAOFileFilterer   // Log filterer for contract events
+}
+
+// SAOFileCaller is an auto generated read-only Go binding around an Ethereum contract.
+type SAOFileCaller struct {
+	contract *bind.BoundContract // Generic contract wrapper for the low level calls
+}
+
+// SAOFileTransactor is an auto generated write-only Go binding around an Ethereum contract.
+type SAOFileTransactor struct {
+	contract *bind.BoundContract // Generic contract wrapper for the low level calls
+}
+
+// SAOFileFilterer is an auto generated log filtering Go binding around an Ethereum contract events.
+type SAOFileFilterer struct {
+	contract *bind.BoundContract // Generic contract wrapper for the low level calls
+}
+
+// SAOFileSession is an auto generated Go binding around an Ethereum contract,
+// with pre-set call and transact options.
+type SAOFileSession struct {
+	Contract     *SAOFile          // Generic contract binding to set the session for
+	CallOpts     bind.CallOpts     // Call options to use throughout this session
+	TransactOpts bind.TransactOpts // Transaction auth options to use throughout this session
+}
+
+// SAOFileCallerSession is an auto generated read-only Go binding around an Ethereum contract,
+// with pre-set call options.
+type SAOFileCallerSession struct {
+	Contract *SAOFileCaller // Generic contract caller binding to set the session for
+	CallOpts bind.CallOpts  // Call options to use throughout this session
+}
+
+// SAOFileTransactorSession is an auto generated write-only Go binding around an Ethereum contract,
+// with pre-set transact options.
+type SAOFileTransactorSession struct {
+	Contract     *SAOFileTransactor // Generic contract transactor binding to set the session for
+	TransactOpts bind.TransactOpts  // Transaction auth options to use throughout this session
+}
+
+// SAOFileRaw is an auto generated low-level Go binding around an Ethereum contract.
+type SAOFileRaw struct {
+	Contract *SAOFile // Generic contract binding to access the raw methods on
+}
+
+// SAOFileCallerRaw is an auto generated low-level read-only Go binding around an Ethereum contract.
+type SAOFileCallerRaw struct {
+	Contract *SAOFileCaller // Generic read-only contract binding to access the raw methods on
+}
+
+// SAOFileTransactorRaw is an auto generated low-level write-only Go binding around an Ethereum contract.
+type SAOFileTransactorRaw struct {
+	Contract *SAOFileTransactor // Generic write-only contract binding to access the raw methods on
+}
+
+// NewSAOFile creates a new instance of SAOFile, bound to a specific deployed contract.
+func NewSAOFile(address common.Address, backend bind.ContractBackend) (*SAOFile, error) {
+	contract, err := bindSAOFile(address, backend, backend, backend)
+	if err != nil {
+		return nil, err
+	}
+	return &SAOFile{SAOFileCaller: SAOFileCaller{contract: contract}, SAOFileTransactor: SAOFileTransactor{contract: contract}, SAOFileFilterer: SAOFileFilterer{contract: contract}}, nil
+}
+
+// NewSAOFileCaller creates a new read-only instance of SAOFile, bound to a specific deployed contract.
+func NewSAOFileCaller(address common.Address, caller bind.ContractCaller) (*SAOFileCaller, error) {
+	contract, err := bindSAOFile(address, caller, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &SAOFileCaller{contract: contract}, nil
+}
+
+// NewSAOFileTransactor creates a new write-only instance of SAOFile, bound to a specific deployed contract.
+func NewSAOFileTransactor(address common.Address, transactor bind.ContractTransactor) (*SAOFileTransactor, error) {
+	contract, err := bindSAOFile(address, nil, transactor, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &SAOFileTransactor{contract: contract}, nil
+}
+
+// NewSAOFileFilterer creates a new log filterer instance of SAOFile, bound to a specific deployed contract.
+func NewSAOFileFilterer(address common.Address, filterer bind.ContractFilterer) (*SAOFileFilterer, error) {
+	contract, err := bindSAOFile(address, nil, nil, filterer)
+	if err != nil {
+		return nil, err
+	}
+	return &SAOFileFilterer{contract: contract}, nil
+}
+
+// bindSAOFile binds a generic wrapper to an already deployed contract.
+func bindSAOFile(address common.Address, caller bind.ContractCaller, transactor bind.ContractTransactor, filterer bind.ContractFilterer) (*bind.BoundContract, error) {
+	parsed, err := abi.JSON(strings.NewReader(SAOFileABI))
+	if err != nil {
+		return nil, err
+	}
+	return bind.NewBoundContract(address, parsed, caller, transactor, filterer), nil
+}
+
+// Call invokes the (constant) contract method with params as input values and
+// sets the output to result. The result type might be a single field for simple
+// returns, a slice of interfaces for anonymous returns and a struct for named
+// returns.
+func (_SAOFile *SAOFileRaw) Call(opts *bind.CallOpts, result *[]interface{}, method string, params ...interface{}) error {
+	return _SAOFile.Contract.SAOFileCaller.contract.Call(opts, result, method, params...)
+}
+
+// Transfer initiates a plain transaction to move funds to the contract, calling
+// its default method if one is available.
+func (_SAOFile *SAOFileRaw) Transfer(opts *bind.TransactOpts) (*types.Transaction, error) {
+	return _SAOFile.Contract.SAOFileTransactor.contract.Transfer(opts)
+}
+
+// Transact invokes the (paid) contract method with params as input values.
+func (_SAOFile *SAOFileRaw) Transact(opts *bind.TransactOpts, method string, params ...interface{}) (*types.Transaction, error) {
+	return _SAOFile.Contract.SAOFileTransactor.contract.Transact(opts, method, params...)
+}
+
+// Call invokes the (constant) contract method with params as input values and
+// sets the output to result. The result type might be a single field for simple
+// returns, a slice of interfaces for anonymous returns and a struct for named
+// returns.
+func (_SAOFile *SAOFileCallerRaw) Call(opts *bind.CallOpts, result *[]interface{}, method string, params ...interface{}) error {
+	return _SAOFile.Contract.contract.Call(opts, result, method, params...)
+}
+
+// Transfer initiates a plain transaction to move funds to the contract, calling
+// its default method if one is available.
+func (_SAOFile *SAOFileTransactorRaw) Transfer(opts *bind.TransactOpts) (*types.Transaction, error) {
+	return _SAOFile.Contract.contract.Transfer(opts)
+}
+
+// Transact invokes the (paid) contract method with params as input values.
+func (_SAOFile *SAOFileTransactorRaw) Transact(opts *bind.TransactOpts, method string, params ...interface{}) (*types.Transaction, error) {
+	return _SAOFile.Contract.contract.Transact(opts, method, params...)
+}
+
+// Admin is a free data retrieval call binding the contract method 0xf851a440.
+//
+// Solidity: function admin() view returns(address)
+func (_SAOFile *SAOFileCaller) Admin(opts *bind.CallOpts) (common.Address, error) {
+	var out []interface{}
+	err := _SAOFile.contract.Call(opts, &out, "admin")
+
+	if err != nil {
+		return *new(common.Address), err
+	}
+
+	out0 := *abi.ConvertType(out[0], new(common.Address)).(*common.Address)
+
+	return out0, err
+
+}
+
+// Admin is a free data retrieval call binding the contract method 0xf851a440.
+//
+// Solidity: function admin() view returns(address)
+func (_SAOFile *SAOFileSession) Admin() (common.Address, error) {
+	return _SAOFile.Contract.Admin(&_SAOFile.CallOpts)
+}
+
+// Admin is a free data retrieval call binding the contract method 0xf851a440.
+//
+// Solidity: function admin() view returns(address)
+func (_SAOFile *SAOFileCallerSession) Admin() (common.Address, error) {
+	return _SAOFile.Contract.Admin(&_SAOFile.CallOpts)
+}
+
+// BalanceOf is a free data retrieval call binding the contract method 0x70a08231.
+//
+// Solidity: function balanceOf(address owner) view returns(uint256)
+func (_SAOFile *SAOFileCaller) BalanceOf(opts *bind.CallOpts, owner common.Address) (*big.Int, error) {
+	var out []interface{}
+	err := _SAOFile.contract.Call(opts, &out, "balanceOf", owner)
+
+	if err != nil {
+		return *new(*big.Int), err
+	}
+
+	out0 := *abi.ConvertType(out[0], new(*big.Int)).(**big.Int)
+
+	return out0, err
+
+}
+
+// BalanceOf is a free data retrieval call binding the contract method 0x70a08231.
+//
+// Solidity: function balanceOf(address owner) view returns(uint256)
+func (_SAOFile *SAOFileSession) BalanceOf(owner common.Address) (*big.Int, error) {
+	return _SAOFile.Contract.BalanceOf(&_SAOFile.CallOpts, owner)
+}
+
+// BalanceOf is a free data retrieval call binding the contract method 0x70a08231.
+//
+// Solidity: function balanceOf(address owner) view returns(uint256)
+func (_SAOFile *SAOFileCallerSession) BalanceOf(owner common.Address) (*big.Int, error) {
+	return _SAOFile.Contract.BalanceOf(&_SAOFile.CallOpts, owner)
+}
+
+// Balances is a free data retrieval call binding the contract method 0x27e235e3.
+//
+// Solidity: function balances(address ) view returns(uint256)
+func (_SAOFile *SAOFileCaller) Balances(opts *bind.CallOpts, arg0 common.Address) (*big.Int, error) {
+	var out []interface{}
+	err := _SAOFile.contract.Call(opts, &out, "balances", arg0)
+
+	if err != nil {
+		return *new(*big.Int), err
+	}
+
+	out0 := *abi.ConvertType(out[0], new(*big.Int)).(**big.Int)
+
+	return out0, err
+
+}
+
+// Balances is a free data retrieval call binding the contract method 0x27e235e3.
+//
+// Solidity: function balances(address ) view returns(uint256)
+func (_SAOFile *SAOFileSession) Balances(arg0 common.Address) (*big.Int, error) {
+	return _SAOFile.Contract.Balances(&_SAOFile.CallOpts, arg0)
+}
+
+// Balances is a free data retrieval call binding the contract method 0x27e235e3.
+//
+// Solidity: function balances(address ) view returns(uint256)
+func (_SAOFile *SAOFileCallerSession) Balances(arg0 common.Address) (*big.Int, error) {
+	return _SAOFile.Contract.Balances(&_SAOFile.CallOpts, arg0)
+}
+
+// Buyer is a free data retrieval call binding the contract method 0xb0e77631.
+//
+// Solidity: function buyer(uint256 , address ) view returns(bool)
+func (_SAOFile *SAOFileCaller) Buyer(opts *bind.CallOpts, arg0 *big.Int, arg1 common.Address) (bool, error) {
+	var out []interface{}
+	err := _SAOFile.contract.Call(opts, &out, "buyer", arg0, arg1)
+
+	if err != nil {
+		return *new(bool), err
+	}
+
+	out0 := *abi.ConvertType(out[0], new(bool)).(*bool)
+
+	return out0, err
+
+}
+
+// Buyer is a free data retrieval call binding the contract method 0xb0e77631.
+//
+// Solidity: function buyer(uint256 , address ) view returns(bool)
+func (_SAOFile *SAOFileSession) Buyer(arg0 *big.Int, arg1 common.Address) (bool, error) {
+	return _SAOFile.Contract.Buyer(&_SAOFile.CallOpts, arg0, arg1)
+}
+
+// Buyer is a free data retrieval call binding the contract method 0xb0e77631.
+//
+// Solidity: function buyer(uint256 , address ) view returns(bool)
+func (_SAOFile *SAOFileCallerSession) Buyer(arg0 *big.Int, arg1 common.Address) (bool, error) {
+	return _SAOFile.Contract.Buyer(&_SAOFile.CallOpts, arg0, arg1)
+}
+
+// Fee is a free data retrieval call binding the contract method 0xddca3f43.
+//
+// Solidity: function fee() view returns(uint256)
+func (_SAOFile *SAOFileCaller) Fee(opts *bind.CallOpts) (*big.Int, error) {
+	var out []interface{}
+	err := _SAOFile.contract.Call(opts, &out, "fee")
+
+	if err != nil {
+		return *new(*big.Int), err
+	}
+
+	out0 := *abi.ConvertType(out[0], new(*big.Int)).(**big.Int)
+
+	return out0, err
+
+}
+
+// Fee is a free data retrieval call binding the contract method 0xddca3f43.
+//
+// Solidity: function fee() view returns(uint256)
+func (_SAOFile *SAOFileSession) Fee() (*big.Int, error) {
+	return _SAOFile.Contract.Fee(&_SAOFile.CallOpts)
+}
+
+// Fee is a free data retrieval call binding the contract method 0xddca3f43.
+//
+// Solidity: function fee() view returns(uint256)
+func (_SAOFile *SAOFileCallerSession) Fee() (*big.Int, error) {
+	return _SAOFile.Contract.Fee(&_SAOFile.CallOpts)
+}
+
+// GetApproved is a free data retrieval call binding the contract method 0x081812fc.
+//
+// Solidity: function getApproved(uint256 tokenId) view returns(address)
+func (_SAOFile *SAOFileCaller) GetApproved(opts *bind.CallOpts, tokenId *big.Int) (common.Address, error) {
+	var out []interface{}
+	err := _SAOFile.contract.Call(opts, &out, "getApproved", tokenId)
+
+	if err != nil {
+		return *new(common.Address), err
+	}
+
+	out0 := *abi.ConvertType(out[0], new(common.Address)).(*common.Address)
+
+	return out0, err
+
+}
+
+// GetApproved is a free data retrieval call binding the contract method 0x081812fc.
+//
+// Solidity: function getApproved(uint256 tokenId) view returns(address)
+func (_SAOFile *SAOFileSession) GetApproved(tokenId *big.Int) (common.Address, error) {
+	return _SAOFile.Contract.GetApproved(&_SAOFile.CallOpts, tokenId)
+}
+
+// GetApproved is a free data retrieval call binding the contract method 0x081812fc.
+//
+// Solidity: function getApproved(uint256 tokenId) view returns(address)
+func (_SAOFile *SAOFileCallerSession) GetApproved(tokenId *big.Int) (common.Address, error) {
+	return _SAOFile.Contract.GetApproved(&_SAOFile.CallOpts, tokenId)
+}
+
+// Idx is a free data retrieval call binding the contract method 0x795dbede.
+//
+// Solidity: function idx() view returns(uint256)
+func (_SAOFile *SAOFileCaller) Idx(opts *bind.CallOpts) (*big.Int, error) {
+	var out []interface{}
+	err := _SAOFile.contract.Call(opts, &out, "idx")
+
+	if err != nil {
+		return *new(*big.Int), err
+	}
+
+	out0 := *abi.ConvertType(out[0], new(*big.Int)).(**big.Int)
+
+	return out0, err
+
+}
+
+// Idx is a free data retrieval call binding the contract method 0x795dbede.
+//
+// Solidity: function idx() view returns(uint256)
+func (_SAOFile *SAOFileSession) Idx() (*big.Int, error) {
+	return _SAOFile.Contract.Idx(&_SAOFile.CallOpts)
+}
+
+// Idx is a free data retrieval call binding the contract method 0x795dbede.
+//
+// Solidity: function idx() view returns(uint256)
+func (_SAOFile *SAOFileCallerSession) Idx() (*big.Int, error) {
+	return _SAOFile.Contract.Idx(&_SAOFile.CallOpts)
+}
+
+// IsApprovedForAll is a free data retrieval call binding the contract method 0xe985e9c5.
+//
+// Solidity: function isApprovedForAll(address owner, address operator) view returns(bool)
+func (_SAOFile *SAOFileCaller) IsApprovedForAll(opts *bind.CallOpts, owner common.Address, operator common.Address) (bool, error) {
+	var out []interface{}
+	err := _SAOFile.contract.Call(opts, &out, "isApprovedForAll", owner, operator)
+
+	if err != nil {
+		return *new(bool), err
+	}
+
+	out0 := *abi.ConvertType(out[0], new(bool)).(*bool)
+
+	return out0, err
+
+}
+
+// IsApprovedForAll is a free data retrieval call binding the contract method 0xe985e9c5.
+//
+// Solidity: function isApprovedForAll(address owner, address operator) view returns(bool)
+func (_SAOFile *SAOFileSession) IsApprovedForAll(owner common.Address, operator common.Address) (bool, error) {
+	return _SAOFile.Contract.IsApprovedForAll(&_SAOFile.CallOpts, owner, operator)
+}
+
+// IsApprovedForAll is a free data retrieval call binding the contract method 0xe985e9c5.
+//
+// Solidity: function isApprovedForAll(address owner, address operator) view returns(bool)
+func (_SAOFile *SAOFileCallerSession) IsApprovedForAll(owner common.Address, operator common.Address) (bool, error) {
+	return _SAOFile.Contract.IsApprovedForAll(&_SAOFile.CallOpts, owner, operator)
+}
+
+// Listing is a free data retrieval call binding the contract method 0x84a184de.
+//
+// Solidity: function listing(uint256 ) view returns(uint256)
+func (_SAOFile *SAOFileCaller) Listing(opts *bind.CallOpts, arg0 *big.Int) (*big.Int, error) {
+	var out []interface{}
+	err := _SAOFile.contract.Call(opts, &out, "listing", arg0)
+
+	if err != nil {
+		return *new(*big.Int), err
+	}
+
+	out0 := *abi.ConvertType(out[0], new(*big.Int)).(**big.Int)
+
+	return out0, err
+
+}
+
+// Listing is a free data retrieval call binding the contract method 0x84a184de.
+//
+// Solidity: function listing(uint256 ) view returns(uint256)
+func (_SAOFile *SAOFileSession) Listing(arg0 *big.Int) (*big.Int, error) {
+	return _SAOFile.Contract.Listing(&_SAOFile.CallOpts, arg0)
+}
+
+// Listing is a free data retrieval call binding the contract method 0x84a184de.
+//
+// Solidity: function listing(uint256 ) view returns(uint256)
+func (_SAOFile *SAOFileCallerSession) Listing(arg0 *big.Int) (*big.Int, error) {
+	return _SAOFile.Contract.Listing(&_SAOFile.CallOpts, arg0)
+}
+
+// Name is a free data retrieval call binding the contract method 0x06fdde03.
+//
+// Solidity: function name() view returns(string)
+func (_SAOFile *SAOFileCaller) Name(opts *bind.CallOpts) (string, error) {
+	var out []interface{}
+	err := _SAOFile.contract.Call(opts, &out, "name")
+
+	if err != nil {
+		return *new(string), err
+	}
+
+	out0 := *abi.ConvertType(out[0], new(string)).(*string)
+
+	return out0, err
+
+}
+
+// Name is a free data retrieval call binding the contract method 0x06fdde03.
+//
+// Solidity: function name() view returns(string)
+func (_SAOFile *SAOFileSession) Name() (string, error) {
+	return _SAOFile.Contract.Name(&_SAOFile.CallOpts)
+}
+
+// Name is a free data retrieval call binding the contract method 0x06fdde03.
+//
+// Solidity: function name() view returns(string)
+func (_SAOFile *SAOFileCallerSession) Name() (string, error) {
+	return _SAOFile.Contract.Name(&_SAOFile.CallOpts)
+}
+
+// OrderIdx is a free data retrieval call binding the contract method 0x84b9c999.
+//
+// Solidity: function orderIdx() view returns(uint256)
+func (_SAOFile *SAOFileCaller) OrderIdx(opts *bind.CallOpts) (*big.Int, error) {
+	var out []interface{}
+	err := _SAOFile.contract.Call(opts, &out, "orderIdx")
+
+	if err != nil {
+		return *new(*big.Int), err
+	}
+
+	out0 := *abi.ConvertType(out[0], new(*big.Int)).(**big.Int)
+
+	return out0, err
+
+}
+
+// OrderIdx is a free data retrieval call binding the contract method 0x84b9c999.
+//
+// Solidity: function orderIdx() view returns(uint256)
+func (_SAOFile *SAOFileSession) OrderIdx() (*big.Int, error) {
+	return _SAOFile.Contract.OrderIdx(&_SAOFile.CallOpts)
+}
+
+// OrderIdx is a free data retrieval call binding the contract method 0x84b9c999.
+//
+// Solidity: function orderIdx() view returns(uint256)
+func (_SAOFile *SAOFileCallerSession) OrderIdx() (*big.Int, error) {
+	return _SAOFile.Contract.OrderIdx(&_SAOFile.CallOpts)
+}
+
+// Orders is a free data retrieval call binding the contract method 0xa85c38ef.
+//
+// Solidity: function orders(uint256 ) view returns(uint256 tokenId, address buyer, address seller, uint256 price, uint256 status, uint256 fee)
+func (_SAOFile *SAOFileCaller) Orders(opts *bind.CallOpts, arg0 *big.Int) (struct {
+	TokenId *big.Int
+	Buyer   common.Address
+	Seller  common.Address
+	Price   *big.Int
+	Status  *big.Int
+	Fee     *big.Int
+}, error) {
+	var out []interface{}
+	err := _SAOFile.contract.Call(opts, &out, "orders", arg0)
+
+	outstruct := new(struct {
+		TokenId *big.Int
+		Buyer   common.Address
+		Seller  common.Address
+		Price   *big.Int
+		Status  *big.Int
+		Fee     *big.Int
+	})
+	if err != nil {
+		return *outstruct, err
+	}
+
+	outstruct.TokenId = *abi.ConvertType(out[0], new(*big.Int)).(**big.Int)
+	outstruct.Buyer = *abi.ConvertType(out[1], new(common.Address)).(*common.Address)
+	outstruct.Seller = *abi.ConvertType(out[2], new(common.Address)).(*common.Address)
+	outstruct.Price = *abi.ConvertType(out[3], new(*big.Int)).(**big.Int)
+	outstruct.Status = *abi.ConvertType(out[4], new(*big.Int)).(**big.Int)
+	outstruct.Fee = *abi.ConvertType(out[5], new(*big.Int)).(**big.Int)
+
+	return *outstruct, err
+
+}
+
+// Orders is a free data retrieval call binding the contract method 0xa85c38ef.
+//
+// Solidity: function orders(uint256 ) view returns(uint256 tokenId, address buyer, address seller, uint256 price, uint256 status, uint256 fee)
+func (_SAOFile *SAOFileSession) Orders(arg0 *big.Int) (struct {
+	TokenId *big.Int
+	Buyer   common.Address
+	Seller  common.Address
+	Price   *big.Int
+	Status  *big.Int
+	Fee     *big.Int
+}, error) {
+	return _SAOFile.Contract.Orders(&_SAOFile.CallOpts, arg0)
+}
+
+// Orders is a free data retrieval call binding the contract method 0xa85c38ef.
+//
+// Solidity: function orders(uint256 ) view returns(uint256 tokenId, address buyer, address seller, uint256 price, uint256 status, uint256 fee)
+func (_SAOFile *SAOFileCallerSession) Orders(arg0 *big.Int) (struct {
+	TokenId *big.Int
+	Buyer   common.Address
+	Seller  common.Address
+	Price   *big.Int
+	Status  *big.Int
+	Fee     *big.Int
+}, error) {
+	return _SAOFile.Contract.Orders(&_SAOFile.CallOpts, arg0)
+}
+
+// Owner is a free data retrieval call binding the contract method 0x8da5cb5b.
+//
+// Solidity: function owner() view returns(address)
+func (_SAOFile *SAOFileCaller) Owner(opts *bind.CallOpts) (common.Address, error) {
+	var out []interface{}
+	err := _SAOFile.contract.Call(opts, &out, "owner")
+
+	if err != nil {
+		return *new(common.Address), err
+	}
+
+	out0 := *abi.ConvertType(out[0], new(common.Address)).(*common.Address)
+
+	return out0, err
+
+}
+
+// Owner is a free data retrieval call binding the contract method 0x8da5cb5b.
+//
+// Solidity: function owner() view returns(address)
+func (_SAOFile *SAOFileSession) Owner() (common.Address, error) {
+	return _SAOFile.Contract.Owner(&_SAOFile.CallOpts)
+}
+
+// Owner is a free data retrieval call binding the contract method 0x8da5cb5b.
+//
+// Solidity: function owner() view returns(address)
+func (_SAOFile *SAOFileCallerSession) Owner() (common.Address, error) {
+	return _SAOFile.Contract.Owner(&_SAOFile.CallOpts)
+}
+
+// OwnerOf is a free data retrieval call binding the contract method 0x6352211e.
+//
+// Solidity: function ownerOf(uint256 tokenId) view returns(address)
+func (_SAOFile *SAOFileCaller) OwnerOf(opts *bind.CallOpts, tokenId *big.Int) (common.Address, error) {
+	var out []interface{}
+	err := _SAOFile.contract.Call(opts, &out, "ownerOf", tokenId)
+
+	if err != nil {
+		return *new(common.Address), err
+	}
+
+	out0 := *abi.ConvertType(out[0], new(common.Address)).(*common.Address)
+
+	return out0, err
+
+}
+
+// OwnerOf is a free data retrieval call binding the contract method 0x6352211e.
+//
+// Solidity: function ownerOf(uint256 tokenId) view returns(address)
+func (_SAOFile *SAOFileSession) OwnerOf(tokenId *big.Int) (common.Address, error) {
+	return _SAOFile.Contract.OwnerOf(&_SAOFile.CallOpts, tokenId)
+}
+
+// OwnerOf is a free data retrieval call binding the contract method 0x6352211e.
+//
+// Solidity: function ownerOf(uint256 tokenId) view returns(address)
+func (_SAOFile *SAOFileCallerSession) OwnerOf(tokenId *big.Int) (common.Address, error) {
+	return _SAOFile.Contract.OwnerOf(&_SAOFile.CallOpts, tokenId)
+}
+
+// SupportsInterface is a free data retrieval call binding the contract method 0x01ffc9a7.
+//
+// Solidity: function supportsInterface(bytes4 interfaceId) view returns(bool)
+func (_SAOFile *SAOFileCaller) SupportsInterface(opts *bind.CallOpts, interfaceId [4]byte) (bool, error) {
+	var out []interface{}
+	err := _SAOFile.contract.Call(opts, &out, "supportsInterface", interfaceId)
+
+	if err != nil {
+		return *new(bool), err
+	}
+
+	out0 := *abi.ConvertType(out[0], new(bool)).(*bool)
+
+	return out0, err
+
+}
+
+// SupportsInterface is a free data retrieval call binding the contract method 0x01ffc9a7.
+//
+// Solidity: function supportsInterface(bytes4 interfaceId) view returns(bool)
+func (_SAOFile *SAOFileSession) SupportsInterface(interfaceId [4]byte) (bool, error) {
+	return _SAOFile.Contract.SupportsInterface(&_SAOFile.CallOpts, interfaceId)
+}
+
+// SupportsInterface is a free data retrieval call binding the contract method 0x01ffc9a7.
+//
+// Solidity: function supportsInterface(bytes4 interfaceId) view returns(bool)
+func (_SAOFile *SAOFileCallerSession) SupportsInterface(interfaceId [4]byte) (bool, error) {
+	return _SAOFile.Contract.SupportsInterface(&_SAOFile.CallOpts, interfaceId)
+}
+
+// Symbol is a free data retrieval call binding the contract method 0x95d89b41.
+//
+// Solidity: function symbol() view returns(string)
+func (_SAOFile *SAOFileCaller) Symbol(opts *bind.CallOpts) (string, error) {
+	var out []interface{}
+	err := _SAOFile.contract.Call(opts, &out, "symbol")
+
+	if err != nil {
+		return *new(string), err
+	}
+
+	out0 := *abi.ConvertType(out[0], new(string)).(*string)
+
+	return out0, err
+
+}
+
+// Symbol is a free data retrieval call binding the contract method 0x95d89b41.
+//
+// Solidity: function symbol() view returns(string)
+func (_SAOFile *SAOFileSession) Symbol() (string, error) {
+	return _SAOFile.Contract.Symbol(&_SAOFile.CallOpts)
+}
+
+// Symbol is a free data retrieval call binding the contract method 0x95d89b41.
+//
+// Solidity: function symbol() view returns(string)
+func (_SAOFile *SAOFileCallerSession) Symbol() (string, error) {
+	return _SAOFile.Contract.Symbol(&_SAOFile.CallOpts)
+}
+
+// TokenURI is a free data retrieval call binding the contract method 0xc87b56dd.
+//
+// Solidity: function tokenURI(uint256 tokenId) view returns(string)
+func (_SAOFile *SAOFileCaller) TokenURI(opts *bind.CallOpts, tokenId *big.Int) (string, error) {
+	var out []interface{}
+	err := _SAOFile.contract.Call(opts, &out, "tokenURI", tokenId)
+
+	if err != nil {
+		return *new(string), err
+	}
+
+	out0 := *abi.ConvertType(out[0], new(string)).(*string)
+
+	return out0, err
+
+}
+
+// TokenURI is a free data retrieval call binding the contract method 0xc87b56dd.
+//
+// Solidity: function tokenURI(uint256 tokenId) view returns(string)
+func (_SAOFile *SAOFileSession) TokenURI(tokenId *big.Int) (string, error) {
+	return _SAOFile.Contract.TokenURI(&_SAOFile.CallOpts, tokenId)
+}
+
+// TokenURI is a free data retrieval call binding the contract method 0xc87b56dd.
+//
+// Solidity: function tokenURI(uint256 tokenId) view returns(string)
+func (_SAOFile *SAOFileCallerSession) TokenURI(tokenId *big.Int) (string, error) {
+	return _SAOFile.Contract.TokenURI(&_SAOFile.CallOpts, tokenId)
+}
+
+// TotalFee is a free data retrieval call binding the contract method 0x1df4ccfc.
+//
+// Solidity: function totalFee() view returns(uint256)
+func (_SAOFile *SAOFileCaller) TotalFee(opts *bind.CallOpts) (*big.Int, error) {
+	var out []interface{}
+	err := _SAOFile.contract.Call(opts, &out, "totalFee")
+
+	if err != nil {
+		return *new(*big.Int), err
+	}
+
+	out0 := *abi.ConvertType(out[0], new(*big.Int)).(**big.Int)
+
+	return out0, err
+
+}
+
+// TotalFee is a free data retrieval call binding the contract method 0x1df4ccfc.
+//
+// Solidity: function totalFee() view returns(uint256)
+func (_SAOFile *SAOFileSession) TotalFee() (*big.Int, error) {
+	return _SAOFile.Contract.TotalFee(&_SAOFile.CallOpts)
+}
+
+// TotalFee is a free data retrieval call binding the contract method 0x1df4ccfc.
+//
+// Solidity: function totalFee() view returns(uint256)
+func (_SAOFile *SAOFileCallerSession) TotalFee() (*big.Int, error) {
+	return _SAOFile.Contract.TotalFee(&_SAOFile.CallOpts)
+}
+
+// Approve is a paid mutator transaction binding the contract method 0x095ea7b3.
+//
+// Solidity: function approve(address to, uint256 tokenId) returns()
+func (_SAOFile *SAOFileTransactor) Approve(opts *bind.TransactOpts, to common.Address, tokenId *big.Int) (*types.Transaction, error) {
+	return _SAOFile.contract.Transact(opts, "approve", to, tokenId)
+}
+
+// Approve is a paid mutator transaction binding the contract method 0x095ea7b3.
+//
+// Solidity: function approve(address to, uint256 tokenId) returns()
+func (_SAOFile *SAOFileSession) Approve(to common.Address, tokenId *big.Int) (*types.Transaction, error) {
+	return _SAOFile.Contract.Approve(&_SAOFile.TransactOpts, to, tokenId)
+}
+
+// Approve is a paid mutator transaction binding the contract method 0x095ea7b3.
+//
+// Solidity: function approve(address to, uint256 tokenId) returns()
+func (_SAOFile *SAOFileTransactorSession) Approve(to common.Address, tokenId *big.Int) (*types.Transaction, error) {
+	return _SAOFile.Contract.Approve(&_SAOFile.TransactOpts, to, tokenId)
+}
+
+// Buy is a paid mutator transaction binding the contract method 0xd96a094a.
+//
+// Solidity: function buy(uint256 tokenId) payable returns()
+func (_SAOFile *SAOFileTransactor) Buy(opts *bind.TransactOpts, tokenId *big.Int) (*types.Transaction, error) {
+	return _SAOFile.contract.Transact(opts, "buy", tokenId)
+}
+
+// Buy is a paid mutator transaction binding the contract method 0xd96a094a.
+//
+// Solidity: function buy(uint256 tokenId) payable returns()
+func (_SAOFile *SAOFileSession) Buy(tokenId *big.Int) (*types.Transaction, error) {
+	return _SAOFile.Contract.Buy(&_SAOFile.TransactOpts, tokenId)
+}
+
+// Buy is a paid mutator transaction binding the contract method 0xd96a094a.
+//
+// Solidity: function buy(uint256 tokenId) payable returns()
+func (_SAOFile *SAOFileTransactorSession) Buy(tokenId *big.Int) (*types.Transaction, error) {
+	return _SAOFile.Contract.Buy(&_SAOFile.TransactOpts, tokenId)
+}
+
+// ChangePrice is a paid mutator transaction binding the contract method 0xb3de019c.
+//
+// Solidity: function changePrice(uint256 tokenId, uint256 price) returns()
+func (_SAOFile *SAOFileTransactor) ChangePrice(opts *bind.TransactOpts, tokenId *big.Int, price *big.Int) (*types.Transaction, error) {
+	return _SAOFile.contract.Transact(opts, "changePrice", tokenId, price)
+}
+
+// ChangePrice is a paid mutator transaction binding the contract method 0xb3de019c.
+//
+// Solidity: function changePrice(uint256 tokenId, uint256 price) returns()
+func (_SAOFile *SAOFileSession) ChangePrice(tokenId *big.Int, price *big.Int) (*types.Transaction, error) {
+	return _SAOFile.Contract.ChangePrice(&_SAOFile.TransactOpts, tokenId, price)
+}
+
+// ChangePrice is a paid mutator transaction binding the contract method 0xb3de019c.
+//
+// Solidity: function changePrice(uint256 tokenId, uint256 price) returns()
+func (_SAOFile *SAOFileTransactorSession) ChangePrice(tokenId *big.Int, price *big.Int) (*types.Transaction, error) {
+	return _SAOFile.Contract.ChangePrice(&_SAOFile.TransactOpts, tokenId, price)
+}
+
+// Finish is a paid mutator transaction binding the contract method 0xd353a1cb.
+//
+// Solidity: function finish(uint256 orderId) returns()
+func (_SAOFile *SAOFileTransactor) Finish(opts *bind.TransactOpts, orderId *big.Int) (*types.Transaction, error) {
+	return _SAOFile.contract.Transact(opts, "finish", orderId)
+}
+
+// Finish is a paid mutator transaction binding the contract method 0xd353a1cb.
+//
+// Solidity: function finish(uint256 orderId) returns()
+func (_SAOFile *SAOFileSession) Finish(orderId *big.Int) (*types.Transaction, error) {
+	return _SAOFile.Contract.Finish(&_SAOFile.TransactOpts, orderId)
+}
+
+// Finish is a paid mutator transaction binding the contract method 0xd353a1cb.
+//
+// Solidity: function finish(uint256 orderId) returns()
+func (_SAOFile *SAOFileTransactorSession) Finish(orderId *big.Int) (*types.Transaction, error) {
+	return _SAOFile.Contract.Finish(&_SAOFile.TransactOpts, orderId)
+}
+
+// Mint is a paid mutator transaction binding the contract method 0x1b2ef1ca.
+//
+// Solidity: function mint(uint256 file_id, uint256 price) returns()
+func (_SAOFile *SAOFileTransactor) Mint(opts *bind.TransactOpts, file_id *big.Int, price *big.Int) (*types.Transaction, error) {
+	return _SAOFile.contract.Transact(opts, "mint", file_id, price)
+}
+
+// Mint is a paid mutator transaction binding the contract method 0x1b2ef1ca.
+//
+// Solidity: function mint(uint256 file_id, uint256 price) returns()
+func (_SAOFile *SAOFileSession) Mint(file_id *big.Int, price *big.Int) (*types.Transaction, error) {
+	return _SAOFile.Contract.Mint(&_SAOFile.TransactOpts, file_id, price)
+}
+
+// Mint is a paid mutator transaction binding the contract method 0x1b2ef1ca.
+//
+// Solidity: function mint(uint256 file_id, uint256 price) returns()
+func (_SAOFile *SAOFileTransactorSession) Mint(file_id *big.Int, price *big.Int) (*types.Transaction, error) {
+	return _SAOFile.Contract.Mint(&_SAOFile.TransactOpts, file_id, price)
+}
+
+// RenounceOwnership is a paid mutator transaction binding the contract method 0x715018a6.
+//
+// Solidity: function renounceOwnership() returns()
+func (_SAOFile *SAOFileTransactor) RenounceOwnership(opts *bind.TransactOpts) (*types.Transaction, error) {
+	return _SAOFile.contract.Transact(opts, "renounceOwnership")
+}
+
+// RenounceOwnership is a paid mutator transaction binding the contract method 0x715018a6.
+//
+// Solidity: function renounceOwnership() returns()
+func (_SAOFile *SAOFileSession) RenounceOwnership() (*types.Transaction, error) {
+	return _SAOFile.Contract.RenounceOwnership(&_SAOFile.TransactOpts)
+}
+
+// RenounceOwnership is a paid mutator transaction binding the contract method 0x715018a6.
+//
+// Solidity: function renounceOwnership() returns()
+func (_SAOFile *SAOFileTransactorSession) RenounceOwnership() (*types.Transaction, error) {
+	return _SAOFile.Contract.RenounceOwnership(&_SAOFile.TransactOpts)
+}
+
+// SafeTransferFrom is a paid mutator transaction binding the contract method 0x42842e0e.
+//
+// Solidity: function safeTransferFrom(address from, address to, uint256 tokenId) returns()
+func (_SAOFile *SAOFileTransactor) SafeTransferFrom(opts *bind.TransactOpts, from common.Address, to common.Address, tokenId *big.Int) (*types.Transaction, error) {
+	return _SAOFile.contract.Transact(opts, "safeTransferFrom", from, to, tokenId)
+}
+
+// SafeTransferFrom is a paid mutator transaction binding the contract method 0x42842e0e.
+//
+// Solidity: function safeTransferFrom(address from, address to, uint256 tokenId) returns()
+func (_SAOFile *SAOFileSession) SafeTransferFrom(from common.Address, to common.Address, tokenId *big.Int) (*types.Transaction, error) {
+	return _SAOFile.Contract.SafeTransferFrom(&_SAOFile.TransactOpts, from, to, tokenId)
+}
+
+// SafeTransferFrom is a paid mutator transaction binding the contract method 0x42842e0e.
+//
+// Solidity: function safeTransferFrom(address from, address to, uint256 tokenId) returns()
+func (_SAOFile *SAOFileTransactorSession) SafeTransferFrom(from common.Address, to common.Address, tokenId *big.Int) (*types.Transaction, error) {
+	return _SAOFile.Contract.SafeTransferFrom(&_SAOFile.TransactOpts, from, to, tokenId)
+}
+
+// SafeTransferFrom0 is a paid mutator transaction binding the contract method 0xb88d4fde.
+//
+// Solidity: function safeTransferFrom(address from, address to, uint256 tokenId, bytes data) returns()
+func (_SAOFile *SAOFileTransactor) SafeTransferFrom0(opts *bind.TransactOpts, from common.Address, to common.Address, tokenId *big.Int, data []byte) (*types.Transaction, error) {
+	return _SAOFile.contract.Transact(opts, "safeTransferFrom0", from, to, tokenId, data)
+}
+
+// SafeTransferFrom0 is a paid mutator transaction binding the contract method 0xb88d4fde.
+//
+// Solidity: function safeTransferFrom(address from, address to, uint256 tokenId, bytes data) returns()
+func (_SAOFile *SAOFileSession) SafeTransferFrom0(from common.Address, to common.Address, tokenId *big.Int, data []byte) (*types.Transaction, error) {
+	return _SAOFile.Contract.SafeTransferFrom0(&_SAOFile.TransactOpts, from, to, tokenId, data)
+}
+
+// SafeTransferFrom0 is a paid mutator transaction binding the contract method 0xb88d4fde.
+//
+// Solidity: function safeTransferFrom(address from, address to, uint256 tokenId, bytes data) returns()
+func (_SAOFile *SAOFileTransactorSession) SafeTransferFrom0(from common.Address, to common.Address, tokenId *big.Int, data []byte) (*types.Transaction, error) {
+	return _SAOFile.Contract.SafeTransferFrom0(&_SAOFile.TransactOpts, from, to, tokenId, data)
+}
+
+// SetApprovalForAll is a paid mutator transaction binding the contract method 0xa22cb465.
+//
+// Solidity: function setApprovalForAll(address operator, bool approved) returns()
+func (_SAOFile *SAOFileTransactor) SetApprovalForAll(opts *bind.TransactOpts, operator common.Address, approved bool) (*types.Transaction, error) {
+	return _SAOFile.contract.Transact(opts, "setApprovalForAll", operator, approved)
+}
+
+// SetApprovalForAll is a paid mutator transaction binding the contract method 0xa22cb465.
+//
+// Solidity: function setApprovalForAll(address operator, bool approved) returns()
+func (_SAOFile *SAOFileSession) SetApprovalForAll(operator common.Address, approved bool) (*types.Transaction, error) {
+	return _SAOFile.Contract.SetApprovalForAll(&_SAOFile.TransactOpts, operator, approved)
+}
+
+// SetApprovalForAll is a paid mutator transaction binding the contract method 0xa22cb465.
+//
+// Solidity: function setApprovalForAll(address operator, bool approved) returns()
+func (_SAOFile *SAOFileTransactorSession) SetApprovalForAll(operator common.Address, approved bool) (*types.Transaction, error) {
+	return _SAOFile.Contract.SetApprovalForAll(&_SAOFile.TransactOpts, operator, approved)
+}
+
+// SetBaseURI is a paid mutator transaction binding the contract method 0x55f804b3.
+//
+// Solidity: function setBaseURI(string _uri) returns()
+func (_SAOFile *SAOFileTransactor) SetBaseURI(opts *bind.TransactOpts, _uri string) (*types.Transaction, error) {
+	return _SAOFile.contract.Transact(opts, "setBaseURI", _uri)
+}
+
+// SetBaseURI is a paid mutator transaction binding the contract method 0x55f804b3.
+//
+// Solidity: function setBaseURI(string _uri) returns()
+func (_SAOFile *SAOFileSession) SetBaseURI(_uri string) (*types.Transaction, error) {
+	return _SAOFile.Contract.SetBaseURI(&_SAOFile.TransactOpts, _uri)
+}
+
+// SetBaseURI is a paid mutator transaction binding the contract method 0x55f804b3.
+//
+// Solidity: function setBaseURI(string _uri) returns()
+func (_SAOFile *SAOFileTransactorSession) SetBaseURI(_uri string) (*types.Transaction, error) {
+	return _SAOFile.Contract.SetBaseURI(&_SAOFile.TransactOpts, _uri)
+}
+
+// TransferFrom is a paid mutator transaction binding the contract method 0x23b872dd.
+//
+// Solidity: function transferFrom(address from, address to, uint256 tokenId) returns()
+func (_SAOFile *SAOFileTransactor) TransferFrom(opts *bind.TransactOpts, from common.Address, to common.Address, tokenId *big.Int) (*types.Transaction, error) {
+	return _SAOFile.contract.Transact(opts, "transferFrom", from, to, tokenId)
+}
+
+// TransferFrom is a paid mutator transaction binding the contract method 0x23b872dd.
+//
+// Solidity: function transferFrom(address from, address to, uint256 tokenId) returns()
+func (_SAOFile *SAOFileSession) TransferFrom(from common.Address, to common.Address, tokenId *big.Int) (*types.Transaction, error) {
+	return _SAOFile.Contract.TransferFrom(&_SAOFile.TransactOpts, from, to, tokenId)
+}
+
+// TransferFrom is a paid mutator transaction binding the contract method 0x23b872dd.
+//
+// Solidity: function transferFrom(address from, address to, uint256 tokenId) returns()
+func (_SAOFile *SAOFileTransactorSession) TransferFrom(from common.Address, to common.Address, tokenId *big.Int) (*types.Transaction, error) {
+	return _SAOFile.Contract.TransferFrom(&_SAOFile.TransactOpts, from, to, tokenId)
+}
+
+// TransferOwnership is a paid mutator transaction binding the contract method 0xf2fde38b.
+//
+// Solidity: function transferOwnership(address newOwner) returns()
+func (_SAOFile *SAOFileTransactor) TransferOwnership(opts *bind.TransactOpts, newOwner common.Address) (*types.Transaction, error) {
+	return _SAOFile.contract.Transact(opts, "transferOwnership", newOwner)
+}
+
+// TransferOwnership is a paid mutator transaction binding the contract method 0xf2fde38b.
+//
+// Solidity: function transferOwnership(address newOwner) returns()
+func (_SAOFile *SAOFileSession) TransferOwnership(newOwner common.Address) (*types.Transaction, error) {
+	return _SAOFile.Contract.TransferOwnership(&_SAOFile.TransactOpts, newOwner)
+}
+
+// TransferOwnership is a paid mutator transaction binding the contract method 0xf2fde38b.
+//
+// Solidity: function transferOwnership(address newOwner) returns()
+func (_SAOFile *SAOFileTransactorSession) TransferOwnership(newOwner common.Address) (*types.Transaction, error) {
+	return _SAOFile.Contract.TransferOwnership(&_SAOFile.TransactOpts, newOwner)
+}
+
+// Withdraw is a paid mutator transaction binding the contract method 0x3ccfd60b.
+//
+// Solidity: function withdraw() returns()
+func (_SAOFile *SAOFileTransactor) Withdraw(opts *bind.TransactOpts) (*types.Transaction, error) {
+	return _SAOFile.contract.Transact(opts, "withdraw")
+}
+
+// Withdraw is a paid mutator transaction binding the contract method 0x3ccfd60b.
+//
+// Solidity: function withdraw() returns()
+func (_SAOFile *SAOFileSession) Withdraw() (*types.Transaction, error) {
+	return _SAOFile.Contract.Withdraw(&_SAOFile.TransactOpts)
+}
+
+// Withdraw is a paid mutator transaction binding the contract method 0x3ccfd60b.
+//
+// Solidity: function withdraw() returns()
+func (_SAOFile *SAOFileTransactorSession) Withdraw() (*types.Transaction, error) {
+	return _SAOFile.Contract.Withdraw(&_SAOFile.TransactOpts)
+}
+
+// SAOFileApprovalIterator is returned from FilterApproval and is used to iterate over the raw logs and unpacked data for Approval events raised by the SAOFile contract.
+type SAOFileApprovalIterator struct {
+	Event *SAOFileApproval // Event containing the contract specifics and raw log
+
+	contract *bind.BoundContract // Generic contract to use for unpacking event data
+	event    string              // Event name to use for unpacking event data
+
+	logs chan types.Log        // Log channel receiving the found contract events
+	sub  ethereum.Subscription // Subscription for errors, completion and termination
+	done bool                  // Whether the subscription completed delivering logs
+	fail error                 // Occurred error to stop iteration
+}
+
+// Next advances the iterator to the subsequent event, returning whether there
+// are any more events found. In case of a retrieval or parsing error, false is
+// returned and Error() can be queried for the exact failure.
+func (it *SAOFileApprovalIterator) Next() bool {
+	// If the iterator failed, stop iterating
+	if it.fail != nil {
+		return false
+	}
+	// If the iterator completed, deliver directly whatever's available
+	if it.done {
+		select {
+		case log := <-it.logs:
+			it.Event = new(SAOFileApproval)
+			if err := it.contract.UnpackLog(it.Event, it.event, log); err != nil {
+				it.fail = err
+				return false
+			}
+			it.Event.Raw = log
+			return true
+
+		default:
+			return false
+		}
+	}
+	// Iterator still in progress, wait for either a data or an error event
+	select {
+	case log := <-it.logs:
+		it.Event = new(SAOFileApproval)
+		if err := it.contract.UnpackLog(it.Event, it.event, log); err != nil {
+			it.fail = err
+			return false
+		}
+		it.Event.Raw = log
+		return true
+
+	case err := <-it.sub.Err():
+		it.done = true
+		it.fail = err
+		return it.Next()
+	}
+}
+
+// Error returns any retrieval or parsing error occurred during filtering.
+func (it *SAOFileApprovalIterator) Error() error {
+	return it.fail
+}
+
+// Close terminates the iteration process, releasing any pending underlying
+// resources.
+func (it *SAOFileApprovalIterator) Close() error {
+	it.sub.Unsubscribe()
+	return nil
+}
+
+// SAOFileApproval represents a Approval event raised by the SAOFile contract.
+type SAOFileApproval struct {
+	Owner    common.Address
+	Approved common.Address
+	TokenId  *big.Int
+	Raw      types.Log // Blockchain specific contextual infos
+}
+
+// FilterApproval is a free log retrieval operation binding the contract event 0x8c5be1e5ebec7d5bd14f71427d1e84f3dd0314c0f7b2291e5b200ac8c7c3b925.
+//
+// Solidity: event Approval(address indexed owner, address indexed approved, uint256 indexed tokenId)
+func (_SAOFile *SAOFileFilterer) FilterApproval(opts *bind.FilterOpts, owner []common.Address, approved []common.Address, tokenId []*big.Int) (*SAOFileApprovalIterator, error) {
+
+	var ownerRule []interface{}
+	for _, ownerItem := range owner {
+		ownerRule = append(ownerRule, ownerItem)
+	}
+	var approvedRule []interface{}
+	for _, approvedItem := range approved {
+		approvedRule = append(approvedRule, approvedItem)
+	}
+	var tokenIdRule []interface{}
+	for _, tokenIdItem := range tokenId {
+		tokenIdRule = append(tokenIdRule, tokenIdItem)
+	}
+
+	logs, sub, err := _SAOFile.contract.FilterLogs(opts, "Approval", ownerRule, approvedRule, tokenIdRule)
+	if err != nil {
+		return nil, err
+	}
+	return &SAOFileApprovalIterator{contract: _SAOFile.contract, event: "Approval", logs: logs, sub: sub}, nil
+}
+
+// WatchApproval is a free log subscription operation binding the contract event 0x8c5be1e5ebec7d5bd14f71427d1e84f3dd0314c0f7b2291e5b200ac8c7c3b925.
+//
+// Solidity: event Approval(address indexed owner, address indexed approved, uint256 indexed tokenId)
+func (_SAOFile *SAOFileFilterer) WatchApproval(opts *bind.WatchOpts, sink chan<- *SAOFileApproval, owner []common.Address, approved []common.Address, tokenId []*big.Int) (event.Subscription, error) {
+
+	var ownerRule []interface{}
+	for _, ownerItem := range owner {
+		ownerRule = append(ownerRule, ownerItem)
+	}
+	var approvedRule []interface{}
+	for _, approvedItem := range approved {
+		approvedRule = append(approvedRule, approvedItem)
+	}
+	var tokenIdRule []interface{}
+	for _, tokenIdItem := range tokenId {
+		tokenIdRule = append(tokenIdRule, tokenIdItem)
+	}
+
+	logs, sub, err := _SAOFile.contract.WatchLogs(opts, "Approval", ownerRule, approvedRule, tokenIdRule)
+	if err != nil {
+		return nil, err
+	}
+	return event.NewSubscription(func(quit <-chan struct{}) error {
+		defer sub.Unsubscribe()
+		for {
+			select {
+			case log := <-logs:
+				// New log arrived, parse the event and forward to the user
+				event := new(SAOFileApproval)
+				if err := _SAOFile.contract.UnpackLog(event, "Approval", log); err != nil {
+					return err
+				}
+				event.Raw = log
+
+				select {
+				case sink <- event:
+				case err := <-sub.Err():
+					return err
+				case <-quit:
+					return nil
+				}
+			case err := <-sub.Err():
+				return err
+			case <-quit:
+				return nil
+			}
+		}
+	}), nil
+}
+
+// ParseApproval is a log parse operation binding the contract event 0x8c5be1e5ebec7d5bd14f71427d1e84f3dd0314c0f7b2291e5b200ac8c7c3b925.
+//
+// Solidity: event Approval(address indexed owner, address indexed approved, uint256 indexed tokenId)
+func (_SAOFile *SAOFileFilterer) ParseApproval(log types.Log) (*SAOFileApproval, error) {
+	event := new(SAOFileApproval)
+	if err := _SAOFile.contract.UnpackLog(event, "Approval", log); err != nil {
+		return nil, err
+	}
+	event.Raw = log
+	return event, nil
+}
+
+// SAOFileApprovalForAllIterator is returned from FilterApprovalForAll and is used to iterate over the raw logs and unpacked data for ApprovalForAll events raised by the SAOFile contract.
+type SAOFileApprovalForAllIterator struct {
+	Event *SAOFileApprovalForAll // Event containing the contract specifics and raw log
+
+	contract *bind.BoundContract // Generic contract to use for unpacking event data
+	event    string              // Event name to use for unpacking event data
+
+	logs chan types.Log        // Log channel receiving the found contract events
+	sub  ethereum.Subscription // Subscription for errors, completion and termination
+	done bool                  // Whether the subscription completed delivering logs
+	fail error                 // Occurred error to stop iteration
+}
+
+// Next advances the iterator to the subsequent event, returning whether there
+// are any more events found. In case of a retrieval or parsing error, false is
+// returned and Error() can be queried for the exact failure.
+func (it *SAOFileApprovalForAllIterator) Next() bool {
+	// If the iterator failed, stop iterating
+	if it.fail != nil {
+		return false
+	}
+	// If the iterator completed, deliver directly whatever's available
+	if it.done {
+		select {
+		case log := <-it.logs:
+			it.Event = new(SAOFileApprovalForAll)
+			if err := it.contract.UnpackLog(it.Event, it.event, log); err != nil {
+				it.fail = err
+				return false
+			}
+			it.Event.Raw = log
+			return true
+
+		default:
+			return false
+		}
+	}
+	// Iterator still in progress, wait for either a data or an error event
+	select {
+	case log := <-it.logs:
+		it.Event = new(SAOFileApprovalForAll)
+		if err := it.contract.UnpackLog(it.Event, it.event, log); err != nil {
+			it.fail = err
+			return false
+		}
+		it.Event.Raw = log
+		return true
+
+	case err := <-it.sub.Err():
+		it.done = true
+		it.fail = err
+		return it.Next()
+	}
+}
+
+// Error returns any retrieval or parsing error occurred during filtering.
+func (it *SAOFileApprovalForAllIterator) Error() error {
+	return it.fail
+}
+
+// Close terminates the iteration process, releasing any pending underlying
+// resources.
+func (it *SAOFileApprovalForAllIterator) Close() error {
+	it.sub.Unsubscribe()
+	return nil
+}
+
+// SAOFileApprovalForAll represents a ApprovalForAll event raised by the SAOFile contract.
+type SAOFileApprovalForAll struct {
+	Owner    common.Address
+	Operator common.Address
+	Approved bool
+	Raw      types.Log // Blockchain specific contextual infos
+}
+
+// FilterApprovalForAll is a free log retrieval operation binding the contract event 0x17307eab39ab6107e8899845ad3d59bd9653f200f220920489ca2b5937696c31.
+//
+// Solidity: event ApprovalForAll(address indexed owner, address indexed operator, bool approved)
+func (_SAOFile *SAOFileFilterer) FilterApprovalForAll(opts *bind.FilterOpts, owner []common.Address, operator []common.Address) (*SAOFileApprovalForAllIterator, error) {
+
+	var ownerRule []interface{}
+	for _, ownerItem := range owner {
+		ownerRule = append(ownerRule, ownerItem)
+	}
+	var operatorRule []interface{}
+	for _, operatorItem := range operator {
+		operatorRule = append(operatorRule, operatorItem)
+	}
+
+	logs, sub, err := _SAOFile.contract.FilterLogs(opts, "ApprovalForAll", ownerRule, operatorRule)
+	if err != nil {
+		return nil, err
+	}
+	return &SAOFileApprovalForAllIterator{contract: _SAOFile.contract, event: "ApprovalForAll", logs: logs, sub: sub}, nil
+}
+
+// WatchApprovalForAll is a free log subscription operation binding the contract event 0x17307eab39ab6107e8899845ad3d59bd9653f200f220920489ca2b5937696c31.
+//
+// Solidity: event ApprovalForAll(address indexed owner, address indexed operator, bool approved)
+func (_SAOFile *SAOFileFilterer) WatchApprovalForAll(opts *bind.WatchOpts, sink chan<- *SAOFileApprovalForAll, owner []common.Address, operator []common.Address) (event.Subscription, error) {
+
+	var ownerRule []interface{}
+	for _, ownerItem := range owner {
+		ownerRule = append(ownerRule, ownerItem)
+	}
+	var operatorRule []interface{}
+	for _, operatorItem := range operator {
+		operatorRule = append(operatorRule, operatorItem)
+	}
+
+	logs, sub, err := _SAOFile.contract.WatchLogs(opts, "ApprovalForAll", ownerRule, operatorRule)
+	if err != nil {
+		return nil, err
+	}
+	return event.NewSubscription(func(quit <-chan struct{}) error {
+		defer sub.Unsubscribe()
+		for {
+			select {
+			case log := <-logs:
+				// New log arrived, parse the event and forward to the user
+				event := new(SAOFileApprovalForAll)
+				if err := _SAOFile.contract.UnpackLog(event, "ApprovalForAll", log); err != nil {
+					return err
+				}
+				event.Raw = log
+
+				select {
+				case sink <- event:
+				case err := <-sub.Err():
+					return err
+				case <-quit:
+					return nil
+				}
+			case err := <-sub.Err():
+				return err
+			case <-quit:
+				return nil
+			}
+		}
+	}), nil
+}
+
+// ParseApprovalForAll is a log parse operation binding the contract event 0x17307eab39ab6107e8899845ad3d59bd9653f200f220920489ca2b5937696c31.
+//
+// Solidity: event ApprovalForAll(address indexed owner, address indexed operator, bool approved)
+func (_SAOFile *SAOFileFilterer) ParseApprovalForAll(log types.Log) (*SAOFileApprovalForAll, error) {
+	event := new(SAOFileApprovalForAll)
+	if err := _SAOFile.contract.UnpackLog(event, "ApprovalForAll", log); err != nil {
+		return nil, err
+	}
+	event.Raw = log
+	return event, nil
+}
+
+// SAOFileBoughtIterator is returned from FilterBought and is used to iterate over the raw logs and unpacked data for Bought events raised by the SAOFile contract.
+type SAOFileBoughtIterator struct {
+	Event *SAOFileBought // Event containing the contract specifics and raw log
+
+	contract *bind.BoundContract // Generic contract to use for unpacking event data
+	event    string              // Event name to use for unpacking event data
+
+	logs chan types.Log        // Log channel receiving the found contract events
+	sub  ethereum.Subscription // Subscription for errors, completion and termination
+	done bool                  // Whether the subscription completed delivering logs
+	fail error                 // Occurred error to stop iteration
+}
+
+// Next advances the iterator to the subsequent event, returning whether there
+// are any more events found. In case of a retrieval or parsing error, false is
+// returned and Error() can be queried for the exact failure.
+func (it *SAOFileBoughtIterator) Next() bool {
+	// If the iterator failed, stop iterating
+	if it.fail != nil {
+		return false
+	}
+	// If the iterator completed, deliver directly whatever's available
+	if it.done {
+		select {
+		case log := <-it.logs:
+			it.Event = new(SAOFileBought)
+			if err := it.contract.UnpackLog(it.Event, it.event, log); err != nil {
+				it.fail = err
+				return false
+			}
+			it.Event.Raw = log
+			return true
+
+		default:
+			return false
+		}
+	}
+	// Iterator still in progress, wait for either a data or an error event
+	select {
+	case log := <-it.logs:
+		it.Event = new(SAOFileBought)
+		if err := it.contract.UnpackLog(it.Event, it.event, log); err != nil {
+			it.fail = err
+			return false
+		}
+		it.Event.Raw = log
+		return true
+
+	case err := <-it.sub.Err():
+		it.done = true
+		it.fail = err
+		return it.Next()
+	}
+}
+
+// Error returns any retrieval or parsing error occurred during filtering.
+func (it *SAOFileBoughtIterator) Error() error {
+	return it.fail
+}
+
+// Close terminates the iteration process, releasing any pending underlying
+// resources.
+func (it *SAOFileBoughtIterator) Close() error {
+	it.sub.Unsubscribe()
+	return nil
+}
+
+// SAOFileBought represents a Bought event raised by the SAOFile contract.
+type SAOFileBought struct {
+	TokenId   *big.Int
+	Buyer     common.Address
+	OrderId   *big.Int
+	Price     *big.Int
+	Timestamp *big.Int
+	Raw       types.Log // Blockchain specific contextual infos
+}
+
+// FilterBought is a free log retrieval operation binding the contract event 0xaf6c8baebc55f540953b129965caf1e3f48010ed731c2cea5ed61e71ebc57153.
+//
+// Solidity: event Bought(uint256 indexed tokenId, address indexed buyer, uint256 indexed orderId, uint256 price, uint256 timestamp)
+func (_SAOFile *SAOFileFilterer) FilterBought(opts *bind.FilterOpts, tokenId []*big.Int, buyer []common.Address, orderId []*big.Int) (*SAOFileBoughtIterator, error) {
+
+	var tokenIdRule []interface{}
+	for _, tokenIdItem := range tokenId {
+		tokenIdRule = append(tokenIdRule, tokenIdItem)
+	}
+	var buyerRule []interface{}
+	for _, buyerItem := range buyer {
+		buyerRule = append(buyerRule, buyerItem)
+	}
+	var orderIdRule []interface{}
+	for _, orderIdItem := range orderId {
+		orderIdRule = append(orderIdRule, orderIdItem)
+	}
+
+	logs, sub, err := _SAOFile.contract.FilterLogs(opts, "Bought", tokenIdRule, buyerRule, orderIdRule)
+	if err != nil {
+		return nil, err
+	}
+	return &SAOFileBoughtIterator{contract: _SAOFile.contract, event: "Bought", logs: logs, sub: sub}, nil
+}
+
+// WatchBought is a free log subscription operation binding the contract event 0xaf6c8baebc55f540953b129965caf1e3f48010ed731c2cea5ed61e71ebc57153.
+//
+// Solidity: event Bought(uint256 indexed tokenId, address indexed buyer, uint256 indexed orderId, uint256 price, uint256 timestamp)
+func (_SAOFile *SAOFileFilterer) WatchBought(opts *bind.WatchOpts, sink chan<- *SAOFileBought, tokenId []*big.Int, buyer []common.Address, orderId []*big.Int) (event.Subscription, error) {
+
+	var tokenIdRule []interface{}
+	for _, tokenIdItem := range tokenId {
+		tokenIdRule = append(tokenIdRule, tokenIdItem)
+	}
+	var buyerRule []interface{}
+	for _, buyerItem := range buyer {
+		buyerRule = append(buyerRule, buyerItem)
+	}
+	var orderIdRule []interface{}
+	for _, orderIdItem := range orderId {
+		orderIdRule = append(orderIdRule, orderIdItem)
+	}
+
+	logs, sub, err := _SAOFile.contract.WatchLogs(opts, "Bought", tokenIdRule, buyerRule, orderIdRule)
+	if err != nil {
+		return nil, err
+	}
+	return event.NewSubscription(func(quit <-chan struct{}) error {
+		defer sub.Unsubscribe()
+		for {
+			select {
+			case log := <-logs:
+				// New log arrived, parse the event and forward to the user
+				event := new(SAOFileBought)
+				if err := _SAOFile.contract.UnpackLog(event, "Bought", log); err != nil {
+					return err
+				}
+				event.Raw = log
+
+				select {
+				case sink <- event:
+				case err := <-sub.Err():
+					return err
+				case <-quit:
+					return nil
+				}
+			case err := <-sub.Err():
+				return err
+			case <-quit:
+				return nil
+			}
+		}
+	}), nil
+}
+
+// ParseBought is a log parse operation binding the contract event 0xaf6c8baebc55f540953b129965caf1e3f48010ed731c2cea5ed61e71ebc57153.
+//
+// Solidity: event Bought(uint256 indexed tokenId, address indexed buyer, uint256 indexed orderId, uint256 price, uint256 timestamp)
+func (_SAOFile *SAOFileFilterer) ParseBought(log types.Log) (*SAOFileBought, error) {
+	event := new(SAOFileBought)
+	if err := _SAOFile.contract.UnpackLog(event, "Bought", log); err != nil {
+		return nil, err
+	}
+	event.Raw = log
+	return event, nil
+}
+
+// SAOFileChangePriceIterator is returned from FilterChangePrice and is used to iterate over the raw logs and unpacked data for ChangePrice events raised by the SAOFile contract.
+type SAOFileChangePriceIterator struct {
+	Event *SAOFileChangePrice // Event containing the contract specifics and raw log
+
+	contract *bind.BoundContract // Generic contract to use for unpacking event data
+	event    string              // Event name to use for unpacking event data
+
+	logs chan types.Log        // Log channel receiving the found contract events
+	sub  ethereum.Subscription // Subscription for errors, completion and termination
+	done bool                  // Whether the subscription completed delivering logs
+	fail error                 // Occurred error to stop iteration
+}
+
+// Next advances the iterator to the subsequent event, returning whether there
+// are any more events found. In case of a retrieval or parsing error, false is
+// returned and Error() can be queried for the exact failure.
+func (it *SAOFileChangePriceIterator) Next() bool {
+	// If the iterator failed, stop iterating
+	if it.fail != nil {
+		return false
+	}
+	// If the iterator completed, deliver directly whatever's available
+	if it.done {
+		select {
+		case log := <-it.logs:
+			it.Event = new(SAOFileChangePrice)
+			if err := it.contract.UnpackLog(it.Event, it.event, log); err != nil {
+				it.fail = err
+				return false
+			}
+			it.Event.Raw = log
+			return true
+
+		default:
+			return false
+		}
+	}
+	// Iterator still in progress, wait for either a data or an error event
+	select {
+	case log := <-it.logs:
+		it.Event = new(SAOFileChangePrice)
+		if err := it.contract.UnpackLog(it.Event, it.event, log); err != nil {
+			it.fail = err
+			return false
+		}
+		it.Event.Raw = log
+		return true
+
+	case err := <-it.sub.Err():
+		it.done = true
+		it.fail = err
+		return it.Next()
+	}
+}
+
+// Error returns any retrieval or parsing error occurred during filtering.
+func (it *SAOFileChangePriceIterator) Error() error {
+	return it.fail
+}
+
+// Close terminates the iteration process, releasing any pending underlying
+// resources.
+func (it *SAOFileChangePriceIterator) Close() error {
+	it.sub.Unsubscribe()
+	return nil
+}
+
+// SAOFileChangePrice represents a ChangePrice event raised by the SAOFile contract.
+type SAOFileChangePrice struct {
+	TokenId   *big.Int
+	Price     *big.Int
+	Timestamp *big.Int
+	Raw       types.Log // Blockchain specific contextual infos
+}
+
+// FilterChangePrice is a free log retrieval operation binding the contract event 0x6fe45af3b9a26eaad954e35ea326b45c0aebeddacdb8d0d57dcc8faa9ab5a6a9.
+//
+// Solidity: event ChangePrice(uint256 indexed tokenId, uint256 price, uint256 timestamp)
+func (_SAOFile *SAOFileFilterer) FilterChangePrice(opts *bind.FilterOpts, tokenId []*big.Int) (*SAOFileChangePriceIterator, error) {
+
+	var tokenIdRule []interface{}
+	for _, tokenIdItem := range tokenId {
+		tokenIdRule = append(tokenIdRule, tokenIdItem)
+	}
+
+	logs, sub, err := _SAOFile.contract.FilterLogs(opts, "ChangePrice", tokenIdRule)
+	if err != nil {
+		return nil, err
+	}
+	return &SAOFileChangePriceIterator{contract: _SAOFile.contract, event: "ChangePrice", logs: logs, sub: sub}, nil
+}
+
+// WatchChangePrice is a free log subscription operation binding the contract event 0x6fe45af3b9a26eaad954e35ea326b45c0aebeddacdb8d0d57dcc8faa9ab5a6a9.
+//
+// Solidity: event ChangePrice(uint256 indexed tokenId, uint256 price, uint256 timestamp)
+func (_SAOFile *SAOFileFilterer) WatchChangePrice(opts *bind.WatchOpts, sink chan<- *SAOFileChangePrice, tokenId []*big.Int) (event.Subscription, error) {
+
+	var tokenIdRule []interface{}
+	for _, tokenIdItem := range tokenId {
+		tokenIdRule = append(tokenIdRule, tokenIdItem)
+	}
+
+	logs, sub, err := _SAOFile.contract.WatchLogs(opts, "ChangePrice", tokenIdRule)
+	if err != nil {
+		return nil, err
+	}
+	return event.NewSubscription(func(quit <-chan struct{}) error {
+		defer sub.Unsubscribe()
+		for {
+			select {
+			case log := <-logs:
+				// New log arrived, parse the event and forward to the user
+				event := new(SAOFileChangePrice)
+				if err := _SAOFile.contract.UnpackLog(event, "ChangePrice", log); err != nil {
+					return err
+				}
+				event.Raw = log
+
+				select {
+				case sink <- event:
+				case err := <-sub.Err():
+					return err
+				case <-quit:
+					return nil
+				}
+			case err := <-sub.Err():
+				return err
+			case <-quit:
+				return nil
+			}
+		}
+	}), nil
+}
+
+// ParseChangePrice is a log parse operation binding the contract event 0x6fe45af3b9a26eaad954e35ea326b45c0aebeddacdb8d0d57dcc8faa9ab5a6a9.
+//
+// Solidity: event ChangePrice(uint256 indexed tokenId, uint256 price, uint256 timestamp)
+func (_SAOFile *SAOFileFilterer) ParseChangePrice(log types.Log) (*SAOFileChangePrice, error) {
+	event := new(SAOFileChangePrice)
+	if err := _SAOFile.contract.UnpackLog(event, "ChangePrice", log); err != nil {
+		return nil, err
+	}
+	event.Raw = log
+	return event, nil
+}
+
+// SAOFileDownloadIterator is returned from FilterDownload and is used to iterate over the raw logs and unpacked data for Download events raised by the SAOFile contract.
+type SAOFileDownloadIterator struct {
+	Event *SAOFileDownload // Event containing the contract specifics and raw log
+
+	contract *bind.BoundContract // Generic contract to use for unpacking event data
+	event    string              // Event name to use for unpacking event data
+
+	logs chan types.Log        // Log channel receiving the found contract events
+	sub  ethereum.Subscription // Subscription for errors, completion and termination
+	done bool                  // Whether the subscription completed delivering logs
+	fail error                 // Occurred error to stop iteration
+}
+
+// Next advances the iterator to the subsequent event, returning whether there
+// are any more events found. In case of a retrieval or parsing error, false is
+// returned and Error() can be queried for the exact failure.
+func (it *SAOFileDownloadIterator) Next() bool {
+	// If the iterator failed, stop iterating
+	if it.fail != nil {
+		return false
+	}
+	// If the iterator completed, deliver directly whatever's available
+	if it.done {
+		select {
+		case log := <-it.logs:
+			it.Event = new(SAOFileDownload)
+			if err := it.contract.UnpackLog(it.Event, it.event, log); err != nil {
+				it.fail = err
+				return false
+			}
+			it.Event.Raw = log
+			return true
+
+		default:
+			return false
+		}
+	}
+	// Iterator still in progress, wait for either a data or an error event
+	select {
+	case log := <-it.logs:
+		it.Event = new(SAOFileDownload)
+		if err := it.contract.UnpackLog(it.Event, it.event, log); err != nil {
+			it.fail = err
+			return false
+		}
+		it.Event.Raw = log
+		return true
+
+	case err := <-it.sub.Err():
+		it.done = true
+		it.fail = err
+		return it.Next()
+	}
+}
+
+// Error returns any retrieval or parsing error occurred during filtering.
+func (it *SAOFileDownloadIterator) Error() error {
+	return it.fail
+}
+
+// Close terminates the iteration process, releasing any pending underlying
+// resources.
+func (it *SAOFileDownloadIterator) Close() error {
+	it.sub.Unsubscribe()
+	return nil
+}
+
+// SAOFileDownload represents a Download event raised by the SAOFile contract.
+type SAOFileDownload struct {
+	OrderId   *big.Int
+	Timestamp *big.Int
+	Raw       types.Log // Blockchain specific contextual infos
+}
+
+// FilterDownload is a free log retrieval operation binding the contract event 0xfc677c6d4fb41b52076143bd16b0f032d45adbe706f64c08956a1829cd048986.
+//
+// Solidity: event Download(uint256 indexed orderId, uint256 timestamp)
+func (_SAOFile *SAOFileFilterer) FilterDownload(opts *bind.FilterOpts, orderId []*big.Int) (*SAOFileDownloadIterator, error) {
+
+	var orderIdRule []interface{}
+	for _, orderIdItem := range orderId {
+		orderIdRule = append(orderIdRule, orderIdItem)
+	}
+
+	logs, sub, err := _SAOFile.contract.FilterLogs(opts, "Download", orderIdRule)
+	if err != nil {
+		return nil, err
+	}
+	return &SAOFileDownloadIterator{contract: _SAOFile.contract, event: "Download", logs: logs, sub: sub}, nil
+}
+
+// WatchDownload is a free log subscription operation binding the contract event 0xfc677c6d4fb41b52076143bd16b0f032d45adbe706f64c08956a1829cd048986.
+//
+// Solidity: event Download(uint256 indexed orderId, uint256 timestamp)
+func (_SAOFile *SAOFileFilterer) WatchDownload(opts *bind.WatchOpts, sink chan<- *SAOFileDownload, orderId []*big.Int) (event.Subscription, error) {
+
+	var orderIdRule []interface{}
+	for _, orderIdItem := range orderId {
+		orderIdRule = append(orderIdRule, orderIdItem)
+	}
+
+	logs, sub, err := _SAOFile.contract.WatchLogs(opts, "Download", orderIdRule)
+	if err != nil {
+		return nil, err
+	}
+	return event.NewSubscription(func(quit <-chan struct{}) error {
+		defer sub.Unsubscribe()
+		for {
+			select {
+			case log := <-logs:
+				// New log arrived, parse the event and forward to the user
+				event := new(SAOFileDownload)
+				if err := _SAOFile.contract.UnpackLog(event, "Download", log); err != nil {
+					return err
+				}
+				event.Raw = log
+
+				select {
+				case sink <- event:
+				case err := <-sub.Err():
+					return err
+				case <-quit:
+					return nil
+				}
+			case err := <-sub.Err():
+				return err
+			case <-quit:
+				return nil
+			}
+		}
+	}), nil
+}
+
+// ParseDownload is a log parse operation binding the contract event 0xfc677c6d4fb41b52076143bd16b0f032d45adbe706f64c08956a1829cd048986.
+//
+// Solidity: event Download(uint256 indexed orderId, uint256 timestamp)
+func (_SAOFile *SAOFileFilterer) ParseDownload(log types.Log) (*SAOFileDownload, error) {
+	event := new(SAOFileDownload)
+	if err := _SAOFile.contract.UnpackLog(event, "Download", log); err != nil {
+		return nil, err
+	}
+	event.Raw = log
+	return event, nil
+}
+
+// SAOFileListingIterator is returned from FilterListing and is used to iterate over the raw logs and unpacked data for Listing events raised by the SAOFile contract.
+type SAOFileListingIterator struct {
+	Event *SAOFileListing // Event containing the contract specifics and raw log
+
+	contract *bind.BoundContract // Generic contract to use for unpacking event data
+	event    string              // Event name to use for unpacking event data
+
+	logs chan types.Log        // Log channel receiving the found contract events
+	sub  ethereum.Subscription // Subscription for errors, completion and termination
+	done bool                  // Whether the subscription completed delivering logs
+	fail error                 // Occurred error to stop iteration
+}
+
+// Next advances the iterator to the subsequent event, returning whether there
+// are any more events found. In case of a retrieval or parsing error, false is
+// returned and Error() can be queried for the exact failure.
+func (it *SAOFileListingIterator) Next() bool {
+	// If the iterator failed, stop iterating
+	if it.fail != nil {
+		return false
+	}
+	// If the iterator completed, deliver directly whatever's available
+	if it.done {
+		select {
+		case log := <-it.logs:
+			it.Event = new(SAOFileListing)
+			if err := it.contract.UnpackLog(it.Event, it.event, log); err != nil {
+				it.fail = err
+				return false
+			}
+			it.Event.Raw = log
+			return true
+
+		default:
+			return false
+		}
+	}
+	// Iterator still in progress, wait for either a data or an error event
+	select {
+	case log := <-it.logs:
+		it.Event = new(SAOFileListing)
+		if err := it.contract.UnpackLog(it.Event, it.event, log); err != nil {
+			it.fail = err
+			return false
+		}
+		it.Event.Raw = log
+		return true
+
+	case err := <-it.sub.Err():
+		it.done = true
+		it.fail = err
+		return it.Next()
+	}
+}
+
+// Error returns any retrieval or parsing error occurred during filtering.
+func (it *SAOFileListingIterator) Error() error {
+	return it.fail
+}
+
+// Close terminates the iteration process, releasing any pending underlying
+// resources.
+func (it *SAOFileListingIterator) Close() error {
+	it.sub.Unsubscribe()
+	return nil
+}
+
+// SAOFileListing represents a Listing event raised by the SAOFile contract.
+type SAOFileListing struct {
+	TokenId   *big.Int
+	FileId    *big.Int
+	Price     *big.Int
+	Timestamp *big.Int
+	Raw       types.Log // Blockchain specific contextual infos
+}
+
+// FilterListing is a free log retrieval operation binding the contract event 0xe96fc9a98f9b2e37b107acde0c3ba4ba52a1fb575da1acd1f15f0b6b1a35b8a9.
+//
+// Solidity: event Listing(uint256 indexed tokenId, uint256 fileId, uint256 price, uint256 timestamp)
+func (_SAOFile *SAOFileFilterer) FilterListing(opts *bind.FilterOpts, tokenId []*big.Int) (*SAOFileListingIterator, error) {
+
+	var tokenIdRule []interface{}
+	for _, tokenIdItem := range tokenId {
+		tokenIdRule = append(tokenIdRule, tokenIdItem)
+	}
+
+	logs, sub, err := _SAOFile.contract.FilterLogs(opts, "Listing", tokenIdRule)
+	if err != nil {
+		return nil, err
+	}
+	return &SAOFileListingIterator{contract: _SAOFile.contract, event: "Listing", logs: logs, sub: sub}, nil
+}
+
+// WatchListing is a free log subscription operation binding the contract event 0xe96fc9a98f9b2e37b107acde0c3ba4ba52a1fb575da1acd1f15f0b6b1a35b8a9.
+//
+// Solidity: event Listing(uint256 indexed tokenId, uint256 fileId, uint256 price, uint256 timestamp)
+func (_SAOFile *SAOFileFilterer) WatchListing(opts *bind.WatchOpts, sink chan<- *SAOFileListing, tokenId []*big.Int) (event.Subscription, error) {
+
+	var tokenIdRule []interface{}
+	for _, tokenIdItem := range tokenId {
+		tokenIdRule = append(tokenIdRule, tokenIdItem)
+	}
+
+	logs, sub, err := _SAOFile.contract.WatchLogs(opts, "Listing", tokenIdRule)
+	if err != nil {
+		return nil, err
+	}
+	return event.NewSubscription(func(quit <-chan struct{}) error {
+		defer sub.Unsubscribe()
+		for {
+			select {
+			case log := <-logs:
+				// New log arrived, parse the event and forward to the user
+				event := new(SAOFileListing)
+				if err := _SAOFile.contract.UnpackLog(event, "Listing", log); err != nil {
+					return err
+				}
+				event.Raw = log
+
+				select {
+				case sink <- event:
+				case err := <-sub.Err():
+					return err
+				case <-quit:
+					return nil
+				}
+			case err := <-sub.Err():
+				return err
+			case <-quit:
+				return nil
+			}
+		}
+	}), nil
+}
+
+// ParseListing is a log parse operation binding the contract event 0xe96fc9a98f9b2e37b107acde0c3ba4ba52a1fb575da1acd1f15f0b6b1a35b8a9.
+//
+// Solidity: event Listing(uint256 indexed tokenId, uint256 fileId, uint256 price, uint256 timestamp)
+func (_SAOFile *SAOFileFilterer) ParseListing(log types.Log) (*SAOFileListing, error) {
+	event := new(SAOFileListing)
+	if err := _SAOFile.contract.UnpackLog(event, "Listing", log); err != nil {
+		return nil, err
+	}
+	event.Raw = log
+	return event, nil
+}
+
+// SAOFileOwnershipTransferredIterator is returned from FilterOwnershipTransferred and is used to iterate over the raw logs and unpacked data for OwnershipTransferred events raised by the SAOFile contract.
+type SAOFileOwnershipTransferredIterator struct {
+	Event *SAOFileOwnershipTransferred // Event containing the contract specifics and raw log
+
+	contract *bind.BoundContract // Generic contract to use for unpacking event data
+	event    string              // Event name to use for unpacking event data
+
+	logs chan types.Log        // Log channel receiving the found contract events
+	sub  ethereum.Subscription // Subscription for errors, completion and termination
+	done bool                  // Whether the subscription completed delivering logs
+	fail error                 // Occurred error to stop iteration
+}
+
+// Next advances the iterator to the subsequent event, returning whether there
+// are any more events found. In case of a retrieval or parsing error, false is
+// returned and Error() can be queried for the exact failure.
+func (it *SAOFileOwnershipTransferredIterator) Next() bool {
+	// If the iterator failed, stop iterating
+	if it.fail != nil {
+		return false
+	}
+	// If the iterator completed, deliver directly whatever's available
+	if it.done {
+		select {
+		case log := <-it.logs:
+			it.Event = new(SAOFileOwnershipTransferred)
+			if err := it.contract.UnpackLog(it.Event, it.event, log); err != nil {
+				it.fail = err
+				return false
+			}
+			it.Event.Raw = log
+			return true
+
+		default:
+			return false
+		}
+	}
+	// Iterator still in progress, wait for either a data or an error event
+	select {
+	case log := <-it.logs:
+		it.Event = new(SAOFileOwnershipTransferred)
+		if err := it.contract.UnpackLog(it.Event, it.event, log); err != nil {
+			it.fail = err
+			return false
+		}
+		it.Event.Raw = log
+		return true
+
+	case err := <-it.sub.Err():
+		it.done = true
+		it.fail = err
+		return it.Next()
+	}
+}
+
+// Error returns any retrieval or parsing error occurred during filtering.
+func (it *SAOFileOwnershipTransferredIterator) Error() error {
+	return it.fail
+}
+
+// Close terminates the iteration process, releasing any pending underlying
+// resources.
+func (it *SAOFileOwnershipTransferredIterator) Close() error {
+	it.sub.Unsubscribe()
+	return nil
+}
+
+// SAOFileOwnershipTransferred represents a OwnershipTransferred event raised by the SAOFile contract.
+type SAOFileOwnershipTransferred struct {
+	PreviousOwner common.Address
+	NewOwner      common.Address
+	Raw           types.Log // Blockchain specific contextual infos
+}
+
+// FilterOwnershipTransferred is a free log retrieval operation binding the contract event 0x8be0079c531659141344cd1fd0a4f28419497f9722a3daafe3b4186f6b6457e0.
+//
+// Solidity: event OwnershipTransferred(address indexed previousOwner, address indexed newOwner)
+func (_SAOFile *SAOFileFilterer) FilterOwnershipTransferred(opts *bind.FilterOpts, previousOwner []common.Address, newOwner []common.Address) (*SAOFileOwnershipTransferredIterator, error) {
+
+	var previousOwnerRule []interface{}
+	for _, previousOwnerItem := range previousOwner {
+		previousOwnerRule = append(previousOwnerRule, previousOwnerItem)
+	}
+	var newOwnerRule []interface{}
+	for _, newOwnerItem := range newOwner {
+		newOwnerRule = append(newOwnerRule, newOwnerItem)
+	}
+
+	logs, sub, err := _SAOFile.contract.FilterLogs(opts, "OwnershipTransferred", previousOwnerRule, newOwnerRule)
+	if err != nil {
+		return nil, err
+	}
+	return &SAOFileOwnershipTransferredIterator{contract: _SAOFile.contract, event: "OwnershipTransferred", logs: logs, sub: sub}, nil
+}
+
+// WatchOwnershipTransferred is a free log subscription operation binding the contract event 0x8be0079c531659141344cd1fd0a4f28419497f9722a3daafe3b4186f6b6457e0.
+//
+// Solidity: event OwnershipTransferred(address indexed previousOwner, address indexed newOwner)
+func (_SAOFile *SAOFileFilterer) WatchOwnershipTransferred(opts *bind.WatchOpts, sink chan<- *SAOFileOwnershipTransferred, previousOwner []common.Address, newOwner []common.Address) (event.Subscription, error) {
+
+	var previousOwnerRule []interface{}
+	for _, previousOwnerItem := range previousOwner {
+		previousOwnerRule = append(previousOwnerRule, previousOwnerItem)
+	}
+	var newOwnerRule []interface{}
+	for _, newOwnerItem := range newOwner {
+		newOwnerRule = append(newOwnerRule, newOwnerItem)
+	}
+
+	logs, sub, err := _SAOFile.contract.WatchLogs(opts, "OwnershipTransferred", previousOwnerRule, newOwnerRule)
+	if err != nil {
+		return nil, err
+	}
+	return event.NewSubscription(func(quit <-chan struct{}) error {
+		defer sub.Unsubscribe()
+		for {
+			select {
+			case log := <-logs:
+				// New log arrived, parse the event and forward to the user
+				event := new(SAOFileOwnershipTransferred)
+				if err := _SAOFile.contract.UnpackLog(event, "OwnershipTransferred", log); err != nil {
+					return err
+				}
+				event.Raw = log
+
+				select {
+				case sink <- event:
+				case err := <-sub.Err():
+					return err
+				case <-quit:
+					return nil
+				}
+			case err := <-sub.Err():
+				return err
+			case <-quit:
+				return nil
+			}
+		}
+	}), nil
+}
+
+// ParseOwnershipTransferred is a log parse operation binding the contract event 0x8be0079c531659141344cd1fd0a4f28419497f9722a3daafe3b4186f6b6457e0.
+//
+// Solidity: event OwnershipTransferred(address indexed previousOwner, address indexed newOwner)
+func (_SAOFile *SAOFileFilterer) ParseOwnershipTransferred(log types.Log) (*SAOFileOwnershipTransferred, error) {
+	event := new(SAOFileOwnershipTransferred)
+	if err := _SAOFile.contract.UnpackLog(event, "OwnershipTransferred", log); err != nil {
+		return nil, err
+	}
+	event.Raw = log
+	return event, nil
+}
+
+// SAOFileTransferIterator is returned from FilterTransfer and is used to iterate over the raw logs and unpacked data for Transfer events raised by the SAOFile contract.
+type SAOFileTransferIterator struct {
+	Event *SAOFileTransfer // Event containing the contract specifics and raw log
+
+	contract *bind.BoundContract // Generic contract to use for unpacking event data
+	event    string              // Event name to use for unpacking event data
+
+	logs chan types.Log        // Log channel receiving the found contract events
+	sub  ethereum.Subscription // Subscription for errors, completion and termination
+	done bool                  // Whether the subscription completed delivering logs
+	fail error                 // Occurred error to stop iteration
+}
+
+// Next advances the iterator to the subsequent event, returning whether there
+// are any more events found. In case of a retrieval or parsing error, false is
+// returned and Error() can be queried for the exact failure.
+func (it *SAOFileTransferIterator) Next() bool {
+	// If the iterator failed, stop iterating
+	if it.fail != nil {
+		return false
+	}
+	// If the iterator completed, deliver directly whatever's available
+	if it.done {
+		select {
+		case log := <-it.logs:
+			it.Event = new(SAOFileTransfer)
+			if err := it.contract.UnpackLog(it.Event, it.event, log); err != nil {
+				it.fail = err
+				return false
+			}
+			it.Event.Raw = log
+			return true
+
+		default:
+			return false
+		}
+	}
+	// Iterator still in progress, wait for either a data or an error event
+	select {
+	case log := <-it.logs:
+		it.Event = new(SAOFileTransfer)
+		if err := it.contract.UnpackLog(it.Event, it.event, log); err != nil {
+			it.fail = err
+			return false
+		}
+		it.Event.Raw = log
+		return true
+
+	case err := <-it.sub.Err():
+		it.done = true
+		it.fail = err
+		return it.Next()
+	}
+}
+
+// Error returns any retrieval or parsing error occurred during filtering.
+func (it *SAOFileTransferIterator) Error() error {
+	return it.fail
+}
+
+// Close terminates the iteration process, releasing any pending underlying
+// resources.
+func (it *SAOFileTransferIterator) Close() error {
+	it.sub.Unsubscribe()
+	return nil
+}
+
+// SAOFileTransfer represents a Transfer event raised by the SAOFile contract.
+type SAOFileTransfer struct {
+	From    common.Address
+	To      common.Address
+	TokenId *big.Int
+	Raw     types.Log // Blockchain specific contextual infos
+}
+
+// FilterTransfer is a free log retrieval operation binding the contract event 0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef.
+//
+// Solidity: event Transfer(address indexed from, address indexed to, uint256 indexed tokenId)
+func (_SAOFile *SAOFileFilterer) FilterTransfer(opts *bind.FilterOpts, from []common.Address, to []common.Address, tokenId []*big.Int) (*SAOFileTransferIterator, error) {
+
+	var fromRule []interface{}
+	for _, fromItem := range from {
+		fromRule = append(fromRule, fromItem)
+	}
+	var toRule []interface{}
+	for _, toItem := range to {
+		toRule = append(toRule, toItem)
+	}
+	var tokenIdRule []interface{}
+	for _, tokenIdItem := range tokenId {
+		tokenIdRule = append(tokenIdRule, tokenIdItem)
+	}
+
+	logs, sub, err := _SAOFile.contract.FilterLogs(opts, "Transfer", fromRule, toRule, tokenIdRule)
+	if err != nil {
+		return nil, err
+	}
+	return &SAOFileTransferIterator{contract: _SAOFile.contract, event: "Transfer", logs: logs, sub: sub}, nil
+}
+
+// WatchTransfer is a free log subscription operation binding the contract event 0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef.
+//
+// Solidity: event Transfer(address indexed from, address indexed to, uint256 indexed tokenId)
+func (_SAOFile *SAOFileFilterer) WatchTransfer(opts *bind.WatchOpts, sink chan<- *SAOFileTransfer, from []common.Address, to []common.Address, tokenId []*big.Int) (event.Subscription, error) {
+
+	var fromRule []interface{}
+	for _, fromItem := range from {
+		fromRule = append(fromRule, fromItem)
+	}
+	var toRule []interface{}
+	for _, toItem := range to {
+		toRule = append(toRule, toItem)
+	}
+	var tokenIdRule []interface{}
+	for _, tokenIdItem := range tokenId {
+		tokenIdRule = append(tokenIdRule, tokenIdItem)
+	}
+
+	logs, sub, err := _SAOFile.contract.WatchLogs(opts, "Transfer", fromRule, toRule, tokenIdRule)
+	if err != nil {
+		return nil, err
+	}
+	return event.NewSubscription(func(quit <-chan struct{}) error {
+		defer sub.Unsubscribe()
+		for {
+			select {
+			case log := <-logs:
+				// New log arrived, parse the event and forward to the user
+				event := new(SAOFileTransfer)
+				if err := _SAOFile.contract.UnpackLog(event, "Transfer", log); err != nil {
+					return err
+				}
+				event.Raw = log
+
+				select {
+				case sink <- event:
+				case err := <-sub.Err():
+					return err
+				case <-quit:
+					return nil
+				}
+			case err := <-sub.Err():
+				return err
+			case <-quit:
+				return nil
+			}
+		}
+	}), nil
+}
+
+// ParseTransfer is a log parse operation binding the contract event 0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef.
+//
+// Solidity: event Transfer(address indexed from, address indexed to, uint256 indexed tokenId)
+func (_SAOFile *SAOFileFilterer) ParseTransfer(log types.Log) (*SAOFileTransfer, error) {
+	event := new(SAOFileTransfer)
+	if err := _SAOFile.contract.UnpackLog(event, "Transfer", log); err != nil {
+		return nil, err
+	}
+	event.Raw = log
+	return event, nil
+}
+
+// SAOFileWithdrawIterator is returned from FilterWithdraw and is used to iterate over the raw logs and unpacked data for Withdraw events raised by the SAOFile contract.
+type SAOFileWithdrawIterator struct {
+	Event *SAOFileWithdraw // Event containing the contract specifics and raw log
+
+	contract *bind.BoundContract // Generic contract to use for unpacking event data
+	event    string              // Event name to use for unpacking event data
+
+	logs chan types.Log        // Log channel receiving the found contract events
+	sub  ethereum.Subscription // Subscription for errors, completion and termination
+	done bool                  // Whether the subscription completed delivering logs
+	fail error                 // Occurred error to stop iteration
+}
+
+// Next advances the iterator to the subsequent event, returning whether there
+// are any more events found. In case of a retrieval or parsing error, false is
+// returned and Error() can be queried for the exact failure.
+func (it *SAOFileWithdrawIterator) Next() bool {
+	// If the iterator failed, stop iterating
+	if it.fail != nil {
+		return false
+	}
+	// If the iterator completed, deliver directly whatever's available
+	if it.done {
+		select {
+		case log := <-it.logs:
+			it.Event = new(SAOFileWithdraw)
+			if err := it.contract.UnpackLog(it.Event, it.event, log); err != nil {
+				it.fail = err
+				return false
+			}
+			it.Event.Raw = log
+			return true
+
+		default:
+			return false
+		}
+	}
+	// Iterator still in progress, wait for either a data or an error event
+	select {
+	case log := <-it.logs:
+		it.Event = new(SAOFileWithdraw)
+		if err := it.contract.UnpackLog(it.Event, it.event, log); err != nil {
+			it.fail = err
+			return false
+		}
+		it.Event.Raw = log
+		return true
+
+	case err := <-it.sub.Err():
+		it.done = true
+		it.fail = err
+		return it.Next()
+	}
+}
+
+// Error returns any retrieval or parsing error occurred during filtering.
+func (it *SAOFileWithdrawIterator) Error() error {
+	return it.fail
+}
+
+// Close terminates the iteration process, releasing any pending underlying
+// resources.
+func (it *SAOFileWithdrawIterator) Close() error {
+	it.sub.Unsubscribe()
+	return nil
+}
+
+// SAOFileWithdraw represents a Withdraw event raised by the SAOFile contract.
+type SAOFileWithdraw struct {
+	User      common.Address
+	Amount    *big.Int
+	Timestamp *big.Int
+	Raw       types.Log // Blockchain specific contextual infos
+}
+
+// FilterWithdraw is a free log retrieval operation binding the contract event 0xf279e6a1f5e320cca91135676d9cb6e44ca8a08c0b88342bcdb1144f6511b568.
+//
+// Solidity: event Withdraw(address indexed user, uint256 amount, uint256 timestamp)
+func (_SAOFile *SAOFileFilterer) FilterWithdraw(opts *bind.FilterOpts, user []common.Address) (*SAOFileWithdrawIterator, error) {
+
+	var userRule []interface{}
+	for _, userItem := range user {
+		userRule = append(userRule, userItem)
+	}
+
+	logs, sub, err := _SAOFile.contract.FilterLogs(opts, "Withdraw", userRule)
+	if err != nil {
+		return nil, err
+	}
+	return &SAOFileWithdrawIterator{contract: _SAOFile.contract, event: "Withdraw", logs: logs, sub: sub}, nil
+}
+
+// WatchWithdraw is a free log subscription operation binding the contract event 0xf279e6a1f5e320cca91135676d9cb6e44ca8a08c0b88342bcdb1144f6511b568.
+//
+// Solidity: event Withdraw(address indexed user, uint256 amount, uint256 timestamp)
+func (_SAOFile *SAOFileFilterer) WatchWithdraw(opts *bind.WatchOpts, sink chan<- *SAOFileWithdraw, user []common.Address) (event.Subscription, error) {
+
+	var userRule []interface{}
+	for _, userItem := range user {
+		userRule = append(userRule, userItem)
+	}
+
+	logs, sub, err := _SAOFile.contract.WatchLogs(opts, "Withdraw", userRule)
+	if err != nil {
+		return nil, err
+	}
+	return event.NewSubscription(func(quit <-chan struct{}) error {
+		defer sub.Unsubscribe()
+		for {
+			select {
+			case log := <-logs:
+				// New log arrived, parse the event and forward to the user
+				event := new(SAOFileWithdraw)
+				if err := _SAOFile.contract.UnpackLog(event, "Withdraw", log); err != nil {
+					return err
+				}
+				event.Raw = log
+
+				select {
+				case sink <- event:
+				case err := <-sub.Err():
+					return err
+				case <-quit:
+					return nil
+				}
+			case err := <-sub.Err():
+				return err
+			case <-quit:
+				return nil
+			}
+		}
+	}), nil
+}
+
+// ParseWithdraw is a log parse operation binding the contract event 0xf279e6a1f5e320cca91135676d9cb6e44ca8a08c0b88342bcdb1144f6511b568.
+//
+// Solidity: event Withdraw(address indexed user, uint256 amount, uint256 timestamp)
+func (_SAOFile *SAOFileFilterer) ParseWithdraw(log types.Log) (*SAOFileWithdraw, error) {
+	event := new(SAOFileWithdraw)
+	if err := _SAOFile.contract.UnpackLog(event, "Withdraw", log); err != nil {
+		return nil, err
+	}
+	event.Raw = log
+	return event, nil
+}